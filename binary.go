@@ -2,93 +2,641 @@ package pqueue
 
 import (
 	"cmp"
+	"context"
+	"io"
+	"iter"
 	"sync"
-	"sync/atomic"
 
 	"github.com/AndrewChon/pqueue/binary"
 )
 
-var binaryIDCounter atomic.Uint64
-
 // Binary is a concurrency-safe, min-priority queue built on a binary heap.
-type Binary[K cmp.Ordered, V any] struct {
+type Binary[K, V any] struct {
 	// A locking order needs to be defined and strictly followed for safety; thus, we do not want to expose the mutex.
 	l  sync.RWMutex
 	id uint64
 
-	heap *binary.Heap[K, V]
+	notEmpty waiters
+	bound    bound
+	closer   closer
+
+	heap     *binary.Heap[K, V]
+	less     func(a, b K) bool
+	tieBreak TieBreak
+	codec    codecs[K, V]
+	meter    meter
+	observer observer[K, V]
+}
+
+func NewBinary[K cmp.Ordered, V any](opts ...Option) *Binary[K, V] {
+	return NewBinaryFunc[K, V](cmp.Less[K], opts...)
 }
 
-func NewBinary[K cmp.Ordered, V any]() *Binary[K, V] {
+// NewBinaryFunc is like NewBinary, but orders keys using the provided less function rather than requiring K to be
+// ordered. For example, a max-priority queue can be built by providing a less function that reports whether a > b.
+// Queues that are melded together are expected to order keys the same way.
+func NewBinaryFunc[K, V any](less func(a, b K) bool, opts ...Option) *Binary[K, V] {
+	o := newOptions(opts, heapOptions)
+
 	return &Binary[K, V]{
-		id:   binaryIDCounter.Add(1),
-		heap: binary.NewHeap[K, V](),
+		id:       idCounter.Add(1),
+		bound:    newBound(o, heapPolicies...),
+		closer:   newCloser(),
+		heap:     binary.NewHeapFunc[K, V](less),
+		less:     less,
+		tieBreak: o.tieBreak,
+		codec:    newCodecs[K, V](o),
+		meter:    newMeter(o),
+		observer: newObserver[K, V](o),
 	}
 }
 
+// NewBinaryFrom builds a Binary queue from the provided items in linear time using bottom-up heap construction. If
+// there are more items than the queue's capacity, the items with the lowest priorities are discarded.
+func NewBinaryFrom[K cmp.Ordered, V any](items []Item[K, V], opts ...Option) *Binary[K, V] {
+	return NewBinaryFromFunc(items, cmp.Less[K], opts...)
+}
+
+// NewBinaryFromFunc is like NewBinaryFrom, but orders keys using the provided less function.
+func NewBinaryFromFunc[K, V any](items []Item[K, V], less func(a, b K) bool, opts ...Option) *Binary[K, V] {
+	b := NewBinaryFunc[K, V](less, opts...)
+	b.insertLocked(entriesOf(items))
+	b.trimLocked()
+
+	return b
+}
+
+// CollectBinary builds a Binary queue from the key-value pairs of seq, where each key is a priority.
+func CollectBinary[K cmp.Ordered, V any](seq iter.Seq2[K, V], opts ...Option) *Binary[K, V] {
+	return NewBinaryFrom(collect(seq), opts...)
+}
+
+// CollectBinaryFunc is like CollectBinary, but orders keys using the provided less function.
+func CollectBinaryFunc[K, V any](seq iter.Seq2[K, V], less func(a, b K) bool, opts ...Option) *Binary[K, V] {
+	return NewBinaryFromFunc(collect(seq), less, opts...)
+}
+
 func (b *Binary[K, V]) Size() int {
-	b.l.RLock()
+	b.meter.rlock(&b.l)
 	defer b.l.RUnlock()
 
 	return b.heap.Size()
 }
 
 func (b *Binary[K, V]) Clear() {
-	b.l.Lock()
+	b.meter.lock(&b.l)
 	defer b.l.Unlock()
 
-	b.heap.Clear()
+	b.observer.clear(b.sizeLocked())
+	b.clearLocked()
+	b.meter.cleared()
 }
 
 func (b *Binary[K, V]) Peek() V {
-	b.l.RLock()
+	_, v, _ := b.PeekItem()
+	return v
+}
+
+// PeekItem returns the priority and value of the element with the highest priority without removing it. ok is false if
+// the queue is empty.
+func (b *Binary[K, V]) PeekItem() (priority K, v V, ok bool) {
+	b.meter.rlock(&b.l)
 	defer b.l.RUnlock()
 
-	minNode := b.heap.FindMin()
-	if minNode == nil {
-		var zero V
-		return zero
+	return b.peekLocked()
+}
+
+func (b *Binary[K, V]) Pop() (v V, ok bool) {
+	_, v, ok = b.PopItem()
+	return v, ok
+}
+
+// PopItem removes and returns the priority and value of the element with the highest priority. ok is false if the
+// queue is empty.
+func (b *Binary[K, V]) PopItem() (priority K, v V, ok bool) {
+	b.meter.lock(&b.l)
+	defer b.l.Unlock()
+
+	return b.popLocked()
+}
+
+// Push inserts a value with the provided priority. If the queue is full, Push follows the queue's OverflowPolicy; see
+// WithCapacity.
+func (b *Binary[K, V]) Push(v V, priority K) error {
+	return b.PushWait(context.Background(), v, priority)
+}
+
+// PushWait is like Push, but returns ctx's error if ctx is done while blocked on a full queue.
+func (b *Binary[K, V]) PushWait(ctx context.Context, v V, priority K) error {
+	b.meter.lock(&b.l)
+	defer b.l.Unlock()
+
+	_, err := b.admitLocked(ctx, v, priority)
+	return err
+}
+
+// PopWait removes and returns the value with the highest priority, blocking until the queue is non-empty. If ctx is
+// done first, PopWait returns ctx's error.
+func (b *Binary[K, V]) PopWait(ctx context.Context) (V, error) {
+	_, v, err := b.PopItemWait(ctx)
+	return v, err
+}
+
+// PopItemWait is like PopWait, but also returns the priority of the element.
+func (b *Binary[K, V]) PopItemWait(ctx context.Context) (priority K, v V, err error) {
+	e, err := b.popEntryWait(ctx)
+	return e.key, e.value, err
+}
+
+// Close closes the queue. Subsequent pushes and melds into the queue fail with ErrClosed, and every goroutine blocked
+// in PopWait or PushWait is woken. Elements that remain in the queue can still be popped, and PopWait returns ErrClosed
+// once the queue is empty. Calling Close more than once has no effect.
+func (b *Binary[K, V]) Close() {
+	b.meter.lock(&b.l)
+	defer b.l.Unlock()
+
+	b.closer.close(b.heap.Size())
+	b.notEmpty.broadcast()
+	b.bound.notFull.broadcast()
+}
+
+// IsClosed reports whether Close has been called.
+func (b *Binary[K, V]) IsClosed() bool {
+	b.meter.rlock(&b.l)
+	defer b.l.RUnlock()
+
+	return b.closer.closed
+}
+
+// Done returns a channel that is closed once the queue has been closed and every remaining element has been removed.
+func (b *Binary[K, V]) Done() <-chan struct{} {
+	return b.closer.done
+}
+
+// TieBreak returns the TieBreak the queue was created with (see WithTieBreak).
+func (b *Binary[K, V]) TieBreak() TieBreak {
+	return b.tieBreak
+}
+
+// All returns an iterator over every element in priority order, without modifying the queue.
+func (b *Binary[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		b.meter.rlock(&b.l)
+		entries := b.entriesLocked()
+		b.l.RUnlock()
+
+		orderedSeq(entries, b.less)(yield)
+	}
+}
+
+// Unordered returns an iterator over every element in no particular order, without modifying the queue. It is cheaper
+// than All, as the elements do not need to be ordered.
+func (b *Binary[K, V]) Unordered() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		b.meter.rlock(&b.l)
+		entries := b.entriesLocked()
+		b.l.RUnlock()
+
+		unorderedSeq(entries)(yield)
 	}
+}
 
-	return minNode.Value()
+// Drain returns an iterator that pops every element in priority order until the queue is empty.
+func (b *Binary[K, V]) Drain() iter.Seq2[K, V] {
+	return drainSeq(b.PopItem)
 }
 
-func (b *Binary[K, V]) Pop() (v V, ok bool) {
-	b.l.Lock()
+// PushMany inserts every provided item while only taking the lock once. If the items do not all fit, PushMany fails
+// with ErrFull without inserting any of them under OverflowError; under every other policy, each item is pushed in turn
+// as if by Push. In that case the batch is not atomic: under OverflowBlock, the lock is released while waiting for
+// room, so other operations may run between items, and items pushed before an error stay in the queue.
+func (b *Binary[K, V]) PushMany(items []Item[K, V]) error {
+	return b.PushManyWait(context.Background(), items)
+}
+
+// PushManyWait is like PushMany, but returns ctx's error if ctx is done while blocked on a full queue.
+func (b *Binary[K, V]) PushManyWait(ctx context.Context, items []Item[K, V]) error {
+	b.meter.lock(&b.l)
 	defer b.l.Unlock()
 
-	n := b.heap.FindMin()
+	if b.closer.closed {
+		return ErrClosed
+	}
+	if b.bound.room(b.heap.Size(), len(items)) {
+		b.insertLocked(entriesOf(items))
+		for _, item := range items {
+			b.observer.push(item.Priority, item.Value)
+		}
+		b.meter.pushed(len(items))
+		return nil
+	}
+	if b.bound.policy == OverflowError {
+		return ErrFull
+	}
+
+	for _, item := range items {
+		if _, err := b.admitLocked(ctx, item.Value, item.Priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PopN removes and returns up to n items in priority order while only taking the lock once.
+func (b *Binary[K, V]) PopN(n int) []Item[K, V] {
+	b.meter.lock(&b.l)
+	defer b.l.Unlock()
+
+	return popN(b.popLocked, b.heap.Size(), n)
+}
+
+// PushHandle inserts a value with the provided priority and returns a Handle to it. If the queue is full, PushHandle
+// behaves like Push, and the returned Handle is nil if the value was not inserted.
+func (b *Binary[K, V]) PushHandle(v V, priority K) (*Handle[K, V], error) {
+	return b.PushHandleWait(context.Background(), v, priority)
+}
+
+// PushHandleWait is like PushHandle, but returns ctx's error if ctx is done while blocked on a full queue.
+func (b *Binary[K, V]) PushHandleWait(ctx context.Context, v V, priority K) (*Handle[K, V], error) {
+	b.meter.lock(&b.l)
+	defer b.l.Unlock()
+
+	n, err := b.admitLocked(ctx, v, priority)
 	if n == nil {
-		return
+		return nil, err
 	}
+	return &Handle[K, V]{node: n}, nil
+}
 
-	v = n.Value()
-	b.heap.RemoveMin()
-	return v, true
+// Update changes the priority of the element referred to by h in Θ(log n). It returns false if h does not refer to an
+// element in this queue.
+func (b *Binary[K, V]) Update(h *Handle[K, V], priority K) bool {
+	b.meter.lock(&b.l)
+	defer b.l.Unlock()
+
+	n := b.nodeOf(h)
+	if n == nil {
+		return false
+	}
+
+	b.heap.Update(n, priority)
+	return true
 }
 
-func (b *Binary[K, V]) Push(v V, priority K) {
-	b.l.Lock()
+// Remove removes the element referred to by h in Θ(log n). It returns false if h does not refer to an element in this
+// queue.
+func (b *Binary[K, V]) Remove(h *Handle[K, V]) bool {
+	b.meter.lock(&b.l)
 	defer b.l.Unlock()
 
-	newNode := binary.NewNode(priority, v)
-	b.heap.Insert(newNode)
+	n := b.nodeOf(h)
+	if n == nil {
+		return false
+	}
+
+	b.heap.Remove(n)
+	b.meter.popped(n)
+	b.observer.pop(n.Key(), n.Value())
+	b.removedLocked()
+	return true
 }
 
-func (b *Binary[K, V]) Meld(other *Binary[K, V]) {
-	if b.id < other.id {
-		b.l.Lock()
-		other.l.Lock()
-	} else if b.id > other.id {
-		other.l.Lock()
-		b.l.Lock()
-	} else {
-		panic(ConcurrencySafetyError)
+// nodeOf returns the node referred to by h, or nil if h does not refer to an element in this queue.
+func (b *Binary[K, V]) nodeOf(h *Handle[K, V]) *binary.Node[K, V] {
+	if h == nil {
+		return nil
 	}
 
+	n, ok := h.node.(*binary.Node[K, V])
+	if !ok || !b.heap.Contains(n) {
+		return nil
+	}
+	return n
+}
+
+// Meld merges other into b and clears other.
+func (b *Binary[K, V]) Meld(other *Binary[K, V]) error {
+	b.meter.lockPair(&b.l, b.id, &other.l, other.id)
 	defer b.l.Unlock()
 	defer other.l.Unlock()
 
+	if err := b.acceptsLocked(other.heap.Size()); err != nil {
+		return err
+	}
+
+	adopt(&b.meter, &other.meter, other.heap.Nodes())
+	b.meter.melded(other.sizeLocked())
+	b.observer.meld(other.sizeLocked())
 	b.heap = binary.Merge(b.heap, other.heap)
-	other.heap.Clear()
+	other.clearLocked()
+	b.trimLocked()
+	b.meter.depth(b.heap.Size())
+	b.notEmpty.broadcast()
+	return nil
+}
+
+// CrossMeld merges any other queue from this package into this one and clears it. See CrossMeldable.
+func (b *Binary[K, V]) CrossMeld(other CrossMeldable) error {
+	if o, ok := other.(*Binary[K, V]); ok {
+		return b.Meld(o)
+	}
+
+	return crossMeld[K, V](b, other)
+}
+
+// Clone returns an independent copy of the queue. Handles to the queue's elements do not refer to the copy's elements.
+func (b *Binary[K, V]) Clone() *Binary[K, V] {
+	return b.CloneFunc(nil)
+}
+
+// CloneFunc is like Clone, but copies every value with copyValue.
+func (b *Binary[K, V]) CloneFunc(copyValue func(V) V) *Binary[K, V] {
+	b.meter.rlock(&b.l)
+	defer b.l.RUnlock()
+
+	return &Binary[K, V]{
+		id:       idCounter.Add(1),
+		bound:    b.bound.clone(),
+		closer:   newCloser(),
+		heap:     b.heap.Clone(copyValue),
+		less:     b.less,
+		tieBreak: b.tieBreak,
+		codec:    b.codec,
+		observer: b.observer,
+	}
+}
+
+// MarshalBinary encodes every element of the queue in a versioned binary format that is shared by every heap-based
+// queue, using the queue's codecs (see WithKeyCodec).
+func (b *Binary[K, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary[K, V](b)
+}
+
+// UnmarshalBinary replaces the contents of the queue with the elements encoded in data by the MarshalBinary method of
+// any heap-based queue. The queue must have been created by its constructor, and keeps its own comparator and options.
+func (b *Binary[K, V]) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary[K, V](b, data)
+}
+
+// MarshalJSON is like MarshalBinary, but encodes the elements as JSON.
+func (b *Binary[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON[K, V](b)
+}
+
+// UnmarshalJSON is like UnmarshalBinary, but decodes elements encoded by MarshalJSON.
+func (b *Binary[K, V]) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON[K, V](b, data)
+}
+
+// GobEncode encodes the queue in the same format as MarshalBinary.
+func (b *Binary[K, V]) GobEncode() ([]byte, error) {
+	return b.MarshalBinary()
+}
+
+// GobDecode decodes the queue in the same way as UnmarshalBinary.
+func (b *Binary[K, V]) GobDecode(data []byte) error {
+	return b.UnmarshalBinary(data)
+}
+
+// Inspect returns the Stats of the queue, with up to top of its elements with the highest priorities. It costs Θ(n),
+// plus O(log n) for every element returned.
+func (b *Binary[K, V]) Inspect(top int) Stats {
+	b.meter.rlock(&b.l)
+	entries := b.entriesLocked()
+	stats := Stats{
+		Kind: "Binary",
+		ID:   b.id,
+		Size: b.heap.Size(),
+		Shape: map[string]any{
+			"arrayLength":   b.heap.Size(),
+			"arrayCapacity": b.heap.Cap(),
+		},
+	}
+	b.l.RUnlock()
+
+	stats.Top = topOf(entries, b.less, top)
+	return stats
+}
+
+// WriteDOT writes the heap that backs the queue to w as a Graphviz graph, as described by binary.Heap.WriteDOT, to help
+// diagnose the shape of the heap. The queue is read-locked until the whole graph has been written.
+func (b *Binary[K, V]) WriteDOT(w io.Writer) error {
+	b.meter.rlock(&b.l)
+	defer b.l.RUnlock()
+
+	return b.heap.WriteDOT(w)
+}
+
+// Validate checks the invariants of the heap that backs the queue (see binary.Heap.Validate), that the queue's size
+// matches the number of elements in its heap, and that its size fits within its capacity.
+func (b *Binary[K, V]) Validate() error {
+	b.meter.rlock(&b.l)
+	defer b.l.RUnlock()
+
+	if err := b.heap.Validate(); err != nil {
+		return invalid(err)
+	}
+	return validateSize(countOf(b.heap.Nodes()), b.heap.Size(), &b.bound)
+}
+
+func (b *Binary[K, V]) queueID() uint64 {
+	return b.id
+}
+
+func (b *Binary[K, V]) mutex() *sync.RWMutex {
+	return &b.l
+}
+
+func (b *Binary[K, V]) sizeLocked() int {
+	return b.heap.Size()
+}
+
+func (b *Binary[K, V]) acceptsLocked(incoming int) error {
+	if b.closer.closed {
+		return ErrClosed
+	}
+	return b.bound.fits(b.heap.Size(), incoming)
+}
+
+// trimLocked evicts the elements with the lowest priorities until the queue fits within its capacity.
+func (b *Binary[K, V]) trimLocked() {
+	excess := b.bound.excess(b.heap.Size())
+	if excess == 0 {
+		return
+	}
+
+	for _, n := range lowestN(b.heap.Nodes(), b.less, excess) {
+		b.heap.Remove(n)
+		b.meter.forget(n)
+		b.observer.drop(n.Key(), n.Value())
+	}
+	b.meter.depth(b.heap.Size())
+}
+
+// removedLocked is called whenever elements are removed from the queue.
+func (b *Binary[K, V]) removedLocked() {
+	b.bound.notFull.broadcast()
+	b.closer.settle(b.heap.Size())
+	b.meter.depth(b.heap.Size())
+}
+
+func (b *Binary[K, V]) meterLocked() *meter {
+	return &b.meter
+}
+
+func (b *Binary[K, V]) observerLocked() *observer[K, V] {
+	return &b.observer
+}
+
+func (b *Binary[K, V]) codecs() codecs[K, V] {
+	return b.codec
+}
+
+func (b *Binary[K, V]) initialized() bool {
+	return b.less != nil
+}
+
+func (b *Binary[K, V]) drainLocked() []entry[K, V] {
+	entries := b.entriesLocked()
+	b.clearLocked()
+
+	return entries
+}
+
+// entriesLocked returns a snapshot of every element in the queue, in no particular order.
+func (b *Binary[K, V]) entriesLocked() []entry[K, V] {
+	entries := make([]entry[K, V], 0, b.heap.Size())
+	for n := range b.heap.Nodes() {
+		entries = append(entries, entry[K, V]{n.Key(), n.Value(), n.Seq()})
+	}
+
+	return entries
+}
+
+func (b *Binary[K, V]) clearLocked() {
+	b.heap.Clear()
+	b.meter.forgetAll()
+	b.removedLocked()
+}
+
+func (b *Binary[K, V]) insertLocked(entries []entry[K, V]) {
+	nodes := make([]*binary.Node[K, V], 0, len(entries))
+	for _, e := range entries {
+		n := binary.NewSequencedNode(e.key, e.value, b.seqOf(e))
+		b.meter.stamp(n)
+		nodes = append(nodes, n)
+	}
+
+	b.heap.InsertMany(nodes)
+	b.meter.depth(b.heap.Size())
+	b.notEmpty.broadcast()
+}
+
+// seqOf returns the sequence number to give an element that is being moved into this queue. The element keeps its
+// sequence number if it has one, so that insertion order survives a CrossMeld.
+func (b *Binary[K, V]) seqOf(e entry[K, V]) uint64 {
+	if e.seq == 0 {
+		return b.tieBreak.nextSeq()
+	}
+	return e.seq
+}
+
+// admitLocked pushes a value once the queue has room for it, as dictated by its overflow policy. It returns nil if the
+// value was not pushed.
+func (b *Binary[K, V]) admitLocked(ctx context.Context, v V, priority K) (*binary.Node[K, V], error) {
+	ok, err := b.bound.admit(ctx, &b.meter, &b.l, &b.closer, b.heap.Size, func() bool {
+		return b.evictLocked(priority)
+	})
+	if !ok {
+		if err == nil {
+			b.observer.drop(priority, v)
+		}
+		return nil, err
+	}
+
+	return b.pushLocked(v, priority), nil
+}
+
+// evictLocked removes the element with the lowest priority to make room for an incoming element with the provided
+// priority. It returns false, leaving the queue untouched, if the incoming element would have the lowest priority.
+func (b *Binary[K, V]) evictLocked(priority K) bool {
+	n, ok := lowest(b.heap.Nodes(), b.less)
+	if !ok || !outranks(priority, n, b.less, b.tieBreak) {
+		return false
+	}
+
+	b.heap.Remove(n)
+	b.meter.forget(n)
+	b.observer.drop(n.Key(), n.Value())
+	return true
+}
+
+func (b *Binary[K, V]) pushLocked(v V, priority K) *binary.Node[K, V] {
+	newNode := binary.NewSequencedNode(priority, v, b.tieBreak.nextSeq())
+	b.heap.Insert(newNode)
+	b.meter.stamp(newNode)
+	b.meter.pushed(1)
+	b.observer.push(priority, v)
+	b.meter.depth(b.heap.Size())
+	b.notEmpty.broadcast()
+
+	return newNode
+}
+
+func (b *Binary[K, V]) peekLocked() (priority K, v V, ok bool) {
+	minNode := b.heap.FindMin()
+	if minNode == nil {
+		return
+	}
+
+	return minNode.Key(), minNode.Value(), true
+}
+
+func (b *Binary[K, V]) popLocked() (priority K, v V, ok bool) {
+	e, ok := b.popEntryLocked()
+	return e.key, e.value, ok
+}
+
+// popEntryLocked is like popLocked, but returns the element as an entry, along with its sequence number.
+func (b *Binary[K, V]) popEntryLocked() (entry[K, V], bool) {
+	h, ok := b.takeLocked()
+	if ok {
+		b.meter.poppedAt(h.pushedAt)
+		b.observer.pop(h.key, h.value)
+	}
+	return h.entry, ok
+}
+
+func (b *Binary[K, V]) popEntryWait(ctx context.Context) (entry[K, V], error) {
+	return waitFor(ctx, &b.meter, &b.l, &b.notEmpty, &b.closer, b.popEntryLocked)
+}
+
+// takeLocked removes the element with the highest priority without counting it as popped.
+func (b *Binary[K, V]) takeLocked() (held[K, V], bool) {
+	n := b.heap.FindMin()
+	if n == nil {
+		return held[K, V]{}, false
+	}
+
+	b.heap.RemoveMin()
+	b.removedLocked()
+	return held[K, V]{entry[K, V]{n.Key(), n.Value(), n.Seq()}, b.meter.taken(n)}, true
+}
+
+func (b *Binary[K, V]) takeWait(ctx context.Context) (held[K, V], error) {
+	return waitFor(ctx, &b.meter, &b.l, &b.notEmpty, &b.closer, b.takeLocked)
+}
+
+// requeueLocked puts an element that was removed with takeLocked back into the queue, without counting it as pushed.
+func (b *Binary[K, V]) requeueLocked(h held[K, V]) {
+	n := binary.NewSequencedNode(h.key, h.value, h.seq)
+	b.heap.Insert(n)
+	b.meter.stampAt(n, h.pushedAt)
+	b.meter.depth(b.heap.Size())
+	b.notEmpty.broadcast()
+}
+
+// pushedLocked returns a channel that is closed the next time elements are added to the queue.
+func (b *Binary[K, V]) pushedLocked() <-chan struct{} {
+	return b.notEmpty.wait()
 }
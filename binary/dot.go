@@ -0,0 +1,29 @@
+package binary
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteDOT writes the Heap to w as a graph in the DOT language of Graphviz. Every node is labelled with its key and
+// its index in the heap's array, and is linked to its children at indices 2i+1 and 2i+2. Keys are formatted with fmt.
+func (h *Heap[K, V]) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph binary {")
+	fmt.Fprintln(bw, "\tnode [shape=circle];")
+	for i, n := range h.array {
+		fmt.Fprintf(bw, "\tn%d [label=%q];\n", i, fmt.Sprintf("%v\n[%d]", n.key, i))
+	}
+	for i := range h.array {
+		for _, c := range []int{2*i + 1, 2*i + 2} {
+			if c < len(h.array) {
+				fmt.Fprintf(bw, "\tn%d -> n%d;\n", i, c)
+			}
+		}
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
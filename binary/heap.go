@@ -2,17 +2,31 @@ package binary
 
 import (
 	"cmp"
+	"iter"
+	"math/bits"
 )
 
-type Node[K cmp.Ordered, V any] struct {
+type Node[K, V any] struct {
 	key   K
 	value V
+	seq   uint64
+
+	// index is the position of the node within its heap's array, or -1 if the node has been removed.
+	index int
+}
+
+func NewNode[K, V any](key K, value V) *Node[K, V] {
+	return NewSequencedNode(key, value, 0)
 }
 
-func NewNode[K cmp.Ordered, V any](key K, value V) *Node[K, V] {
+// NewSequencedNode is like NewNode, but also assigns the node a sequence number. Nodes with equal keys are ordered by
+// ascending sequence number.
+func NewSequencedNode[K, V any](key K, value V, seq uint64) *Node[K, V] {
 	return &Node[K, V]{
 		key:   key,
 		value: value,
+		seq:   seq,
+		index: -1,
 	}
 }
 
@@ -24,24 +38,90 @@ func (n *Node[K, V]) Value() V {
 	return n.value
 }
 
-type Heap[K cmp.Ordered, V any] struct {
+func (n *Node[K, V]) Seq() uint64 {
+	return n.seq
+}
+
+type Heap[K, V any] struct {
 	array []*Node[K, V]
+	less  func(a, b K) bool
 }
 
 func NewHeap[K cmp.Ordered, V any]() *Heap[K, V] {
+	return NewHeapFunc[K, V](cmp.Less[K])
+}
+
+// NewHeapFunc is like NewHeap, but orders keys using the provided less function.
+func NewHeapFunc[K, V any](less func(a, b K) bool) *Heap[K, V] {
 	return &Heap[K, V]{
 		array: make([]*Node[K, V], 0),
+		less:  less,
 	}
 }
 
+// NewHeapFrom builds a Heap from the provided nodes in linear time. The Heap takes ownership of the slice.
+func NewHeapFrom[K cmp.Ordered, V any](nodes []*Node[K, V]) *Heap[K, V] {
+	return NewHeapFromFunc(nodes, cmp.Less[K])
+}
+
+// NewHeapFromFunc is like NewHeapFrom, but orders keys using the provided less function.
+func NewHeapFromFunc[K, V any](nodes []*Node[K, V], less func(a, b K) bool) *Heap[K, V] {
+	h := &Heap[K, V]{
+		array: nodes,
+		less:  less,
+	}
+	h.heapify()
+
+	return h
+}
+
 func (h *Heap[K, V]) Size() int {
 	return len(h.array)
 }
 
+// Cap returns the capacity of the array that backs the Heap, which may exceed its size.
+func (h *Heap[K, V]) Cap() int {
+	return cap(h.array)
+}
+
 func (h *Heap[K, V]) Clear() {
 	h.array = make([]*Node[K, V], 0)
 }
 
+// Contains reports whether the node is currently stored in the Heap.
+func (h *Heap[K, V]) Contains(n *Node[K, V]) bool {
+	return n != nil && n.index >= 0 && n.index < len(h.array) && h.array[n.index] == n
+}
+
+// Nodes returns an iterator over every node in the Heap, in no particular order.
+func (h *Heap[K, V]) Nodes() iter.Seq[*Node[K, V]] {
+	return func(yield func(*Node[K, V]) bool) {
+		for _, n := range h.array {
+			if !yield(n) {
+				return
+			}
+		}
+	}
+}
+
+// Clone returns a copy of the Heap in Θ(n), made up of copies of its nodes. If copyValue is not nil, it is used to copy
+// every value.
+func (h *Heap[K, V]) Clone(copyValue func(V) V) *Heap[K, V] {
+	array := make([]*Node[K, V], len(h.array))
+	for i, n := range h.array {
+		c := *n
+		if copyValue != nil {
+			c.value = copyValue(c.value)
+		}
+		array[i] = &c
+	}
+
+	return &Heap[K, V]{
+		array: array,
+		less:  h.less,
+	}
+}
+
 func (h *Heap[K, V]) FindMin() *Node[K, V] {
 	if len(h.array) == 0 {
 		return nil
@@ -49,8 +129,9 @@ func (h *Heap[K, V]) FindMin() *Node[K, V] {
 	return h.array[0]
 }
 
-func Merge[K cmp.Ordered, V any](a, b *Heap[K, V]) *Heap[K, V] {
-	newHeap := NewHeap[K, V]()
+// Merge forms a new Heap from the nodes of two other heaps. The new Heap orders keys the same way as a.
+func Merge[K, V any](a, b *Heap[K, V]) *Heap[K, V] {
+	newHeap := NewHeapFunc[K, V](a.less)
 
 	sizeA := len(a.array)
 	sizeB := len(b.array)
@@ -67,12 +148,7 @@ func Merge[K cmp.Ordered, V any](a, b *Heap[K, V]) *Heap[K, V] {
 
 	newHeap.array = append(newHeap.array, a.array...)
 	newHeap.array = append(newHeap.array, b.array...)
-
-	// Heapify down from the bottom up.
-	size := len(newHeap.array)
-	for i := size/2 - 1; i >= 0; i-- {
-		newHeap.heapifyDown(i)
-	}
+	newHeap.heapify()
 
 	return newHeap
 }
@@ -80,10 +156,27 @@ func Merge[K cmp.Ordered, V any](a, b *Heap[K, V]) *Heap[K, V] {
 func (h *Heap[K, V]) Insert(newNode *Node[K, V]) {
 	h.array = append(h.array, newNode)
 	newNodeIndex := len(h.array) - 1
+	newNode.index = newNodeIndex
 
 	h.heapifyUp(newNodeIndex)
 }
 
+// InsertMany inserts several nodes at once. Depending on how many nodes are being inserted relative to the size of the
+// Heap, the nodes are either inserted one at a time in O(k log n), or appended in bulk, after which the entire Heap is
+// rebuilt in O(n + k).
+func (h *Heap[K, V]) InsertMany(nodes []*Node[K, V]) {
+	size := len(h.array) + len(nodes)
+	if len(nodes)*bits.Len(uint(size)) < 2*size {
+		for _, n := range nodes {
+			h.Insert(n)
+		}
+		return
+	}
+
+	h.array = append(h.array, nodes...)
+	h.heapify()
+}
+
 func (h *Heap[K, V]) RemoveMin() {
 	size := len(h.array)
 
@@ -91,19 +184,75 @@ func (h *Heap[K, V]) RemoveMin() {
 		return
 	}
 
+	minNode := h.array[0]
 	h.array[0] = h.array[size-1]
+	h.array[0].index = 0
 	h.array = h.array[:size-1]
+	minNode.index = -1
 	h.heapifyDown(0)
 }
 
+// Remove removes an arbitrary node from the Heap. The node must be stored in the Heap (see Contains).
+func (h *Heap[K, V]) Remove(n *Node[K, V]) {
+	i := n.index
+	last := len(h.array) - 1
+
+	h.swap(i, last)
+	h.array = h.array[:last]
+	n.index = -1
+
+	if i < last {
+		moved := h.array[i]
+		h.heapifyUp(i)
+		h.heapifyDown(moved.index)
+	}
+}
+
+// Update changes the key of a node stored in the Heap and restores the heap property, whether the key was increased or
+// decreased.
+func (h *Heap[K, V]) Update(n *Node[K, V], newKey K) {
+	n.key = newKey
+
+	h.heapifyUp(n.index)
+	h.heapifyDown(n.index)
+}
+
+// heapify restores the heap property across the entire array by heapifying down from the bottom up.
+func (h *Heap[K, V]) heapify() {
+	for i, n := range h.array {
+		n.index = i
+	}
+
+	size := len(h.array)
+	for i := size/2 - 1; i >= 0; i-- {
+		h.heapifyDown(i)
+	}
+}
+
+// precedes reports whether node a has a higher priority than node b. Ties between equal keys are broken by sequence
+// number.
+func (h *Heap[K, V]) precedes(a, b *Node[K, V]) bool {
+	if h.less(a.key, b.key) {
+		return true
+	}
+	return a.seq < b.seq && !h.less(b.key, a.key)
+}
+
+// swap swaps two nodes in the array, keeping their indices in sync.
+func (h *Heap[K, V]) swap(i, j int) {
+	h.array[i], h.array[j] = h.array[j], h.array[i]
+	h.array[i].index = i
+	h.array[j].index = j
+}
+
 func (h *Heap[K, V]) heapifyUp(i int) {
 	if i <= 0 {
 		return
 	}
 
 	parentIndex := (i - 1) / 2
-	if h.array[i].key < h.array[parentIndex].key {
-		h.array[i], h.array[parentIndex] = h.array[parentIndex], h.array[i]
+	if h.precedes(h.array[i], h.array[parentIndex]) {
+		h.swap(i, parentIndex)
 		h.heapifyUp(parentIndex)
 	}
 }
@@ -119,16 +268,16 @@ func (h *Heap[K, V]) heapifyDown(i int) {
 	rightChild := 2*i + 2
 	smallest := i
 
-	if leftChild < size && h.array[leftChild].key < h.array[smallest].key {
+	if leftChild < size && h.precedes(h.array[leftChild], h.array[smallest]) {
 		smallest = leftChild
 	}
 
-	if rightChild < size && h.array[rightChild].key < h.array[smallest].key {
+	if rightChild < size && h.precedes(h.array[rightChild], h.array[smallest]) {
 		smallest = rightChild
 	}
 
 	if smallest != i {
-		h.array[i], h.array[smallest] = h.array[smallest], h.array[i]
+		h.swap(i, smallest)
 		h.heapifyDown(smallest)
 	}
 }
@@ -0,0 +1,27 @@
+package binary
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalid is returned by Validate when a Heap's invariants do not hold.
+var ErrInvalid = errors.New("binary: invalid heap")
+
+// Validate checks the invariants of the Heap in Θ(n): that no node has a higher priority than its parent, and that
+// every node records its own index in the heap's array. It returns an error wrapping ErrInvalid that describes the
+// first violation found, or nil if the Heap is valid.
+func (h *Heap[K, V]) Validate() error {
+	for i, n := range h.array {
+		if n == nil {
+			return fmt.Errorf("%w: node at index %d is nil", ErrInvalid, i)
+		}
+		if n.index != i {
+			return fmt.Errorf("%w: node at index %d records index %d", ErrInvalid, i, n.index)
+		}
+		if i > 0 && h.precedes(n, h.array[(i-1)/2]) {
+			return fmt.Errorf("%w: node at index %d precedes its parent at index %d", ErrInvalid, i, (i-1)/2)
+		}
+	}
+	return nil
+}
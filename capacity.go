@@ -0,0 +1,193 @@
+package pqueue
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+)
+
+var (
+	// ErrFull is returned when an element is pushed onto a full queue that uses OverflowError, or when a Meld would
+	// overflow a queue that uses OverflowBlock or OverflowError.
+	ErrFull = errors.New("pqueue: queue is full")
+	// ErrUnsupportedPolicy is the panic value used when a queue is constructed with an OverflowPolicy it does not
+	// support.
+	ErrUnsupportedPolicy = errors.New("pqueue: overflow policy is not supported by this queue")
+)
+
+// OverflowPolicy determines what a bounded queue does when an element is pushed while it is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the pusher until room becomes available. This is the default.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowError rejects the incoming element with ErrFull.
+	OverflowError
+	// OverflowDrop silently discards the incoming element.
+	OverflowDrop
+	// OverflowEvictMax evicts the element with the lowest priority (i.e., the largest key) to make room. If the
+	// incoming element would itself have the lowest priority, it is discarded instead. It is only supported by
+	// heap-based queues, and finding the element to evict costs O(n).
+	OverflowEvictMax
+	// OverflowOverwriteOldest evicts the oldest element to make room. It is only supported by CircularBuffer.
+	OverflowOverwriteOldest
+)
+
+// WithCapacity bounds the queue to at most capacity elements, with policy determining what happens when an element is
+// pushed onto a full queue. A capacity of zero or less leaves the queue unbounded. It is supported by heap-based queues
+// and CircularBuffer.
+//
+// Melding into a bounded queue never blocks. If the combined size would exceed the capacity, Meld fails with ErrFull
+// under OverflowBlock and OverflowError, leaving both queues untouched. Under every other policy, the queues are melded
+// and then trimmed back down to capacity: heap-based queues evict their lowest-priority elements, while CircularBuffer
+// discards its newest elements under OverflowDrop and its oldest elements under OverflowOverwriteOldest.
+func WithCapacity(capacity int, policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.set |= optCapacity
+		o.capacity = max(capacity, 0)
+		o.overflow = policy
+	}
+}
+
+// heapPolicies are the overflow policies supported by every heap-based queue.
+var heapPolicies = []OverflowPolicy{OverflowBlock, OverflowError, OverflowDrop, OverflowEvictMax}
+
+// bound holds the capacity of a queue, along with the goroutines waiting for it to have room. Its methods must be
+// called with the queue's lock held.
+type bound struct {
+	capacity int
+	policy   OverflowPolicy
+	notFull  waiters
+}
+
+func newBound(o options, supported ...OverflowPolicy) bound {
+	if !slices.Contains(supported, o.overflow) {
+		panic(ErrUnsupportedPolicy)
+	}
+
+	return bound{
+		capacity: o.capacity,
+		policy:   o.overflow,
+	}
+}
+
+// clone returns a copy of bd without any of its waiters.
+func (bd *bound) clone() bound {
+	return bound{
+		capacity: bd.capacity,
+		policy:   bd.policy,
+	}
+}
+
+// admit makes room for one more element, as dictated by the overflow policy. It reports whether the element should be
+// pushed; if it should be discarded instead, admit returns false without an error. Under OverflowBlock, admit releases
+// l while waiting, and reacquires it with mt, the meter of l's queue, before returning. admit fails with ErrClosed if c
+// is closed, including while waiting. evict is called under OverflowEvictMax and OverflowOverwriteOldest, and reports
+// whether room was made for the incoming element.
+func (bd *bound) admit(ctx context.Context, mt *meter, l *sync.RWMutex, c *closer, size func() int, evict func() bool) (bool, error) {
+	for {
+		if c.closed {
+			return false, ErrClosed
+		}
+		if bd.capacity == 0 || size() < bd.capacity {
+			return true, nil
+		}
+
+		switch bd.policy {
+		case OverflowBlock:
+			ch := bd.notFull.wait()
+			l.Unlock()
+
+			select {
+			case <-ch:
+				mt.lock(l)
+			case <-ctx.Done():
+				mt.lock(l)
+				return false, ctx.Err()
+			}
+		case OverflowError:
+			return false, ErrFull
+		case OverflowDrop:
+			return false, nil
+		default:
+			return evict(), nil
+		}
+	}
+}
+
+// room reports whether a queue of the provided size can take incoming more elements without exceeding its capacity.
+func (bd *bound) room(size, incoming int) bool {
+	return bd.capacity == 0 || size+incoming <= bd.capacity
+}
+
+// fits returns ErrFull if a queue of the provided size cannot take incoming more elements without evicting any, and its
+// policy does not allow for evictions.
+func (bd *bound) fits(size, incoming int) error {
+	if bd.room(size, incoming) {
+		return nil
+	}
+
+	if bd.policy == OverflowBlock || bd.policy == OverflowError {
+		return ErrFull
+	}
+	return nil
+}
+
+// excess returns the number of elements that a queue of the provided size must evict to fit within its capacity.
+func (bd *bound) excess(size int) int {
+	if bd.capacity == 0 {
+		return 0
+	}
+	return max(size-bd.capacity, 0)
+}
+
+// prioritized is implemented by the nodes of every heap package.
+type prioritized[K any] interface {
+	Key() K
+	Seq() uint64
+}
+
+// comparePriority compares two nodes such that the node with the higher priority (i.e., the smaller key, with ties
+// broken by sequence number) is ordered first.
+func comparePriority[K any, N prioritized[K]](a, b N, less func(a, b K) bool) int {
+	switch {
+	case less(a.Key(), b.Key()):
+		return -1
+	case less(b.Key(), a.Key()):
+		return 1
+	}
+	return cmp.Compare(a.Seq(), b.Seq())
+}
+
+// lowest returns the node with the lowest priority in O(n).
+func lowest[K any, N prioritized[K]](nodes iter.Seq[N], less func(a, b K) bool) (lowest N, ok bool) {
+	for n := range nodes {
+		if !ok || comparePriority(n, lowest, less) > 0 {
+			lowest, ok = n, true
+		}
+	}
+	return lowest, ok
+}
+
+// lowestN returns the n nodes with the lowest priorities in O(n log n), from the lowest priority upwards.
+func lowestN[K any, N prioritized[K]](nodes iter.Seq[N], less func(a, b K) bool, n int) []N {
+	all := slices.Collect(nodes)
+	slices.SortFunc(all, func(a, b N) int {
+		return comparePriority(b, a, less)
+	})
+
+	return all[:min(n, len(all))]
+}
+
+// outranks reports whether an incoming element with the provided priority would have a higher priority than an
+// existing element. Since the incoming element would be the newest, ties between equal keys are only won under
+// TieBreakLIFO.
+func outranks[K any, N prioritized[K]](priority K, existing N, less func(a, b K) bool, tieBreak TieBreak) bool {
+	if less(priority, existing.Key()) {
+		return true
+	}
+	return tieBreak == TieBreakLIFO && !less(existing.Key(), priority)
+}
@@ -0,0 +1,117 @@
+package pqueue
+
+import (
+	"context"
+	"time"
+)
+
+// Feed pushes every item received from in onto q, which lets a queue be used as a priority-reordering stage between two
+// goroutines. Feed stops receiving while q is full under OverflowBlock, so a bounded queue applies backpressure to the
+// producer. Once in is closed, Feed closes q and returns nil, so that a Stream reading from q ends once every remaining
+// element has been delivered. Otherwise, Feed returns ctx's error if ctx is done first, or the error from a failed push
+// (e.g., ErrFull under OverflowError), leaving q open.
+func Feed[K, V any](ctx context.Context, q Queue[K, V], in <-chan Item[K, V]) error {
+	for {
+		select {
+		case item, ok := <-in:
+			if !ok {
+				q.Close()
+				return nil
+			}
+
+			if err := q.PushWait(ctx, item.Value, item.Priority); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Stream returns a channel that delivers the elements of q in priority order. Whenever the consumer is ready, it
+// receives the element that has the highest priority at that moment, even if that element was pushed while Stream was
+// waiting on the consumer.
+//
+// The channel is closed once q has been closed and drained, or once ctx is done. In the latter case, the element that
+// Stream was waiting to deliver, if any, is returned to q, even if q has since filled up to its capacity. Elements are
+// reported as popped to q's Metrics and Observer once they have been delivered, and elements that Stream returns to q
+// are not reported as pushed again. Stream panics with IncompatibleQueueError if q does not come from this package.
+func Stream[K, V any](ctx context.Context, q Queue[K, V]) <-chan Item[K, V] {
+	s, ok := q.(streamer[K, V])
+	if !ok {
+		panic(IncompatibleQueueError)
+	}
+
+	out := make(chan Item[K, V])
+	go func() {
+		defer close(out)
+
+		for {
+			h, err := s.takeWait(ctx)
+			if err != nil {
+				return
+			}
+
+			if !stream(ctx, s, h, out) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// streamer is implemented by every queue that Stream can read from.
+type streamer[K, V any] interface {
+	crossMelder[K, V]
+
+	// takeWait is like takeLocked, but blocks until the queue is non-empty.
+	takeWait(ctx context.Context) (held[K, V], error)
+	// takeLocked removes the element with the highest priority without counting it as popped.
+	takeLocked() (held[K, V], bool)
+	// requeueLocked puts an element that was removed with takeLocked back into the queue, without counting it as
+	// pushed.
+	requeueLocked(h held[K, V])
+	// pushedLocked returns a channel that is closed the next time elements are added to the queue.
+	pushedLocked() <-chan struct{}
+}
+
+// held is an element that Stream has removed from a queue, but has yet to deliver.
+type held[K, V any] struct {
+	entry[K, V]
+	// pushedAt is the time at which the element was pushed, if the queue has Metrics.
+	pushedAt time.Time
+}
+
+// stream sends h to out. Whenever elements are pushed onto s in the meantime, h is exchanged for the element with the
+// highest priority in s, which may be h itself. stream returns false if ctx is done before the send succeeds, in which
+// case h is returned to s.
+func stream[K, V any](ctx context.Context, s streamer[K, V], h held[K, V], out chan<- Item[K, V]) bool {
+	l, mt := s.mutex(), s.meterLocked()
+
+	mt.lock(l)
+	pushed := s.pushedLocked()
+	l.Unlock()
+
+	for {
+		select {
+		case out <- Item[K, V]{h.key, h.value}:
+			mt.lock(l)
+			mt.poppedAt(h.pushedAt)
+			s.observerLocked().pop(h.key, h.value)
+			l.Unlock()
+			return true
+		case <-pushed:
+			mt.lock(l)
+			s.requeueLocked(h)
+			h, _ = s.takeLocked()
+			pushed = s.pushedLocked()
+			l.Unlock()
+		case <-ctx.Done():
+			mt.lock(l)
+			s.requeueLocked(h)
+			l.Unlock()
+			return false
+		}
+	}
+}
@@ -1,12 +1,12 @@
 package pqueue
 
 import (
+	"context"
+	"fmt"
+	"iter"
 	"sync"
-	"sync/atomic"
 )
 
-var circularBufferIDCounter atomic.Uint64
-
 type node[T any] struct {
 	value T
 	left  *node[T]
@@ -17,40 +17,142 @@ type CircularBuffer[T any] struct {
 	l  sync.RWMutex
 	id uint64
 
-	size int
-	root *node[T]
+	notEmpty waiters
+	bound    bound
+	closer   closer
+
+	size     int
+	root     *node[T]
+	codec    Codec[T]
+	meter    meter
+	observer observer[any, T]
 }
 
-func NewCircularBuffer[T any]() *CircularBuffer[T] {
+// NewCircularBuffer creates an empty CircularBuffer. WithCapacity, WithValueCodec, WithMetrics and WithObserver are the
+// only options it supports, and it supports every OverflowPolicy except OverflowEvictMax.
+func NewCircularBuffer[T any](opts ...Option) *CircularBuffer[T] {
+	o := newOptions(opts, optCapacity|optMetrics|optObserver|optValueCodec)
+
 	return &CircularBuffer[T]{
-		root: nil,
-		id:   circularBufferIDCounter.Add(1),
+		root:     nil,
+		id:       idCounter.Add(1),
+		bound:    newBound(o, OverflowBlock, OverflowError, OverflowDrop, OverflowOverwriteOldest),
+		closer:   newCloser(),
+		codec:    codecOf[T](o.valueCodec),
+		meter:    newMeter(o),
+		observer: newObserver[any, T](o),
 	}
 }
 
+// CollectCircularBuffer builds a CircularBuffer from the values of seq, in order. If there are more values than the
+// buffer's capacity, the oldest values are discarded under OverflowOverwriteOldest, and the newest values otherwise.
+func CollectCircularBuffer[T any](seq iter.Seq[T], opts ...Option) *CircularBuffer[T] {
+	cb := NewCircularBuffer[T](opts...)
+	for v := range seq {
+		cb.pushLocked(v)
+	}
+	cb.trimLocked()
+
+	return cb
+}
+
 func (cb *CircularBuffer[T]) Size() int {
-	cb.l.RLock()
+	cb.meter.rlock(&cb.l)
 	defer cb.l.RUnlock()
 
 	return cb.size
 }
 
 func (cb *CircularBuffer[T]) Clear() {
-	cb.l.Lock()
+	cb.meter.lock(&cb.l)
 	defer cb.l.Unlock()
 
-	cb.root = nil
-	cb.size = 0
+	cb.observer.clear(cb.size)
+	cb.clearLocked()
+	cb.meter.cleared()
 }
 
-func (cb *CircularBuffer[T]) Push(v T) {
-	cb.l.Lock()
+// Push appends a value to the back of the buffer. If the buffer is full, Push follows the buffer's OverflowPolicy; see
+// WithCapacity.
+func (cb *CircularBuffer[T]) Push(v T) error {
+	return cb.PushWait(context.Background(), v)
+}
+
+// PushWait is like Push, but returns ctx's error if ctx is done while blocked on a full buffer.
+func (cb *CircularBuffer[T]) PushWait(ctx context.Context, v T) error {
+	cb.meter.lock(&cb.l)
+	defer cb.l.Unlock()
+
+	ok, err := cb.bound.admit(ctx, &cb.meter, &cb.l, &cb.closer, cb.sizeLocked, func() bool {
+		oldest, _ := cb.removeOldestLocked()
+		cb.observer.drop(nil, oldest)
+		return true
+	})
+	switch {
+	case ok:
+		cb.pushLocked(v)
+		cb.observer.push(nil, v)
+	case err == nil:
+		cb.observer.drop(nil, v)
+	}
+	return err
+}
+
+func (cb *CircularBuffer[T]) Pop() (v T, ok bool) {
+	cb.meter.lock(&cb.l)
 	defer cb.l.Unlock()
 
+	return cb.popLocked()
+}
+
+// PopWait removes and returns the oldest value, blocking until the buffer is non-empty. If ctx is done first, PopWait
+// returns ctx's error.
+func (cb *CircularBuffer[T]) PopWait(ctx context.Context) (T, error) {
+	return waitFor(ctx, &cb.meter, &cb.l, &cb.notEmpty, &cb.closer, cb.popLocked)
+}
+
+// Close closes the buffer. Subsequent pushes and melds into the buffer fail with ErrClosed, and every goroutine blocked
+// in PopWait or PushWait is woken. Values that remain in the buffer can still be popped, and PopWait returns ErrClosed
+// once the buffer is empty. Calling Close more than once has no effect.
+func (cb *CircularBuffer[T]) Close() {
+	cb.meter.lock(&cb.l)
+	defer cb.l.Unlock()
+
+	cb.closer.close(cb.size)
+	cb.notEmpty.broadcast()
+	cb.bound.notFull.broadcast()
+}
+
+// IsClosed reports whether Close has been called.
+func (cb *CircularBuffer[T]) IsClosed() bool {
+	cb.meter.rlock(&cb.l)
+	defer cb.l.RUnlock()
+
+	return cb.closer.closed
+}
+
+// Done returns a channel that is closed once the buffer has been closed and every remaining value has been removed.
+func (cb *CircularBuffer[T]) Done() <-chan struct{} {
+	return cb.closer.done
+}
+
+func (cb *CircularBuffer[T]) pushLocked(v T) {
+	cb.appendLocked(v)
+	cb.meter.pushed(1)
+	cb.meter.depth(cb.size)
+}
+
+// appendLocked appends a value to the back of the buffer without counting it as pushed or reporting the new depth,
+// e.g., when the buffer is cloned or restored.
+func (cb *CircularBuffer[T]) appendLocked(v T) {
+	defer cb.notEmpty.broadcast()
+
 	newNode := &node[T]{
 		value: v,
 	}
 
+	cb.meter.stamp(newNode)
+
 	// If the buffer is empty, simply set cb.root to newNode.
 	if cb.root == nil {
 		newNode.left = newNode
@@ -75,15 +177,43 @@ func (cb *CircularBuffer[T]) Push(v T) {
 	cb.size++
 }
 
-func (cb *CircularBuffer[T]) Pop() (v T, ok bool) {
-	cb.l.Lock()
-	defer cb.l.Unlock()
+func (cb *CircularBuffer[T]) sizeLocked() int {
+	return cb.size
+}
 
+func (cb *CircularBuffer[T]) clearLocked() {
+	cb.meter.forgetAll()
+	cb.root = nil
+	cb.size = 0
+	cb.removedLocked()
+}
+
+// removedLocked is called whenever values are removed from the buffer.
+func (cb *CircularBuffer[T]) removedLocked() {
+	cb.bound.notFull.broadcast()
+	cb.closer.settle(cb.size)
+	cb.meter.depth(cb.size)
+}
+
+func (cb *CircularBuffer[T]) popLocked() (v T, ok bool) {
+	if cb.root != nil {
+		cb.meter.popped(cb.root)
+		cb.observer.pop(nil, cb.root.value)
+	}
+	return cb.removeOldestLocked()
+}
+
+// removeOldestLocked removes and returns the oldest value without counting it as popped, e.g., to discard it.
+func (cb *CircularBuffer[T]) removeOldestLocked() (v T, ok bool) {
 	if cb.root == nil {
 		var zero T
 		return zero, false
 	}
 
+	cb.meter.forget(cb.root)
+
+	defer cb.removedLocked()
+
 	minNode := cb.root
 
 	// If cb is a singleton, simply set cb.root to nil.
@@ -113,44 +243,255 @@ func (cb *CircularBuffer[T]) Pop() (v T, ok bool) {
 }
 
 func (cb *CircularBuffer[T]) Peek() T {
-	cb.l.RLock()
+	v, _ := cb.PeekItem()
+	return v
+}
+
+// PeekItem returns the oldest value without removing it. ok is false if the buffer is empty.
+func (cb *CircularBuffer[T]) PeekItem() (v T, ok bool) {
+	cb.meter.rlock(&cb.l)
 	defer cb.l.RUnlock()
 
 	if cb.root == nil {
-		var zero T
-		return zero
+		return
+	}
+
+	return cb.root.value, true
+}
+
+// All returns an iterator over every value from oldest to newest, without modifying the buffer.
+func (cb *CircularBuffer[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		cb.meter.rlock(&cb.l)
+		values := cb.valuesLocked()
+		cb.l.RUnlock()
+
+		for _, v := range values {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Drain returns an iterator that pops every value from oldest to newest until the buffer is empty.
+func (cb *CircularBuffer[T]) Drain() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			v, ok := cb.Pop()
+			if !ok || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Validate checks that the values of the buffer are linked into a consistent ring, that the buffer's size matches the
+// number of values in the ring, and that its size fits within its capacity.
+func (cb *CircularBuffer[T]) Validate() error {
+	cb.meter.rlock(&cb.l)
+	defer cb.l.RUnlock()
+
+	// A ring that does not lead back to its root would never end, so the walk is cut short past the expected size.
+	count := 0
+	for n := cb.root; n != nil; n = n.right {
+		if n.right == nil || n.right.left != n {
+			return fmt.Errorf("%w: value %d of %d is not linked back by the next value", ErrInvalid, count, cb.size)
+		}
+
+		count++
+		if n.right == cb.root || count > cb.size {
+			break
+		}
 	}
+	return validateSize(count, cb.size, &cb.bound)
+}
+
+// Inspect returns the Stats of the buffer, with up to top of its oldest values.
+func (cb *CircularBuffer[T]) Inspect(top int) Stats {
+	cb.meter.rlock(&cb.l)
+	defer cb.l.RUnlock()
+
+	stats := Stats{
+		Kind: "CircularBuffer",
+		ID:   cb.id,
+		Size: cb.size,
+		Top:  make([]Item[any, any], 0, min(max(top, 0), cb.size)),
+	}
+	for n := cb.root; len(stats.Top) < top && n != nil; n = n.right {
+		stats.Top = append(stats.Top, Item[any, any]{nil, n.value})
+		if n.right == cb.root {
+			break
+		}
+	}
+	return stats
+}
+
+// valuesLocked returns a snapshot of every value in the buffer, from oldest to newest.
+func (cb *CircularBuffer[T]) valuesLocked() []T {
+	values := make([]T, 0, cb.size)
+	for n := range cb.nodesLocked() {
+		values = append(values, n.value)
+	}
+	return values
+}
+
+// nodesLocked returns an iterator over the nodes of the buffer, from oldest to newest.
+func (cb *CircularBuffer[T]) nodesLocked() iter.Seq[*node[T]] {
+	return func(yield func(*node[T]) bool) {
+		if cb.root == nil {
+			return
+		}
+
+		n := cb.root
+		for yield(n) {
+			n = n.right
+			if n == cb.root {
+				return
+			}
+		}
+	}
+}
+
+// Clone returns an independent copy of the buffer, which holds its values in the same order.
+func (cb *CircularBuffer[T]) Clone() *CircularBuffer[T] {
+	return cb.CloneFunc(nil)
+}
+
+// CloneFunc is like Clone, but copies every value with copyValue.
+func (cb *CircularBuffer[T]) CloneFunc(copyValue func(T) T) *CircularBuffer[T] {
+	cb.meter.rlock(&cb.l)
+	defer cb.l.RUnlock()
+
+	c := &CircularBuffer[T]{
+		id:       idCounter.Add(1),
+		bound:    cb.bound.clone(),
+		closer:   newCloser(),
+		codec:    cb.codec,
+		observer: cb.observer,
+	}
+
+	for _, v := range cb.valuesLocked() {
+		if copyValue != nil {
+			v = copyValue(v)
+		}
+		c.appendLocked(v)
+	}
+	return c
+}
+
+// MarshalBinary encodes every value of the buffer, from oldest to newest, in a versioned binary format using the
+// buffer's codec (see WithValueCodec).
+func (cb *CircularBuffer[T]) MarshalBinary() ([]byte, error) {
+	cb.meter.rlock(&cb.l)
+	values := cb.valuesLocked()
+	cb.l.RUnlock()
+
+	return marshalFIFO(values, cb.codec)
+}
+
+// UnmarshalBinary replaces the contents of the buffer with the values encoded in data by MarshalBinary. The buffer must
+// have been created by its constructor, and keeps its own options.
+func (cb *CircularBuffer[T]) UnmarshalBinary(data []byte) error {
+	if cb.codec == nil {
+		return ErrUninitialized
+	}
+
+	values, err := unmarshalFIFO(data, cb.codec)
+	if err != nil {
+		return err
+	}
+	return cb.restore(values)
+}
+
+// MarshalJSON is like MarshalBinary, but encodes the values as JSON.
+func (cb *CircularBuffer[T]) MarshalJSON() ([]byte, error) {
+	cb.meter.rlock(&cb.l)
+	values := cb.valuesLocked()
+	cb.l.RUnlock()
+
+	return marshalFIFOJSON(values, cb.codec)
+}
 
-	return cb.root.value
+// UnmarshalJSON is like UnmarshalBinary, but decodes values encoded by MarshalJSON.
+func (cb *CircularBuffer[T]) UnmarshalJSON(data []byte) error {
+	if cb.codec == nil {
+		return ErrUninitialized
+	}
+
+	values, err := unmarshalFIFOJSON(data, cb.codec)
+	if err != nil {
+		return err
+	}
+	return cb.restore(values)
+}
+
+// GobEncode encodes the buffer in the same format as MarshalBinary.
+func (cb *CircularBuffer[T]) GobEncode() ([]byte, error) {
+	return cb.MarshalBinary()
 }
 
-func (cb *CircularBuffer[T]) Meld(other *CircularBuffer[T]) {
-	if cb.id < other.id {
-		cb.l.Lock()
-		other.l.Lock()
-	} else if cb.id > other.id {
-		other.l.Lock()
-		cb.l.Lock()
-	} else {
-		panic(ConcurrencySafetyError)
+// GobDecode decodes the buffer in the same way as UnmarshalBinary.
+func (cb *CircularBuffer[T]) GobDecode(data []byte) error {
+	return cb.UnmarshalBinary(data)
+}
+
+// restore replaces the contents of the buffer with the provided values, discarding values that do not fit within its
+// capacity as trimLocked does.
+func (cb *CircularBuffer[T]) restore(values []T) error {
+	cb.meter.lock(&cb.l)
+	defer cb.l.Unlock()
+
+	if cb.closer.closed {
+		return ErrClosed
 	}
 
+	cb.clearLocked()
+	for _, v := range values {
+		cb.appendLocked(v)
+	}
+	cb.trimLocked()
+	cb.meter.depth(cb.size)
+	return nil
+}
+
+// Meld appends every value of other to the back of cb and clears other.
+func (cb *CircularBuffer[T]) Meld(other *CircularBuffer[T]) error {
+	cb.meter.lockPair(&cb.l, cb.id, &other.l, other.id)
+
 	defer func() {
 		cb.l.Unlock()
 		other.l.Unlock()
 	}()
 
+	if cb.closer.closed {
+		return ErrClosed
+	}
 	if other.root == nil {
-		return
+		return nil
+	}
+
+	if err := cb.bound.fits(cb.size, other.size); err != nil {
+		return err
 	}
 
+	defer cb.notEmpty.broadcast()
+	defer other.removedLocked()
+	defer func() { cb.meter.depth(cb.size) }()
+	defer cb.trimLocked()
+
+	adopt(&cb.meter, &other.meter, other.nodesLocked())
+	cb.meter.melded(other.size)
+	cb.observer.meld(other.size)
+	other.meter.forgetAll()
+
 	if cb.root == nil {
 		cb.root = other.root
 		cb.size = other.size
 
 		other.root = nil
 		other.size = 0
-		return
+		return nil
 	}
 
 	cbLast := cb.root.left
@@ -176,4 +517,37 @@ func (cb *CircularBuffer[T]) Meld(other *CircularBuffer[T]) {
 	// Clear other.
 	other.root = nil
 	other.size = 0
+
+	return nil
+}
+
+// trimLocked discards values until the buffer fits within its capacity. The oldest values are discarded under
+// OverflowOverwriteOldest, and the newest values otherwise.
+func (cb *CircularBuffer[T]) trimLocked() {
+	for range cb.bound.excess(cb.size) {
+		var v T
+		if cb.bound.policy == OverflowOverwriteOldest {
+			v, _ = cb.removeOldestLocked()
+		} else {
+			v = cb.popBackLocked()
+		}
+		cb.observer.drop(nil, v)
+	}
+}
+
+// popBackLocked removes and returns the newest value from the buffer, which must not be empty.
+func (cb *CircularBuffer[T]) popBackLocked() T {
+	tail := cb.root.left
+	cb.meter.forget(tail)
+	if cb.size == 1 {
+		cb.root = nil
+		cb.size = 0
+		return tail.value
+	}
+
+	tail.left.right = cb.root
+	cb.root.left = tail.left
+
+	cb.size--
+	return tail.value
 }
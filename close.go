@@ -0,0 +1,42 @@
+package pqueue
+
+import "errors"
+
+// ErrClosed is returned when pushing onto or melding into a queue that has been closed, and by PopWait once a closed
+// queue has been drained.
+var ErrClosed = errors.New("pqueue: queue is closed")
+
+// closer tracks whether a queue has been closed. Its methods must be called with the queue's lock held.
+type closer struct {
+	closed bool
+	// done is closed once the queue has been closed and drained.
+	done chan struct{}
+}
+
+func newCloser() closer {
+	return closer{done: make(chan struct{})}
+}
+
+// close marks the queue as closed, given its current size. It is a no-op if the queue is already closed.
+func (c *closer) close(size int) {
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+	c.settle(size)
+}
+
+// settle closes done if the queue has been closed and drained. It must be called whenever elements are removed from
+// the queue.
+func (c *closer) settle(size int) {
+	if !c.closed || size > 0 {
+		return
+	}
+
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+}
@@ -0,0 +1,82 @@
+package pqueue
+
+import (
+	"iter"
+	"maps"
+	"sync"
+)
+
+// Stats describes the state of a queue at a point in time, for debugging.
+type Stats struct {
+	// Kind is the type of the queue, e.g., "Pairing".
+	Kind string
+	ID   uint64
+	Size int
+	// Top holds the elements with the highest priorities, in priority order. The elements of a CircularBuffer are
+	// listed from oldest to newest, with nil priorities.
+	Top []Item[any, any]
+	// Shape holds statistics about the internal structure of the queue, which depend on its kind:
+	//
+	//	Binary        arrayLength and arrayCapacity, the length and capacity of the heap's array
+	//	Pairing       rootChildren, the number of children of the root of the heap
+	//	SkewBinomial  treeRanks, the rank of every tree in the heap's forest
+	Shape map[string]any
+}
+
+// Inspector is implemented by every queue that can be registered with Register.
+type Inspector interface {
+	// Inspect returns the Stats of the queue, with up to top of its elements.
+	Inspect(top int) Stats
+}
+
+// registry holds the queues registered with Register.
+var registry struct {
+	l      sync.RWMutex
+	queues map[string]Inspector
+}
+
+// Register registers q under name, replacing any queue previously registered under that name, so that its Stats can be
+// served by the httpdebug package. Registering is opt-in, and keeps q from being garbage collected until it is
+// unregistered with Unregister.
+func Register(name string, q Inspector) {
+	registry.l.Lock()
+	defer registry.l.Unlock()
+
+	if registry.queues == nil {
+		registry.queues = make(map[string]Inspector)
+	}
+	registry.queues[name] = q
+}
+
+// Unregister removes the queue registered under name, if any.
+func Unregister(name string) {
+	registry.l.Lock()
+	defer registry.l.Unlock()
+
+	delete(registry.queues, name)
+}
+
+// Registered returns an iterator over every registered queue and its name, in no particular order.
+func Registered() iter.Seq2[string, Inspector] {
+	registry.l.RLock()
+	queues := maps.Clone(registry.queues)
+	registry.l.RUnlock()
+
+	return maps.All(queues)
+}
+
+// topOf returns up to n of the provided entries with the highest priorities, in priority order.
+func topOf[K, V any](entries []entry[K, V], less func(a, b K) bool, n int) []Item[any, any] {
+	top := make([]Item[any, any], 0, min(max(n, 0), len(entries)))
+	if n <= 0 {
+		return top
+	}
+
+	for priority, v := range orderedSeq(entries, less) {
+		top = append(top, Item[any, any]{priority, v})
+		if len(top) == n {
+			break
+		}
+	}
+	return top
+}
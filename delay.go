@@ -0,0 +1,244 @@
+package pqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AndrewChon/pqueue/pairing"
+)
+
+// Clock tells the time for a DelayQueue, and wakes it up when a deadline passes. DelayQueue uses the system clock by
+// default; a different Clock can be injected with WithClock, e.g., to control time deterministically in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the current time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// WithClock makes a DelayQueue or Leaser tell the time with c. No other queue supports it.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.set |= optClock
+		o.clock = c
+	}
+}
+
+// DelayQueue is a concurrency-safe queue of values that only become poppable once their deadline has passed. Values
+// are popped in order of their deadlines, and values with equal deadlines are ordered by the queue's TieBreak (see
+// WithTieBreak). DelayQueue is built on a pairing heap, so pushing costs Θ(1). It is unbounded.
+type DelayQueue[V any] struct {
+	l  sync.RWMutex
+	id uint64
+
+	// notEmpty is broadcast whenever a value is pushed, which wakes Take up to reschedule itself in case the value has
+	// an earlier deadline than the one it is sleeping until.
+	notEmpty waiters
+	closer   closer
+
+	root     *pairing.Tree[time.Time, V]
+	size     int
+	tieBreak TieBreak
+	clock    Clock
+	meter    meter
+}
+
+func NewDelayQueue[V any](opts ...Option) *DelayQueue[V] {
+	o := newOptions(opts, optTieBreak|optClock|optMetrics)
+
+	clock := o.clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	return &DelayQueue[V]{
+		id:       idCounter.Add(1),
+		closer:   newCloser(),
+		tieBreak: o.tieBreak,
+		clock:    clock,
+		meter:    newMeter(o),
+	}
+}
+
+func (d *DelayQueue[V]) Size() int {
+	d.meter.rlock(&d.l)
+	defer d.l.RUnlock()
+
+	return d.size
+}
+
+func (d *DelayQueue[V]) Clear() {
+	d.meter.lock(&d.l)
+	defer d.l.Unlock()
+
+	d.root = nil
+	d.size = 0
+	d.closer.settle(0)
+	d.meter.forgetAll()
+	d.meter.cleared()
+	d.meter.depth(0)
+}
+
+// Peek returns the value with the earliest deadline without removing it and regardless of whether the deadline has
+// passed, or the zero value if the queue is empty.
+func (d *DelayQueue[V]) Peek() V {
+	_, v, _ := d.PeekItem()
+	return v
+}
+
+// PeekItem returns the value with the earliest deadline along with its deadline, without removing it and regardless of
+// whether the deadline has passed. ok is false if the queue is empty.
+func (d *DelayQueue[V]) PeekItem() (deadline time.Time, v V, ok bool) {
+	d.meter.rlock(&d.l)
+	defer d.l.RUnlock()
+
+	if d.root == nil {
+		return
+	}
+	return d.root.Key(), d.root.Value(), true
+}
+
+// Push inserts a value that becomes poppable once deadline has passed. If the value has an earlier deadline than every
+// other value, goroutines blocked in Take are woken up to wait for the new deadline instead.
+func (d *DelayQueue[V]) Push(v V, deadline time.Time) error {
+	d.meter.lock(&d.l)
+	defer d.l.Unlock()
+
+	if d.closer.closed {
+		return ErrClosed
+	}
+
+	newTree := pairing.NewSequencedTree(deadline, v, d.tieBreak.nextSeq())
+	d.root = pairing.InsertFunc(d.root, newTree, before)
+	d.size++
+	d.meter.stamp(newTree)
+	d.meter.pushed(1)
+	d.meter.depth(d.size)
+	d.notEmpty.broadcast()
+	return nil
+}
+
+// PushAfter is like Push, but the value becomes poppable once delay has elapsed, as measured by the queue's Clock.
+func (d *DelayQueue[V]) PushAfter(v V, delay time.Duration) error {
+	return d.Push(v, d.clock.Now().Add(delay))
+}
+
+// PopReady removes and returns the value with the earliest deadline if that deadline has passed. ok is false if the
+// queue is empty or no deadline has passed yet.
+func (d *DelayQueue[V]) PopReady() (v V, ok bool) {
+	_, v, ok = d.PopReadyItem()
+	return v, ok
+}
+
+// PopReadyItem is like PopReady, but also returns the deadline of the value.
+func (d *DelayQueue[V]) PopReadyItem() (deadline time.Time, v V, ok bool) {
+	d.meter.lock(&d.l)
+	defer d.l.Unlock()
+
+	return d.popReadyLocked(d.clock.Now())
+}
+
+// Take removes and returns the value with the earliest deadline, sleeping until that deadline has passed. Pushing a
+// value with an earlier deadline reschedules the sleep. Take returns ctx's error if ctx is done first, or ErrClosed
+// once a closed queue has been drained.
+func (d *DelayQueue[V]) Take(ctx context.Context) (V, error) {
+	_, v, err := d.TakeItem(ctx)
+	return v, err
+}
+
+// TakeItem is like Take, but also returns the deadline of the value.
+func (d *DelayQueue[V]) TakeItem(ctx context.Context) (deadline time.Time, v V, err error) {
+	for {
+		d.meter.lock(&d.l)
+		now := d.clock.Now()
+		if deadline, v, ok := d.popReadyLocked(now); ok {
+			d.l.Unlock()
+			return deadline, v, nil
+		}
+		if d.root == nil && d.closer.closed {
+			d.l.Unlock()
+			return deadline, v, ErrClosed
+		}
+
+		// Wait until either the earliest deadline passes or a value is pushed, whichever comes first.
+		var expired <-chan time.Time
+		if d.root != nil {
+			expired = d.clock.After(d.root.Key().Sub(now))
+		}
+		pushed := d.notEmpty.wait()
+		d.l.Unlock()
+
+		select {
+		case <-expired:
+		case <-pushed:
+		case <-ctx.Done():
+			return deadline, v, ctx.Err()
+		}
+	}
+}
+
+// Close closes the queue. Subsequent pushes fail with ErrClosed, but remaining values can still be popped once their
+// deadlines pass.
+func (d *DelayQueue[V]) Close() {
+	d.meter.lock(&d.l)
+	defer d.l.Unlock()
+
+	d.closer.close(d.size)
+	d.notEmpty.broadcast()
+}
+
+// IsClosed reports whether Close has been called.
+func (d *DelayQueue[V]) IsClosed() bool {
+	d.meter.rlock(&d.l)
+	defer d.l.RUnlock()
+
+	return d.closer.closed
+}
+
+// Done returns a channel that is closed once the queue has been closed and every remaining value has been removed.
+func (d *DelayQueue[V]) Done() <-chan struct{} {
+	return d.closer.done
+}
+
+// Validate checks the invariants of the pairing heap that backs the queue (see pairing.Validate), and that the queue's
+// size matches the number of values in its heap.
+func (d *DelayQueue[V]) Validate() error {
+	d.meter.rlock(&d.l)
+	defer d.l.RUnlock()
+
+	if err := pairing.ValidateFunc(d.root, before); err != nil {
+		return invalid(err)
+	}
+	return validateSize(countOf(d.root.Nodes()), d.size, nil)
+}
+
+func (d *DelayQueue[V]) popReadyLocked(now time.Time) (deadline time.Time, v V, ok bool) {
+	if d.root == nil || d.root.Key().After(now) {
+		return
+	}
+
+	minTree := d.root
+	d.root = pairing.RemoveMinFunc(d.root, before)
+	d.size--
+	d.closer.settle(d.size)
+	d.meter.popped(minTree)
+	d.meter.depth(d.size)
+	return minTree.Key(), minTree.Value(), true
+}
+
+// before orders deadlines for the pairing heap.
+func before(a, b time.Time) bool {
+	return a.Before(b)
+}
@@ -0,0 +1,100 @@
+package durable
+
+import (
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// Option configures a Queue when it is opened.
+type Option func(*options)
+
+type options struct {
+	sync            SyncPolicy
+	syncInterval    time.Duration
+	checkpointEvery int
+
+	// keyCodec and valueCodec hold Codecs of the queue's key and value types, which are only known to Open.
+	keyCodec   any
+	valueCodec any
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		sync:         SyncAlways,
+		syncInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// SyncPolicy determines when writes to the log are flushed to stable storage with fsync.
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes the log after every operation, so that no acknowledged operation can be lost. This is the
+	// default.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval flushes the log periodically in the background (see WithSyncInterval). A crash may lose the
+	// operations of the last interval, but never corrupts the queue.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system, except when the Queue is checkpointed or closed.
+	SyncNever
+)
+
+// WithSyncPolicy sets the policy that determines when the log is flushed to stable storage.
+func WithSyncPolicy(p SyncPolicy) Option {
+	return func(o *options) {
+		o.sync = p
+	}
+}
+
+// WithSyncInterval makes the Queue use SyncInterval, flushing the log every d. The default interval, which is also used
+// if d is not positive, is one second.
+func WithSyncInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.sync = SyncInterval
+		if d > 0 {
+			o.syncInterval = d
+		}
+	}
+}
+
+// WithCheckpointEvery makes the Queue checkpoint itself automatically once n operations have been logged since the last
+// checkpoint, which bounds both the size of the log and the time it takes to recover.
+func WithCheckpointEvery(n int) Option {
+	return func(o *options) {
+		o.checkpointEvery = max(n, 0)
+	}
+}
+
+// WithKeyCodec makes the Queue encode keys in its log with c. Open panics with pqueue.ErrCodecMismatch if K is not the
+// queue's key type. Snapshots are encoded by the underlying heap, which has codecs of its own (see
+// pqueue.WithKeyCodec).
+func WithKeyCodec[K any](c pqueue.Codec[K]) Option {
+	return func(o *options) {
+		o.keyCodec = c
+	}
+}
+
+// WithValueCodec is like WithKeyCodec, but for values.
+func WithValueCodec[V any](c pqueue.Codec[V]) Option {
+	return func(o *options) {
+		o.valueCodec = c
+	}
+}
+
+// codecOf returns the Codec stored in an option, or pqueue.JSONCodec if there is none.
+func codecOf[T any](c any) pqueue.Codec[T] {
+	if c == nil {
+		return pqueue.JSONCodec[T]{}
+	}
+
+	codec, ok := c.(pqueue.Codec[T])
+	if !ok {
+		panic(pqueue.ErrCodecMismatch)
+	}
+	return codec
+}
@@ -0,0 +1,646 @@
+// Package durable provides a crash-safe wrapper around the heap-based queues of pqueue. Every operation that modifies a
+// Queue is appended to a write-ahead log in a directory on local disk before it is applied to the underlying heap, and
+// Open recovers the queue after a crash by loading its latest snapshot and replaying the log that follows it.
+//
+// # Crash consistency
+//
+// Every record of the log carries its length and a checksum, so a record that was only partially written when the
+// process crashed (i.e., a torn write) is detected on recovery; it and everything after it are discarded, and the log
+// is truncated before new records are appended. Snapshots are written to a temporary file and renamed into place, so a
+// crash while checkpointing leaves either the old snapshot and log or the new ones, never a mix of both.
+//
+// How much of the tail of the log can be lost in a crash depends on the SyncPolicy. With the default, SyncAlways, every
+// operation that returned successfully survives a crash.
+package durable
+
+import (
+	"bytes"
+	"cmp"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// ErrCorrupt is returned by Open when the snapshot is damaged, or when the log cannot be replayed onto it.
+var ErrCorrupt = errors.New("durable: snapshot or log is corrupt")
+
+// ErrTieBreak is returned by Open when the heap does not break ties deterministically. A heap that uses
+// pqueue.TieBreakNone may evict a different one of several elements with equal priorities when the log is replayed than
+// it did before the crash.
+var ErrTieBreak = errors.New("durable: heap must use pqueue.TieBreakFIFO or pqueue.TieBreakLIFO")
+
+// Heap is the set of methods a queue must implement to be made durable. Every heap-based queue in pqueue implements
+// it, but only those created with pqueue.WithTieBreak can be opened.
+type Heap[K, V any] interface {
+	pqueue.Queue[K, V]
+	PushMany(items []pqueue.Item[K, V]) error
+	TieBreak() pqueue.TieBreak
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// Queue is a durable min-priority queue. It owns the Heap it wraps, which must not be used directly once it has been
+// passed to Open; otherwise, its contents and the log diverge.
+//
+// Every operation is logged under the Queue's own lock, so a Heap that was created with pqueue.OverflowBlock must not
+// fill up, as Push would block forever. Bounded heaps should use one of the other overflow policies instead.
+type Queue[K, V any] struct {
+	l sync.Mutex
+
+	heap Heap[K, V]
+	dir  string
+	opts options
+
+	key   pqueue.Codec[K]
+	value pqueue.Codec[V]
+
+	gen     uint64
+	log     *os.File
+	records int
+	dirty   bool
+
+	// err is set once the log could not be written. From then on, the contents of the heap are no longer durable, and
+	// every operation that modifies the Queue fails with err.
+	err    error
+	closed bool
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Open opens the durable queue in dir, creating dir if it does not exist, and recovers the contents of heap from the
+// snapshot and log in dir. heap should be empty and freshly constructed; its previous contents are discarded. Open
+// fails with ErrTieBreak if heap uses pqueue.TieBreakNone.
+//
+// Open panics with pqueue.ErrCodecMismatch if a codec passed through WithKeyCodec or WithValueCodec does not match the
+// queue's key or value type.
+func Open[K, V any](dir string, heap Heap[K, V], opts ...Option) (*Queue[K, V], error) {
+	if heap.TieBreak() == pqueue.TieBreakNone {
+		return nil, ErrTieBreak
+	}
+
+	o := newOptions(opts)
+	q := &Queue[K, V]{
+		heap:  heap,
+		dir:   dir,
+		opts:  o,
+		key:   codecOf[K](o.keyCodec),
+		value: codecOf[V](o.valueCodec),
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := q.recover(); err != nil {
+		return nil, err
+	}
+
+	if o.sync == SyncInterval {
+		q.stop = make(chan struct{})
+		q.stopped = make(chan struct{})
+		go q.syncEvery(o.syncInterval)
+	}
+	return q, nil
+}
+
+// Size returns the number of elements in the queue.
+func (q *Queue[K, V]) Size() int {
+	return q.heap.Size()
+}
+
+// Peek returns the value with the highest priority without removing it, or the zero value if the queue is empty.
+func (q *Queue[K, V]) Peek() V {
+	return q.heap.Peek()
+}
+
+// PeekItem returns the priority and value of the element with the highest priority without removing it. ok is false if
+// the queue is empty.
+func (q *Queue[K, V]) PeekItem() (priority K, v V, ok bool) {
+	return q.heap.PeekItem()
+}
+
+// Push logs a value with the provided priority, and then inserts it. If the heap rejects the value (e.g., with
+// pqueue.ErrFull), Push returns its error; replaying the record rejects the value again.
+func (q *Queue[K, V]) Push(v V, priority K) error {
+	q.l.Lock()
+	defer q.l.Unlock()
+
+	if err := q.writableLocked(); err != nil {
+		return err
+	}
+
+	payload, err := q.appendItem([]byte{opPush}, priority, v)
+	if err != nil {
+		return err
+	}
+	return q.appendLocked(payload, func() error {
+		return q.heap.Push(v, priority)
+	})
+}
+
+// Pop removes and returns the value with the highest priority. ok is false if the queue is empty. See PopItem.
+func (q *Queue[K, V]) Pop() (v V, ok bool, err error) {
+	_, v, ok, err = q.PopItem()
+	return v, ok, err
+}
+
+// PopItem logs the removal of the element with the highest priority, and then removes it and returns its priority and
+// value. ok is false if the queue is empty. If the removal could not be logged, the element stays in the queue, and
+// only the error is returned.
+func (q *Queue[K, V]) PopItem() (priority K, v V, ok bool, err error) {
+	q.l.Lock()
+	defer q.l.Unlock()
+
+	if err = q.writableLocked(); err != nil {
+		return priority, v, false, err
+	}
+
+	// The Queue owns the heap, so the element it peeks at is the one it pops once the removal is logged.
+	priority, v, ok = q.heap.PeekItem()
+	if !ok {
+		return priority, v, false, nil
+	}
+
+	payload, err := q.appendItem([]byte{opPop}, priority, v)
+	if err == nil {
+		err = q.appendLocked(payload, func() error {
+			q.heap.PopItem()
+			return nil
+		})
+	}
+	if err != nil {
+		var zeroK K
+		var zeroV V
+		return zeroK, zeroV, false, err
+	}
+	return priority, v, true, nil
+}
+
+// Clear removes every element from the queue, and logs it.
+func (q *Queue[K, V]) Clear() error {
+	q.l.Lock()
+	defer q.l.Unlock()
+
+	if err := q.writableLocked(); err != nil {
+		return err
+	}
+
+	return q.appendLocked([]byte{opClear}, func() error {
+		q.heap.Clear()
+		return nil
+	})
+}
+
+// Meld takes every element out of other, logs them as a single record, and then pushes them onto the heap all at once.
+// other is left empty, unless the heap rejects the elements (e.g., with pqueue.ErrFull), in which case they are pushed
+// back onto other; replaying the record rejects them again. If other rejects some of them in turn (e.g., because it is
+// closed), Meld returns a *MeldError that holds them, so that they are not lost.
+func (q *Queue[K, V]) Meld(other pqueue.Queue[K, V]) error {
+	q.l.Lock()
+	defer q.l.Unlock()
+
+	if err := q.writableLocked(); err != nil {
+		return err
+	}
+
+	items := popAll(other)
+	payload, err := q.appendItems([]byte{opMeld}, items)
+	if err == nil {
+		err = q.appendLocked(payload, func() error {
+			return q.heap.PushMany(items)
+		})
+	}
+	if err != nil {
+		if rejected, pushErr := pushBack(other, items); len(rejected) > 0 {
+			return &MeldError[K, V]{Err: err, PushBackErr: pushErr, Items: rejected}
+		}
+		return err
+	}
+	return nil
+}
+
+// MeldError is returned by Meld when the heap rejects the elements of other, and other rejects some of them when they
+// are pushed back onto it. The rejected elements are in neither queue.
+type MeldError[K, V any] struct {
+	// Err is the reason the heap rejected the elements.
+	Err error
+	// PushBackErr is the reason other rejected Items.
+	PushBackErr error
+	// Items holds the elements that were rejected by both queues.
+	Items []pqueue.Item[K, V]
+}
+
+func (e *MeldError[K, V]) Error() string {
+	return fmt.Sprintf("durable: meld failed: %v; %d elements could not be pushed back: %v", e.Err, len(e.Items),
+		e.PushBackErr)
+}
+
+func (e *MeldError[K, V]) Unwrap() []error {
+	return []error{e.Err, e.PushBackErr}
+}
+
+// manyPusher is implemented by queues that can push several elements at once, such as every Heap.
+type manyPusher[K, V any] interface {
+	PushMany(items []pqueue.Item[K, V]) error
+}
+
+// manyPopper is implemented by queues that can pop several elements at once, such as every heap-based queue in pqueue.
+type manyPopper[K, V any] interface {
+	PopN(n int) []pqueue.Item[K, V]
+}
+
+// popAll pops every element of q in priority order. The elements are popped all at once if q supports it, so that
+// nothing can be pushed onto q or popped from it halfway through.
+func popAll[K, V any](q pqueue.Queue[K, V]) []pqueue.Item[K, V] {
+	if many, ok := q.(manyPopper[K, V]); ok {
+		return many.PopN(math.MaxInt)
+	}
+
+	var items []pqueue.Item[K, V]
+	for {
+		priority, v, ok := q.PopItem()
+		if !ok {
+			return items
+		}
+		items = append(items, pqueue.Item[K, V]{Priority: priority, Value: v})
+	}
+}
+
+// pushBack pushes items back onto the queue they were popped from, and returns the ones it rejects along with the
+// first error. The items are pushed all at once if the queue supports it, as a bounded queue that uses
+// pqueue.OverflowError only accepts them all or none of them.
+func pushBack[K, V any](q pqueue.Queue[K, V], items []pqueue.Item[K, V]) ([]pqueue.Item[K, V], error) {
+	if many, ok := q.(manyPusher[K, V]); ok {
+		err := many.PushMany(items)
+		if err == nil {
+			return nil, nil
+		}
+		if errors.Is(err, pqueue.ErrClosed) {
+			return items, err
+		}
+	}
+
+	var rejected []pqueue.Item[K, V]
+	var first error
+	for _, item := range items {
+		if err := q.Push(item.Value, item.Priority); err != nil {
+			rejected = append(rejected, item)
+			first = cmp.Or(first, err)
+		}
+	}
+	return rejected, first
+}
+
+// Checkpoint compacts the log: it writes a snapshot of the queue, and starts a new, empty log. Checkpointing bounds
+// the size of the log, and with it the time Open takes to recover the queue.
+func (q *Queue[K, V]) Checkpoint() error {
+	q.l.Lock()
+	defer q.l.Unlock()
+
+	if err := q.writableLocked(); err != nil {
+		return err
+	}
+	return q.checkpointLocked()
+}
+
+// Sync flushes the log to stable storage, regardless of the SyncPolicy.
+func (q *Queue[K, V]) Sync() error {
+	q.l.Lock()
+	defer q.l.Unlock()
+
+	if err := q.writableLocked(); err != nil {
+		return err
+	}
+	return q.syncLocked()
+}
+
+// Close flushes and closes the log, and closes the heap. Subsequent operations that modify the queue fail with
+// pqueue.ErrClosed. Calling Close more than once is a no-op.
+func (q *Queue[K, V]) Close() error {
+	q.l.Lock()
+	if q.closed {
+		q.l.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.l.Unlock()
+
+	if q.stop != nil {
+		close(q.stop)
+		<-q.stopped
+	}
+
+	q.l.Lock()
+	defer q.l.Unlock()
+
+	q.heap.Close()
+	err := q.err
+	if err == nil {
+		err = q.log.Sync()
+	}
+	if cerr := q.log.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writableLocked returns the error that operations modifying the queue must fail with, if any.
+func (q *Queue[K, V]) writableLocked() error {
+	if q.closed {
+		return pqueue.ErrClosed
+	}
+	return q.err
+}
+
+// appendLocked appends a record to the log and flushes it according to the SyncPolicy, and only then applies the logged
+// operation to the heap with apply, so that the heap never reflects an operation that the log is missing. Finally, it
+// checkpoints the queue if WithCheckpointEvery says so. If apply fails, its error is returned, and the record stays in
+// the log; the heap is in the same state whenever the record is replayed, so replaying it fails the same way.
+func (q *Queue[K, V]) appendLocked(payload []byte, apply func() error) error {
+	if _, err := q.log.Write(frame(payload)); err != nil {
+		q.err = fmt.Errorf("durable: writing log: %w", err)
+		return q.err
+	}
+	q.records++
+
+	switch q.opts.sync {
+	case SyncAlways:
+		if err := q.syncLocked(); err != nil {
+			return err
+		}
+	case SyncInterval:
+		q.dirty = true
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+	if q.opts.checkpointEvery > 0 && q.records >= q.opts.checkpointEvery {
+		return q.checkpointLocked()
+	}
+	return nil
+}
+
+func (q *Queue[K, V]) syncLocked() error {
+	if err := q.log.Sync(); err != nil {
+		q.err = fmt.Errorf("durable: syncing log: %w", err)
+		return q.err
+	}
+	q.dirty = false
+	return nil
+}
+
+// syncEvery flushes the log every d until the queue is closed.
+func (q *Queue[K, V]) syncEvery(d time.Duration) {
+	defer close(q.stopped)
+
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-t.C:
+			q.l.Lock()
+			if q.dirty && q.err == nil {
+				_ = q.syncLocked()
+			}
+			q.l.Unlock()
+		}
+	}
+}
+
+// checkpointLocked writes a snapshot for the next generation, and then switches to that generation's log. The new log
+// is created before the snapshot, so that once the snapshot is in place, nothing can fail before the switch.
+func (q *Queue[K, V]) checkpointLocked() error {
+	data, err := q.heap.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	gen := q.gen + 1
+	log, err := createLog(q.dir, gen)
+	if err != nil {
+		return err
+	}
+	if err := writeSnapshot(q.dir, data, gen); err != nil {
+		_ = log.Close()
+		_ = os.Remove(filepath.Join(q.dir, logName(gen)))
+		return err
+	}
+
+	old := q.log
+	q.log, q.gen, q.records, q.dirty = log, gen, 0, false
+
+	// The old log is no longer needed; if it cannot be removed, the next Open removes it instead.
+	_ = old.Close()
+	_ = os.Remove(filepath.Join(q.dir, logName(gen-1)))
+	return nil
+}
+
+// recover restores the heap from the snapshot, replays the log onto it, and opens the log for appending. A torn or
+// corrupt record at the end of the log is truncated away.
+func (q *Queue[K, V]) recover() error {
+	data, gen, err := readSnapshot(q.dir)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		if err := q.heap.UnmarshalBinary(data); err != nil {
+			return fmt.Errorf("%w: %w", ErrCorrupt, err)
+		}
+	} else {
+		q.heap.Clear()
+	}
+
+	if err := removeStale(q.dir, gen); err != nil {
+		return err
+	}
+
+	path := filepath.Join(q.dir, logName(gen))
+	records, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	valid, err := q.replay(records)
+	if err != nil {
+		return err
+	}
+
+	log, err := createLog(q.dir, gen)
+	if err != nil {
+		return err
+	}
+	if valid < len(records) {
+		if err := log.Truncate(int64(valid)); err == nil {
+			err = log.Sync()
+		}
+		if err != nil {
+			_ = log.Close()
+			return err
+		}
+	}
+
+	q.gen, q.log = gen, log
+	return nil
+}
+
+// replay applies every record of the log to the heap until it reaches the end of the log, or a torn or corrupt record.
+// It returns the size of the records that were applied. Records that the heap rejects with pqueue.ErrFull were rejected
+// before the crash too, and are skipped.
+func (q *Queue[K, V]) replay(records []byte) (int, error) {
+	valid := 0
+	for valid < len(records) {
+		payload, size, ok := unframe(records[valid:])
+		if !ok {
+			break
+		}
+		if err := q.apply(payload); err != nil && !errors.Is(err, pqueue.ErrFull) {
+			return 0, fmt.Errorf("%w: record at offset %d: %w", ErrCorrupt, valid, err)
+		}
+
+		valid += size
+		q.records++
+	}
+	return valid, nil
+}
+
+func (q *Queue[K, V]) apply(payload []byte) error {
+	if len(payload) == 0 {
+		return errors.New("empty record")
+	}
+
+	r := payloadReader{data: payload[1:], ok: true}
+	switch payload[0] {
+	case opPush:
+		priority, v, err := q.readItem(&r)
+		if err != nil {
+			return err
+		}
+		return q.heap.Push(v, priority)
+	case opPop:
+		key, value := r.bytes(), r.bytes()
+		if !r.ok {
+			return errors.New("truncated record")
+		}
+		return q.popMatching(key, value)
+	case opMeld:
+		n := r.uvarint()
+		if n > uint64(len(r.data)) {
+			return errors.New("truncated record")
+		}
+
+		items := make([]pqueue.Item[K, V], n)
+		for i := range items {
+			priority, v, err := q.readItem(&r)
+			if err != nil {
+				return err
+			}
+			items[i] = pqueue.Item[K, V]{Priority: priority, Value: v}
+		}
+		return q.heap.PushMany(items)
+	case opClear:
+		q.heap.Clear()
+		return nil
+	default:
+		return fmt.Errorf("unknown op %d", payload[0])
+	}
+}
+
+// popMatching pops the element whose key and value encode to the given bytes. The heap may order elements with equal
+// keys differently than it did before the crash (e.g., if it does not break ties), so elements that come before the
+// popped one are pushed back afterwards.
+func (q *Queue[K, V]) popMatching(key, value []byte) error {
+	var skipped []pqueue.Item[K, V]
+	defer func() {
+		if len(skipped) > 0 {
+			_ = q.heap.PushMany(skipped)
+		}
+	}()
+
+	for {
+		priority, v, ok := q.heap.PopItem()
+		if !ok {
+			return errors.New("popped element is not in the queue")
+		}
+
+		k, err := q.key.Marshal(priority)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(k, key) {
+			b, err := q.value.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(b, value) {
+				return nil
+			}
+		}
+		skipped = append(skipped, pqueue.Item[K, V]{Priority: priority, Value: v})
+	}
+}
+
+func (q *Queue[K, V]) appendItem(buf []byte, priority K, v V) ([]byte, error) {
+	k, err := q.key.Marshal(priority)
+	if err != nil {
+		return nil, err
+	}
+	b, err := q.value.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return appendBytes(appendBytes(buf, k), b), nil
+}
+
+func (q *Queue[K, V]) appendItems(buf []byte, items []pqueue.Item[K, V]) ([]byte, error) {
+	buf = binary.AppendUvarint(buf, uint64(len(items)))
+	for _, item := range items {
+		var err error
+		if buf, err = q.appendItem(buf, item.Priority, item.Value); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (q *Queue[K, V]) readItem(r *payloadReader) (priority K, v V, err error) {
+	k, b := r.bytes(), r.bytes()
+	if !r.ok {
+		return priority, v, errors.New("truncated record")
+	}
+
+	if priority, err = q.key.Unmarshal(k); err != nil {
+		return priority, v, err
+	}
+	v, err = q.value.Unmarshal(b)
+	return priority, v, err
+}
+
+// createLog opens the log of generation gen for appending, creating it if it does not exist.
+func createLog(dir string, gen uint64) (*os.File, error) {
+	path := filepath.Join(dir, logName(gen))
+	_, statErr := os.Stat(path)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if os.IsNotExist(statErr) {
+		if err := syncDir(dir); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,129 @@
+package durable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A Queue's directory holds at most one snapshot and one log. The snapshot records the generation of the log that
+// follows it, so that a log whose operations are already part of the snapshot is never replayed:
+//
+//	snapshot      the state of the queue as of the start of log generation g
+//	wal-<g>       the operations performed since the snapshot, with g in hexadecimal
+//
+// A snapshot is framed as follows, where the data is encoded by the heap's MarshalBinary:
+//
+//	magic "PQDS" | version (1 byte) | g (8 bytes, little-endian) | CRC-32C of data (4 bytes, little-endian) | data
+const (
+	snapshotName    = "snapshot"
+	snapshotTmpName = "snapshot.tmp"
+	logPrefix       = "wal-"
+
+	snapshotMagic      = "PQDS"
+	snapshotVersion    = 1
+	snapshotHeaderSize = len(snapshotMagic) + 1 + 8 + 4
+)
+
+func logName(gen uint64) string {
+	return fmt.Sprintf("%s%016x", logPrefix, gen)
+}
+
+// readSnapshot returns the data and generation of the snapshot in dir. If there is no snapshot, it returns nil data
+// and generation 0.
+func readSnapshot(dir string) (data []byte, gen uint64, err error) {
+	raw, err := os.ReadFile(filepath.Join(dir, snapshotName))
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(raw) < snapshotHeaderSize || string(raw[:len(snapshotMagic)]) != snapshotMagic ||
+		raw[len(snapshotMagic)] != snapshotVersion {
+		return nil, 0, ErrCorrupt
+	}
+
+	header := raw[len(snapshotMagic)+1 : snapshotHeaderSize]
+	gen = binary.LittleEndian.Uint64(header[0:8])
+	data = raw[snapshotHeaderSize:]
+	if crc32.Checksum(data, castagnoli) != binary.LittleEndian.Uint32(header[8:12]) {
+		return nil, 0, ErrCorrupt
+	}
+	return data, gen, nil
+}
+
+// writeSnapshot atomically replaces the snapshot in dir: the snapshot is written to a temporary file and flushed, which
+// is then renamed over the previous snapshot.
+func writeSnapshot(dir string, data []byte, gen uint64) error {
+	raw := make([]byte, 0, snapshotHeaderSize+len(data))
+	raw = append(raw, snapshotMagic...)
+	raw = append(raw, snapshotVersion)
+	raw = binary.LittleEndian.AppendUint64(raw, gen)
+	raw = binary.LittleEndian.AppendUint32(raw, crc32.Checksum(data, castagnoli))
+	raw = append(raw, data...)
+
+	tmp := filepath.Join(dir, snapshotTmpName)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(raw); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(dir, snapshotName))
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return syncDir(dir)
+}
+
+// removeStale removes every log in dir other than that of generation gen, along with any temporary snapshot left behind
+// by a checkpoint that did not complete.
+func removeStale(dir string, gen uint64) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		stale := name == snapshotTmpName
+		if hex, ok := strings.CutPrefix(name, logPrefix); ok {
+			g, err := strconv.ParseUint(hex, 16, 64)
+			stale = err == nil && g != gen
+		}
+
+		if stale {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncDir flushes dir itself, which makes the creation, removal and renaming of its files durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package durable
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// Every record of the log is framed as follows, which makes it possible to detect a record that was only partially
+// written (i.e., a torn write) when the process crashed:
+//
+//	len(payload) (4 bytes, little-endian) | CRC-32C of payload (4 bytes, little-endian) | payload
+//
+// The payload starts with one of the ops below, followed by the operands of the op. Keys and values are encoded with
+// the queue's codecs, and prefixed with their length as an unsigned varint.
+const (
+	// opPush is followed by the key and value of the pushed element.
+	opPush byte = iota + 1
+	// opPop is followed by the key and value of the popped element.
+	opPop
+	// opMeld is followed by the number of melded elements, and then by the key and value of each one.
+	opMeld
+	// opClear has no operands.
+	opClear
+)
+
+const frameHeaderSize = 8
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// frame wraps a payload into a record.
+func frame(payload []byte) []byte {
+	record := make([]byte, frameHeaderSize, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(payload, castagnoli))
+
+	return append(record, payload...)
+}
+
+// unframe reads the record at the start of data, and returns its payload along with the size of the whole record. ok
+// is false if the record is incomplete or does not match its checksum.
+func unframe(data []byte) (payload []byte, size int, ok bool) {
+	if len(data) < frameHeaderSize {
+		return nil, 0, false
+	}
+
+	n := binary.LittleEndian.Uint32(data[0:4])
+	if uint64(n) > uint64(len(data)-frameHeaderSize) {
+		return nil, 0, false
+	}
+
+	payload = data[frameHeaderSize : frameHeaderSize+int(n)]
+	if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(data[4:8]) {
+		return nil, 0, false
+	}
+	return payload, frameHeaderSize + int(n), true
+}
+
+// appendBytes appends b, prefixed with its length.
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// payloadReader reads the operands of a payload. Once an error is encountered, every subsequent read is a no-op, so
+// that callers only need to check ok once they are done.
+type payloadReader struct {
+	data []byte
+	ok   bool
+}
+
+func (r *payloadReader) uvarint() uint64 {
+	if !r.ok {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.ok = false
+		return 0
+	}
+
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *payloadReader) bytes() []byte {
+	n := r.uvarint()
+	if !r.ok || n > uint64(len(r.data)) {
+		r.ok = false
+		return nil
+	}
+
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
@@ -0,0 +1,483 @@
+package pqueue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrInvalidFormat is returned when decoding data that is not in a supported serialization format, or that was
+	// encoded by a queue of the wrong kind (e.g., a CircularBuffer snapshot being loaded into a heap-based queue).
+	ErrInvalidFormat = errors.New("pqueue: invalid or unsupported serialization format")
+	// ErrUninitialized is returned when decoding into a queue that was not created by its constructor.
+	ErrUninitialized = errors.New("pqueue: queue was not created by its constructor")
+	// ErrCodecMismatch is the panic value used when a queue is constructed with a Codec for a type other than its key
+	// or value type.
+	ErrCodecMismatch = errors.New("pqueue: codec does not match the queue's key or value type")
+)
+
+// Codec encodes and decodes the keys or values of a queue when it is serialized. Queues use JSONCodec by default, and
+// a different Codec can be plugged in with WithKeyCodec and WithValueCodec.
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// JSONCodec encodes values with encoding/json. When a queue is encoded as JSON with this Codec, keys and values are
+// embedded as is, rather than as opaque strings.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Unmarshal(data []byte) (v T, err error) {
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// GobCodec encodes values with encoding/gob. Every value is encoded as a standalone gob stream, so values of interface
+// types must be registered with gob.Register.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Marshal(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&v)
+	return buf.Bytes(), err
+}
+
+func (GobCodec[T]) Unmarshal(data []byte) (v T, err error) {
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// WithKeyCodec makes the queue encode and decode its keys with c. The queue's constructor panics with
+// ErrCodecMismatch if K is not the queue's key type. It is supported by heap-based queues and External.
+func WithKeyCodec[K any](c Codec[K]) Option {
+	return func(o *options) {
+		o.set |= optKeyCodec
+		o.keyCodec = c
+	}
+}
+
+// WithValueCodec makes the queue encode and decode its values with c. The queue's constructor panics with
+// ErrCodecMismatch if V is not the queue's value type. It is supported by heap-based queues, External and
+// CircularBuffer.
+func WithValueCodec[V any](c Codec[V]) Option {
+	return func(o *options) {
+		o.set |= optValueCodec
+		o.valueCodec = c
+	}
+}
+
+// codecOf returns the Codec stored in an option, or JSONCodec if there is none.
+func codecOf[T any](c any) Codec[T] {
+	if c == nil {
+		return JSONCodec[T]{}
+	}
+
+	codec, ok := c.(Codec[T])
+	if !ok {
+		panic(ErrCodecMismatch)
+	}
+	return codec
+}
+
+// codecs holds the codecs of a heap-based queue.
+type codecs[K, V any] struct {
+	key   Codec[K]
+	value Codec[V]
+}
+
+func newCodecs[K, V any](o options) codecs[K, V] {
+	return codecs[K, V]{
+		key:   codecOf[K](o.keyCodec),
+		value: codecOf[V](o.valueCodec),
+	}
+}
+
+// The serialization format is versioned so that it can evolve without breaking snapshots that have already been saved.
+// Every heap-based queue shares the same format, so a snapshot of one heap kind can be loaded into any other.
+//
+// The binary format is laid out as follows, with every integer encoded as an unsigned varint:
+//
+//	version (1 byte) | kind (1 byte) | count | items...
+//
+// Every item of a heap-based queue is encoded as seq | len(key) | key | len(value) | value, and every item of a
+// CircularBuffer as len(value) | value, from oldest to newest. Items of heap-based queues are in no particular order.
+//
+// The JSON format is an object holding the version, the kind of queue ("heap" or "fifo"), and either the items of a
+// heap-based queue or the values of a CircularBuffer.
+const (
+	formatVersion = 1
+
+	kindHeap byte = 0
+	kindFIFO byte = 1
+
+	jsonKindHeap = "heap"
+	jsonKindFIFO = "fifo"
+)
+
+// serializable is implemented by every heap-based queue.
+type serializable[K, V any] interface {
+	crossMelder[K, V]
+
+	// entriesLocked returns a snapshot of every element in the queue, in no particular order.
+	entriesLocked() []entry[K, V]
+	// codecs returns the codecs of the queue, which never change after construction.
+	codecs() codecs[K, V]
+	// initialized reports whether the queue was created by its constructor.
+	initialized() bool
+}
+
+func marshalBinary[K, V any](q serializable[K, V]) ([]byte, error) {
+	l := q.mutex()
+	q.meterLocked().rlock(l)
+	entries := q.entriesLocked()
+	l.RUnlock()
+
+	c := q.codecs()
+	buf := appendHeader(nil, kindHeap, len(entries))
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, e.seq)
+
+		var err error
+		if buf, err = appendEncoded(buf, c.key, e.key); err != nil {
+			return nil, err
+		}
+		if buf, err = appendEncoded(buf, c.value, e.value); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func unmarshalBinary[K, V any](q serializable[K, V], data []byte) error {
+	if !q.initialized() {
+		return ErrUninitialized
+	}
+
+	r := reader{data: data}
+	n := r.header(kindHeap)
+
+	c := q.codecs()
+	entries := make([]entry[K, V], 0, min(n, len(data)))
+	for range n {
+		var e entry[K, V]
+		var err error
+
+		e.seq = r.uvarint()
+		if e.key, err = decodeNext(&r, c.key); err != nil {
+			return err
+		}
+		if e.value, err = decodeNext(&r, c.value); err != nil {
+			return err
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := r.end(); err != nil {
+		return err
+	}
+	return restore(q, entries)
+}
+
+type jsonHeap struct {
+	Version int            `json:"version"`
+	Kind    string         `json:"kind"`
+	Items   []jsonHeapItem `json:"items"`
+}
+
+type jsonHeapItem struct {
+	Priority json.RawMessage `json:"priority"`
+	Value    json.RawMessage `json:"value"`
+	Seq      uint64          `json:"seq,omitempty"`
+}
+
+func marshalJSON[K, V any](q serializable[K, V]) ([]byte, error) {
+	l := q.mutex()
+	q.meterLocked().rlock(l)
+	entries := q.entriesLocked()
+	l.RUnlock()
+
+	c := q.codecs()
+	snapshot := jsonHeap{
+		Version: formatVersion,
+		Kind:    jsonKindHeap,
+		Items:   make([]jsonHeapItem, len(entries)),
+	}
+	for i, e := range entries {
+		var err error
+
+		snapshot.Items[i].Seq = e.seq
+		if snapshot.Items[i].Priority, err = encodeJSON(c.key, e.key); err != nil {
+			return nil, err
+		}
+		if snapshot.Items[i].Value, err = encodeJSON(c.value, e.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(snapshot)
+}
+
+func unmarshalJSON[K, V any](q serializable[K, V], data []byte) error {
+	if !q.initialized() {
+		return ErrUninitialized
+	}
+
+	var snapshot jsonHeap
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	// Items is only nil if it is missing or null, as an empty queue is encoded as an empty array.
+	if snapshot.Version != formatVersion || snapshot.Kind != jsonKindHeap || snapshot.Items == nil {
+		return ErrInvalidFormat
+	}
+
+	c := q.codecs()
+	entries := make([]entry[K, V], len(snapshot.Items))
+	for i, item := range snapshot.Items {
+		var err error
+
+		entries[i].seq = item.Seq
+		if entries[i].key, err = decodeJSON(c.key, item.Priority); err != nil {
+			return err
+		}
+		if entries[i].value, err = decodeJSON(c.value, item.Value); err != nil {
+			return err
+		}
+	}
+
+	return restore(q, entries)
+}
+
+// restore replaces the contents of q with the provided entries, evicting the ones with the lowest priorities if they do
+// not fit within q's capacity. Decoded elements keep their sequence numbers, so that their insertion order survives a
+// round trip.
+func restore[K, V any](q serializable[K, V], entries []entry[K, V]) error {
+	for _, e := range entries {
+		observeSeq(e.seq)
+	}
+
+	l := q.mutex()
+	q.meterLocked().lock(l)
+	defer l.Unlock()
+
+	if err := q.acceptsLocked(0); err != nil {
+		return err
+	}
+
+	q.drainLocked()
+	q.insertLocked(entries)
+	q.trimLocked()
+	return nil
+}
+
+// observeSeq advances seqCounter past a sequence number that was decoded from a snapshot, so that elements pushed
+// afterwards are ordered after it. Sequence numbers in the upper half of the range were handed out by TieBreakLIFO.
+func observeSeq(seq uint64) {
+	if seq == 0 {
+		return
+	}
+
+	n := seq
+	if seq > math.MaxUint64/2 {
+		n = math.MaxUint64 - seq
+	}
+
+	for {
+		current := seqCounter.Load()
+		if current >= n || seqCounter.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+type jsonFIFO struct {
+	Version int               `json:"version"`
+	Kind    string            `json:"kind"`
+	Values  []json.RawMessage `json:"values"`
+}
+
+func marshalFIFO[T any](values []T, c Codec[T]) ([]byte, error) {
+	buf := appendHeader(nil, kindFIFO, len(values))
+	for _, v := range values {
+		var err error
+		if buf, err = appendEncoded(buf, c, v); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func unmarshalFIFO[T any](data []byte, c Codec[T]) ([]T, error) {
+	r := reader{data: data}
+	n := r.header(kindFIFO)
+
+	values := make([]T, 0, min(n, len(data)))
+	for range n {
+		v, err := decodeNext(&r, c)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	if err := r.end(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func marshalFIFOJSON[T any](values []T, c Codec[T]) ([]byte, error) {
+	snapshot := jsonFIFO{
+		Version: formatVersion,
+		Kind:    jsonKindFIFO,
+		Values:  make([]json.RawMessage, len(values)),
+	}
+	for i, v := range values {
+		var err error
+		if snapshot.Values[i], err = encodeJSON(c, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(snapshot)
+}
+
+func unmarshalFIFOJSON[T any](data []byte, c Codec[T]) ([]T, error) {
+	var snapshot jsonFIFO
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Version != formatVersion || snapshot.Kind != jsonKindFIFO || snapshot.Values == nil {
+		return nil, ErrInvalidFormat
+	}
+
+	values := make([]T, len(snapshot.Values))
+	for i, raw := range snapshot.Values {
+		var err error
+		if values[i], err = decodeJSON(c, raw); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func appendHeader(buf []byte, kind byte, n int) []byte {
+	buf = append(buf, formatVersion, kind)
+	return binary.AppendUvarint(buf, uint64(n))
+}
+
+// appendEncoded appends the length of v's encoding, followed by the encoding itself.
+func appendEncoded[T any](buf []byte, c Codec[T], v T) ([]byte, error) {
+	data, err := c.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...), nil
+}
+
+// decodeNext decodes the next length-prefixed value from r.
+func decodeNext[T any](r *reader, c Codec[T]) (v T, err error) {
+	data := r.next(r.uvarint())
+	if r.err != nil {
+		return v, r.err
+	}
+	return c.Unmarshal(data)
+}
+
+// encodeJSON encodes v for embedding in a JSON document. The output of JSONCodec is embedded as is, while the output
+// of every other Codec is embedded as a base64 string.
+func encodeJSON[T any](c Codec[T], v T) (json.RawMessage, error) {
+	data, err := c.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := c.(JSONCodec[T]); ok {
+		return data, nil
+	}
+	return json.Marshal(data)
+}
+
+// decodeJSON is the inverse of encodeJSON.
+func decodeJSON[T any](c Codec[T], raw json.RawMessage) (v T, err error) {
+	data := []byte(raw)
+	if _, ok := c.(JSONCodec[T]); !ok {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return v, err
+		}
+	}
+	return c.Unmarshal(data)
+}
+
+// reader reads the binary format. Once an error is encountered, every subsequent read is a no-op, so that callers only
+// need to check err once they are done.
+type reader struct {
+	data []byte
+	err  error
+}
+
+// header reads the header, checking that it was written for the expected kind of queue, and returns the item count.
+func (r *reader) header(kind byte) int {
+	if len(r.data) < 2 || r.data[0] != formatVersion || r.data[1] != kind {
+		r.err = ErrInvalidFormat
+		return 0
+	}
+	r.data = r.data[2:]
+
+	n := r.uvarint()
+	if n > math.MaxInt32 {
+		r.err = ErrInvalidFormat
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("%w: truncated or overflowing integer", ErrInvalidFormat)
+		return 0
+	}
+
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *reader) next(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if n > uint64(len(r.data)) {
+		r.err = fmt.Errorf("%w: unexpected end of data", ErrInvalidFormat)
+		return nil
+	}
+
+	data := r.data[:n]
+	r.data = r.data[n:]
+	return data
+}
+
+// end returns the first error encountered while reading, or an error if any data was left unread.
+func (r *reader) end() error {
+	if r.err == nil && len(r.data) != 0 {
+		r.err = fmt.Errorf("%w: trailing data", ErrInvalidFormat)
+	}
+	return r.err
+}
@@ -0,0 +1,168 @@
+// Package expvarmetrics provides an implementation of pqueue.Metrics that publishes a queue's measurements with expvar,
+// and so serves them as JSON from /debug/vars.
+//
+// Measurements are published as a map with the following variables:
+//
+//	pushes, pops, melds, clears  the number of calls to each operation
+//	melded                       the number of elements moved into the queue by melds
+//	depth                        the number of elements in the queue
+//	waited                       a histogram of how long popped elements spent in the queue
+//	lockWaited                   a histogram of how long operations waited to acquire the queue's lock
+//
+// Each histogram counts durations in buckets that grow by powers of 4 from 1µs, each keyed by its upper bound, and also
+// holds the count and the sum, in seconds, of every duration it has observed.
+package expvarmetrics
+
+import (
+	"expvar"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// numBuckets is the number of buckets of a histogram with finite upper bounds, which range from 1µs to about 18
+// minutes.
+const numBuckets = 16
+
+// Metrics is a pqueue.Metrics backed by an expvar.Map. It is safe for concurrent use, but depth is only meaningful if
+// the Metrics is used by a single queue.
+type Metrics struct {
+	m *expvar.Map
+
+	pushes     expvar.Int
+	pops       expvar.Int
+	melds      expvar.Int
+	melded     expvar.Int
+	clears     expvar.Int
+	depth      expvar.Int
+	waited     Histogram
+	lockWaited Histogram
+}
+
+var _ pqueue.Metrics = (*Metrics)(nil)
+
+// New creates a Metrics and publishes it under name. Like expvar.Publish, New panics if name is already in use.
+func New(name string) *Metrics {
+	m := NewUnpublished()
+	expvar.Publish(name, m.m)
+	return m
+}
+
+// NewUnpublished creates a Metrics without publishing it, e.g., to nest it within another expvar.Map with Map.
+func NewUnpublished() *Metrics {
+	m := &Metrics{m: new(expvar.Map)}
+	m.m.Set("pushes", &m.pushes)
+	m.m.Set("pops", &m.pops)
+	m.m.Set("melds", &m.melds)
+	m.m.Set("melded", &m.melded)
+	m.m.Set("clears", &m.clears)
+	m.m.Set("depth", &m.depth)
+	m.m.Set("waited", &m.waited)
+	m.m.Set("lockWaited", &m.lockWaited)
+	return m
+}
+
+// Map returns the map of variables in which m publishes its measurements.
+func (m *Metrics) Map() *expvar.Map {
+	return m.m
+}
+
+func (m *Metrics) Pushed(n int) {
+	m.pushes.Add(int64(n))
+}
+
+func (m *Metrics) Popped(n int) {
+	m.pops.Add(int64(n))
+}
+
+func (m *Metrics) Melded(n int) {
+	m.melds.Add(1)
+	m.melded.Add(int64(n))
+}
+
+func (m *Metrics) Cleared() {
+	m.clears.Add(1)
+}
+
+func (m *Metrics) Depth(n int) {
+	m.depth.Set(int64(n))
+}
+
+func (m *Metrics) Waited(d time.Duration) {
+	m.waited.Observe(d)
+}
+
+func (m *Metrics) LockWaited(d time.Duration) {
+	m.lockWaited.Observe(d)
+}
+
+// Histogram is an expvar.Var that counts durations in exponentially growing buckets. The zero value is an empty
+// Histogram ready to use.
+type Histogram struct {
+	// buckets[i] counts durations up to bucketBound(i), and the last bucket counts every longer duration.
+	buckets [numBuckets + 1]atomic.Int64
+	count   atomic.Int64
+	sum     atomic.Int64
+}
+
+// Observe adds d to the histogram.
+func (h *Histogram) Observe(d time.Duration) {
+	h.buckets[bucketOf(d)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+}
+
+// Count returns the number of durations that have been observed.
+func (h *Histogram) Count() int64 {
+	return h.count.Load()
+}
+
+// Sum returns the sum of every duration that has been observed.
+func (h *Histogram) Sum() time.Duration {
+	return time.Duration(h.sum.Load())
+}
+
+// String returns the histogram as a JSON object, as required by expvar.Var.
+func (h *Histogram) String() string {
+	var b strings.Builder
+	b.WriteString(`{"buckets": {`)
+	for i := range h.buckets {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if i == numBuckets {
+			b.WriteString(`"+Inf"`)
+		} else {
+			b.WriteString(strconv.Quote(bucketBound(i).String()))
+		}
+		b.WriteString(": ")
+		b.WriteString(strconv.FormatInt(h.buckets[i].Load(), 10))
+	}
+	b.WriteString(`}, "count": `)
+	b.WriteString(strconv.FormatInt(h.Count(), 10))
+	b.WriteString(`, "sum": `)
+	b.WriteString(strconv.FormatFloat(h.Sum().Seconds(), 'g', -1, 64))
+	b.WriteString("}")
+	return b.String()
+}
+
+// bucketBound returns the upper bound of the ith bucket, which is 4^i µs.
+func bucketBound(i int) time.Duration {
+	return time.Microsecond << (2 * i)
+}
+
+// bucketOf returns the index of the bucket that counts d.
+func bucketOf(d time.Duration) int {
+	if d <= time.Microsecond {
+		return 0
+	}
+
+	// The smallest i such that d <= 4^i µs is half the bit length of ceil(d / 1µs) - 1, rounded up.
+	us := uint64((d + time.Microsecond - 1) / time.Microsecond)
+	i := (bits.Len64(us-1) + 1) / 2
+	return min(i, numBuckets)
+}
@@ -0,0 +1,495 @@
+package pqueue
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"iter"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+
+	binaryheap "github.com/AndrewChon/pqueue/binary"
+)
+
+// runChunkSize is the number of bytes External reads from a run at a time. Runs are not kept open between reads, so
+// the number of runs is not limited by the number of files a process may open, and every run only costs one chunk of
+// memory.
+const runChunkSize = 32 << 10
+
+// maxRuns is the number of runs at which External merges the smaller half of them into a single run, which caps the
+// memory taken by their chunks at maxRuns chunks. Merging the smaller runs first means that every element is rewritten
+// a logarithmic number of times.
+const maxRuns = 64
+
+// External is a concurrency-safe, min-priority queue for more elements than fit in memory. It keeps at most a limited
+// number of elements in an in-memory binary heap. Once the heap is full, its elements are sorted and spilled to a
+// temporary file as a run, and Pop lazily merges the heap with every run, reading each run in chunks as its elements
+// are popped. Besides the heap, every run holds a chunk of up to 32 KiB in memory, and once there are 64 runs, the
+// smaller half of them are merged into one.
+//
+// Keys and values are written to runs with the queue's codecs (see WithKeyCodec and WithValueCodec), and runs are
+// created in the directory set by WithSpillDir. A run's file is removed once every element in it has been popped, or
+// when the queue is cleared. External is unbounded, and does not support WithCapacity or WithObserver. It reports to
+// the Metrics set by WithMetrics, except for how long elements waited, which would take memory for every element.
+//
+// External does not implement Queue, as melding it into another queue would have to load every element into memory.
+type External[K, V any] struct {
+	l  sync.RWMutex
+	id uint64
+
+	notEmpty waiters
+	closer   closer
+
+	// heap holds the elements that have not been spilled, and runs holds every run, keyed by the element at its head.
+	heap    *binaryheap.Heap[K, V]
+	runs    *binaryheap.Heap[K, *run[K, V]]
+	limit   int
+	spilled int
+
+	dir      string
+	less     func(a, b K) bool
+	tieBreak TieBreak
+	codec    codecs[K, V]
+	meter    meter
+
+	// err is the first error encountered while reading a run, which cannot be returned by Pop.
+	err error
+}
+
+// WithSpillDir makes an External queue spill its runs to temporary files in dir. By default, runs are spilled to the
+// directory returned by os.TempDir. Only NewExternal and NewExternalFunc support it.
+func WithSpillDir(dir string) Option {
+	return func(o *options) {
+		o.set |= optSpillDir
+		o.spillDir = dir
+	}
+}
+
+// NewExternal creates an External queue that keeps at most limit elements in its in-memory heap. The limit counts
+// elements, not bytes, so it should take the size of the elements into account. A limit below 1 is treated as 1.
+func NewExternal[K cmp.Ordered, V any](limit int, opts ...Option) *External[K, V] {
+	return NewExternalFunc[K, V](limit, cmp.Less[K], opts...)
+}
+
+// NewExternalFunc is like NewExternal, but orders keys using the provided less function.
+func NewExternalFunc[K, V any](limit int, less func(a, b K) bool, opts ...Option) *External[K, V] {
+	o := newOptions(opts, optTieBreak|optSpillDir|optMetrics|optKeyCodec|optValueCodec)
+
+	return &External[K, V]{
+		id:       idCounter.Add(1),
+		closer:   newCloser(),
+		heap:     binaryheap.NewHeapFunc[K, V](less),
+		runs:     binaryheap.NewHeapFunc[K, *run[K, V]](less),
+		limit:    max(limit, 1),
+		dir:      o.spillDir,
+		less:     less,
+		tieBreak: o.tieBreak,
+		codec:    newCodecs[K, V](o),
+		meter:    newMeter(o),
+	}
+}
+
+func (e *External[K, V]) Size() int {
+	e.meter.rlock(&e.l)
+	defer e.l.RUnlock()
+
+	return e.sizeLocked()
+}
+
+// Runs returns the number of runs that have been spilled to disk and not yet fully popped.
+func (e *External[K, V]) Runs() int {
+	e.meter.rlock(&e.l)
+	defer e.l.RUnlock()
+
+	return e.runs.Size()
+}
+
+// Err returns the first error encountered while reading a run. Once a run cannot be read, its remaining elements are
+// lost, and Pop moves on to the other runs.
+func (e *External[K, V]) Err() error {
+	e.meter.rlock(&e.l)
+	defer e.l.RUnlock()
+
+	return e.err
+}
+
+// Clear removes every element from the queue, and removes the files of every run.
+func (e *External[K, V]) Clear() {
+	e.meter.lock(&e.l)
+	defer e.l.Unlock()
+
+	e.heap.Clear()
+	for n := range e.runs.Nodes() {
+		n.Value().discard()
+	}
+	e.runs.Clear()
+	e.spilled = 0
+	e.closer.settle(0)
+	e.meter.cleared()
+	e.meter.depth(0)
+}
+
+func (e *External[K, V]) Peek() V {
+	_, v, _ := e.PeekItem()
+	return v
+}
+
+// PeekItem returns the priority and value of the element with the highest priority without removing it. ok is false if
+// the queue is empty.
+func (e *External[K, V]) PeekItem() (priority K, v V, ok bool) {
+	e.meter.rlock(&e.l)
+	defer e.l.RUnlock()
+
+	head, _, ok := e.headLocked()
+	return head.key, head.value, ok
+}
+
+func (e *External[K, V]) Pop() (v V, ok bool) {
+	_, v, ok = e.PopItem()
+	return v, ok
+}
+
+// PopItem removes and returns the priority and value of the element with the highest priority. ok is false if the
+// queue is empty.
+func (e *External[K, V]) PopItem() (priority K, v V, ok bool) {
+	e.meter.lock(&e.l)
+	defer e.l.Unlock()
+
+	head, ok := e.popEntryLocked()
+	return head.key, head.value, ok
+}
+
+// Push inserts a value with the provided priority. If the in-memory heap is full, it is spilled to a new run first,
+// merging runs if there are too many; if that fails, the value is not pushed and the error is returned.
+func (e *External[K, V]) Push(v V, priority K) error {
+	e.meter.lock(&e.l)
+	defer e.l.Unlock()
+
+	if e.closer.closed {
+		return ErrClosed
+	}
+	if e.heap.Size() >= e.limit {
+		if err := e.spillLocked(); err != nil {
+			return err
+		}
+	}
+
+	e.heap.Insert(binaryheap.NewSequencedNode(priority, v, e.tieBreak.nextSeq()))
+	e.meter.pushed(1)
+	e.meter.depth(e.sizeLocked())
+	e.notEmpty.broadcast()
+	return nil
+}
+
+// PopWait removes and returns the value with the highest priority, blocking until the queue is non-empty. If ctx is
+// done first, PopWait returns ctx's error.
+func (e *External[K, V]) PopWait(ctx context.Context) (V, error) {
+	_, v, err := e.PopItemWait(ctx)
+	return v, err
+}
+
+// PopItemWait is like PopWait, but also returns the priority of the element.
+func (e *External[K, V]) PopItemWait(ctx context.Context) (priority K, v V, err error) {
+	head, err := waitFor(ctx, &e.meter, &e.l, &e.notEmpty, &e.closer, e.popEntryLocked)
+	return head.key, head.value, err
+}
+
+// Close closes the queue. Subsequent pushes fail with ErrClosed, but remaining elements can still be popped.
+func (e *External[K, V]) Close() {
+	e.meter.lock(&e.l)
+	defer e.l.Unlock()
+
+	e.closer.close(e.sizeLocked())
+	e.notEmpty.broadcast()
+}
+
+// IsClosed reports whether Close has been called.
+func (e *External[K, V]) IsClosed() bool {
+	e.meter.rlock(&e.l)
+	defer e.l.RUnlock()
+
+	return e.closer.closed
+}
+
+// Done returns a channel that is closed once the queue has been closed and every remaining element has been removed.
+func (e *External[K, V]) Done() <-chan struct{} {
+	return e.closer.done
+}
+
+// Drain returns an iterator that pops elements in priority order until the queue is empty.
+func (e *External[K, V]) Drain() iter.Seq2[K, V] {
+	return drainSeq(e.PopItem)
+}
+
+func (e *External[K, V]) sizeLocked() int {
+	return e.heap.Size() + e.spilled
+}
+
+// headLocked returns the element with the highest priority, which is either the minimum of the in-memory heap or the
+// head of a run. In the latter case, it also returns the run.
+func (e *External[K, V]) headLocked() (entry[K, V], *run[K, V], bool) {
+	n, r := e.heap.FindMin(), e.runs.FindMin()
+	switch {
+	case n == nil && r == nil:
+		return entry[K, V]{}, nil, false
+	// Ties go to the in-memory heap, unless the head of the run was pushed first.
+	case r == nil || n != nil && !e.less(r.Key(), n.Key()) && (e.less(n.Key(), r.Key()) || n.Seq() <= r.Seq()):
+		return entry[K, V]{n.Key(), n.Value(), n.Seq()}, nil, true
+	default:
+		return r.Value().head, r.Value(), true
+	}
+}
+
+func (e *External[K, V]) popEntryLocked() (entry[K, V], bool) {
+	head, r, ok := e.headLocked()
+	if !ok {
+		return head, false
+	}
+
+	if r == nil {
+		e.heap.RemoveMin()
+	} else {
+		e.runs.RemoveMin()
+		e.spilled--
+		e.requeueLocked(r)
+	}
+
+	e.closer.settle(e.sizeLocked())
+	e.meter.poppedUntimed()
+	e.meter.depth(e.sizeLocked())
+	return head, true
+}
+
+// requeueLocked puts a run whose head has just been popped back into the heap of runs, keyed by its next element. The
+// run is removed instead if it has been exhausted, or if its next element cannot be read.
+func (e *External[K, V]) requeueLocked(r *run[K, V]) {
+	r.remaining--
+	if r.remaining == 0 {
+		r.discard()
+		return
+	}
+
+	if err := r.next(e.codec); err != nil {
+		if e.err == nil {
+			e.err = err
+		}
+		e.spilled -= r.remaining
+		r.discard()
+		return
+	}
+	e.runs.Insert(binaryheap.NewSequencedNode(r.head.key, r, r.head.seq))
+}
+
+// spillLocked sorts the in-memory heap, writes it to a new run, and empties the heap. If the run cannot be written, the
+// heap is left untouched.
+func (e *External[K, V]) spillLocked() error {
+	if e.runs.Size() >= maxRuns-1 {
+		if err := e.mergeRunsLocked(); err != nil {
+			return err
+		}
+	}
+
+	nodes := slices.Collect(e.heap.Nodes())
+	slices.SortFunc(nodes, func(a, b *binaryheap.Node[K, V]) int {
+		return comparePriority(a, b, e.less)
+	})
+
+	r, err := writeRun(e.dir, len(nodes), func(yield func(entry[K, V], error) bool) {
+		for _, n := range nodes {
+			if !yield(entry[K, V]{n.Key(), n.Value(), n.Seq()}, nil) {
+				return
+			}
+		}
+	}, e.codec)
+	if err != nil {
+		return err
+	}
+
+	e.heap.Clear()
+	e.spilled += len(nodes)
+	e.runs.Insert(binaryheap.NewSequencedNode(r.head.key, r, r.head.seq))
+	return nil
+}
+
+// mergeRunsLocked merges the smaller half of the runs into a single run. The runs are read through copies, so if the
+// merged run cannot be written, they are left untouched.
+func (e *External[K, V]) mergeRunsLocked() error {
+	nodes := slices.Collect(e.runs.Nodes())
+	slices.SortFunc(nodes, func(a, b *binaryheap.Node[K, *run[K, V]]) int {
+		return cmp.Compare(a.Value().remaining, b.Value().remaining)
+	})
+	nodes = nodes[:len(nodes)/2]
+
+	heads := binaryheap.NewHeapFunc[K, *run[K, V]](e.less)
+	size := 0
+	for _, n := range nodes {
+		r := *n.Value()
+		heads.Insert(binaryheap.NewSequencedNode(r.head.key, &r, r.head.seq))
+		size += r.remaining
+	}
+
+	merged, err := writeRun(e.dir, size, func(yield func(entry[K, V], error) bool) {
+		for n := heads.FindMin(); n != nil; n = heads.FindMin() {
+			r := n.Value()
+			if !yield(r.head, nil) {
+				return
+			}
+
+			heads.RemoveMin()
+			if r.remaining--; r.remaining == 0 {
+				continue
+			}
+			if err := r.next(e.codec); err != nil {
+				yield(entry[K, V]{}, err)
+				return
+			}
+			heads.Insert(binaryheap.NewSequencedNode(r.head.key, r, r.head.seq))
+		}
+	}, e.codec)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range nodes {
+		e.runs.Remove(n)
+		n.Value().discard()
+	}
+	e.runs.Insert(binaryheap.NewSequencedNode(merged.head.key, merged, merged.head.seq))
+	return nil
+}
+
+// run is a sorted sequence of elements that has been spilled to a file, in the same layout as the items of the binary
+// serialization format: seq | len(key) | key | len(value) | value.
+type run[K, V any] struct {
+	path string
+	size int64
+
+	// offset is the position in the file up to which the file has been read into buf.
+	offset int64
+	buf    []byte
+
+	head      entry[K, V]
+	remaining int
+	cleanup   runtime.Cleanup
+}
+
+// writeRun writes the n elements of entries, which must be sorted, to a new file in dir, and returns the run with its
+// first element loaded. If entries yields an error, writing stops and the error is returned.
+func writeRun[K, V any](dir string, n int, entries iter.Seq2[entry[K, V], error], c codecs[K, V]) (*run[K, V], error) {
+	f, err := os.CreateTemp(dir, "pqueue-run-*")
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	var buf []byte
+	for e, eerr := range entries {
+		if err = eerr; err != nil {
+			break
+		}
+
+		buf = binary.AppendUvarint(buf, e.seq)
+		if buf, err = appendEncoded(buf, c.key, e.key); err != nil {
+			break
+		}
+		if buf, err = appendEncoded(buf, c.value, e.value); err != nil {
+			break
+		}
+
+		// Elements are buffered until a chunk's worth has been encoded.
+		if len(buf) >= runChunkSize {
+			var written int
+			written, err = f.Write(buf)
+			size += int64(written)
+			if err != nil {
+				break
+			}
+			buf = buf[:0]
+		}
+	}
+	if err == nil {
+		var written int
+		written, err = f.Write(buf)
+		size += int64(written)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	r := &run[K, V]{path: f.Name(), size: size, remaining: n}
+	if err == nil {
+		err = r.next(c)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+
+	// If the queue is dropped without being drained or cleared, the file is removed once the run is garbage collected.
+	r.cleanup = runtime.AddCleanup(r, func(path string) {
+		_ = os.Remove(path)
+	}, r.path)
+	return r, nil
+}
+
+// next loads the run's next element into head, reading another chunk of the file if the element is not buffered yet.
+func (r *run[K, V]) next(c codecs[K, V]) error {
+	for {
+		rd := reader{data: r.buf}
+		seq := rd.uvarint()
+		key := rd.next(rd.uvarint())
+		value := rd.next(rd.uvarint())
+		if rd.err != nil {
+			if r.offset == r.size {
+				return rd.err
+			}
+			if err := r.fill(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		k, err := c.key.Unmarshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := c.value.Unmarshal(value)
+		if err != nil {
+			return err
+		}
+
+		r.head = entry[K, V]{k, v, seq}
+		r.buf = rd.data
+		return nil
+	}
+}
+
+// fill appends the next chunk of the file to buf. Chunks grow with buf, so that elements larger than a chunk can still
+// be read.
+func (r *run[K, V]) fill() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n := min(int64(max(runChunkSize, 2*len(r.buf))), r.size-r.offset)
+	buf := make([]byte, len(r.buf)+int(n))
+	copy(buf, r.buf)
+	if _, err := f.ReadAt(buf[len(r.buf):], r.offset); err != nil {
+		return err
+	}
+
+	r.buf = buf
+	r.offset += n
+	return nil
+}
+
+// discard removes the run's file.
+func (r *run[K, V]) discard() {
+	r.cleanup.Stop()
+	r.buf = nil
+	_ = os.Remove(r.path)
+}
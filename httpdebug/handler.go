@@ -0,0 +1,83 @@
+// Package httpdebug serves the Stats of every queue registered with pqueue.Register over HTTP, in the manner of
+// net/http/pprof. Importing the package registers its handler at /debug/pqueue on http.DefaultServeMux:
+//
+//	import _ "github.com/AndrewChon/pqueue/httpdebug"
+//
+// Handler returns the same handler, to serve it from a different ServeMux instead.
+//
+// The handler responds with a JSON object that maps the name of every registered queue to its kind, ID, size, the
+// elements with the highest priorities, and statistics about its internal structure (see pqueue.Stats). Priorities and
+// values are formatted with fmt. The number of elements listed per queue is 10 by default, and can be changed with the
+// top query parameter, e.g., /debug/pqueue?top=50.
+package httpdebug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// defaultTop is the number of elements listed per queue when the top query parameter is missing.
+const defaultTop = 10
+
+func init() {
+	http.Handle("/debug/pqueue", Handler())
+}
+
+// Handler returns a handler that serves the Stats of every registered queue.
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+type queueJSON struct {
+	Kind  string         `json:"kind"`
+	ID    uint64         `json:"id"`
+	Size  int            `json:"size"`
+	Top   []itemJSON     `json:"top"`
+	Shape map[string]any `json:"shape,omitempty"`
+}
+
+type itemJSON struct {
+	Priority string `json:"priority"`
+	Value    string `json:"value"`
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	top := defaultTop
+	if s := r.URL.Query().Get("top"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "httpdebug: top must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	queues := make(map[string]queueJSON)
+	for name, q := range pqueue.Registered() {
+		stats := q.Inspect(top)
+
+		items := make([]itemJSON, len(stats.Top))
+		for i, item := range stats.Top {
+			items[i] = itemJSON{fmt.Sprint(item.Priority), fmt.Sprint(item.Value)}
+		}
+
+		queues[name] = queueJSON{
+			Kind:  stats.Kind,
+			ID:    stats.ID,
+			Size:  stats.Size,
+			Top:   items,
+			Shape: stats.Shape,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(queues)
+}
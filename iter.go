@@ -0,0 +1,59 @@
+package pqueue
+
+import (
+	"iter"
+
+	"github.com/AndrewChon/pqueue/binary"
+)
+
+// orderedSeq returns an iterator over a snapshot of entries in priority order. The entries are heapified in linear time
+// up front, and each step of the iteration then costs Θ(log n), so stopping early is cheap.
+func orderedSeq[K, V any](entries []entry[K, V], less func(a, b K) bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		nodes := make([]*binary.Node[K, V], len(entries))
+		for i, e := range entries {
+			nodes[i] = binary.NewSequencedNode(e.key, e.value, e.seq)
+		}
+
+		h := binary.NewHeapFromFunc(nodes, less)
+		for n := h.FindMin(); n != nil; n = h.FindMin() {
+			h.RemoveMin()
+			if !yield(n.Key(), n.Value()) {
+				return
+			}
+		}
+	}
+}
+
+// unorderedSeq returns an iterator over a snapshot of entries in the order they were taken.
+func unorderedSeq[K, V any](entries []entry[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, e := range entries {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// drainSeq returns an iterator that pops elements with the provided pop function until it reports that the queue is
+// empty.
+func drainSeq[K, V any](pop func() (K, V, bool)) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for {
+			priority, v, ok := pop()
+			if !ok || !yield(priority, v) {
+				return
+			}
+		}
+	}
+}
+
+// collect gathers every key-value pair of seq into a slice of items.
+func collect[K, V any](seq iter.Seq2[K, V]) []Item[K, V] {
+	var items []Item[K, V]
+	for priority, v := range seq {
+		items = append(items, Item[K, V]{priority, v})
+	}
+	return items
+}
@@ -0,0 +1,242 @@
+package pqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLeaseExpired is returned when acknowledging a lease that is no longer held, because it has expired or has already
+// been acknowledged.
+var ErrLeaseExpired = errors.New("pqueue: lease has expired or was already settled")
+
+// Receipt is returned when an element is leased, and identifies the lease to Ack and Nack.
+type Receipt[K, V any] struct {
+	Priority K
+	Value    V
+	// Deadline is the time at which the lease expires, as measured by the Leaser's Clock.
+	Deadline time.Time
+
+	id uint64
+}
+
+// Leaser adds visibility timeouts to a queue, for at-least-once processing. Leasing an element pops it from the queue
+// and hides it for a while; the element is only removed for good once it is acknowledged with Ack. If the lease is
+// released with Nack, or expires before it is acknowledged (e.g., because the worker holding it crashed), the element
+// is pushed back onto the queue.
+//
+// Expired leases are returned to the queue by a goroutine, which Close stops. Elements are pushed back with Push, so if
+// the queue is bounded, it should not use OverflowBlock. An expired element that cannot be pushed back, e.g., because
+// the queue has been closed, is dropped.
+type Leaser[K, V any] struct {
+	l sync.Mutex
+
+	queue  Queue[K, V]
+	clock  Clock
+	leases map[uint64]Receipt[K, V]
+	nextID uint64
+
+	// expiries holds the ID of every lease, keyed by its deadline.
+	expiries *DelayQueue[uint64]
+
+	// closing is canceled by Close, which stops the reaping goroutine and wakes every blocked LeaseWait. No lease is
+	// granted once it is canceled.
+	closing context.Context
+	stop    context.CancelFunc
+	stopped chan struct{}
+}
+
+// NewLeaser creates a Leaser over q. Elements should only be popped from q through the Leaser afterwards. The Leaser
+// tells the time with the Clock set by WithClock, or with the system clock by default.
+func NewLeaser[K, V any](q Queue[K, V], opts ...Option) *Leaser[K, V] {
+	o := newOptions(opts, optClock)
+
+	clock := o.clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lr := &Leaser[K, V]{
+		queue:    q,
+		clock:    clock,
+		leases:   make(map[uint64]Receipt[K, V]),
+		expiries: NewDelayQueue[uint64](WithClock(clock), WithTieBreak(TieBreakFIFO)),
+		closing:  ctx,
+		stop:     cancel,
+		stopped:  make(chan struct{}),
+	}
+
+	go lr.reap(ctx)
+	return lr
+}
+
+// Lease pops the element with the highest priority and hides it for d. ok is false if the queue is empty, or if the
+// Leaser has been closed.
+func (lr *Leaser[K, V]) Lease(d time.Duration) (r Receipt[K, V], ok bool) {
+	lr.l.Lock()
+	defer lr.l.Unlock()
+
+	if lr.closing.Err() != nil {
+		return r, false
+	}
+	lr.reclaimLocked()
+
+	priority, v, ok := lr.queue.PopItem()
+	if !ok {
+		return r, false
+	}
+	return lr.grantLocked(priority, v, d), true
+}
+
+// LeaseWait is like Lease, but blocks until the queue is non-empty. It returns ctx's error if ctx is done first, or
+// ErrClosed once the Leaser has been closed, or the queue has been closed and drained.
+func (lr *Leaser[K, V]) LeaseWait(ctx context.Context, d time.Duration) (Receipt[K, V], error) {
+	lr.l.Lock()
+	if lr.closing.Err() != nil {
+		lr.l.Unlock()
+		return Receipt[K, V]{}, ErrClosed
+	}
+	lr.reclaimLocked()
+	lr.l.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(lr.closing, cancel)()
+
+	priority, v, err := lr.queue.PopItemWait(ctx)
+	if err != nil {
+		if lr.closing.Err() != nil {
+			return Receipt[K, V]{}, ErrClosed
+		}
+		return Receipt[K, V]{}, err
+	}
+
+	lr.l.Lock()
+	defer lr.l.Unlock()
+
+	// Close may have returned every leased element while the element was being popped, so it is returned too.
+	if lr.closing.Err() != nil {
+		lr.expire(Receipt[K, V]{Priority: priority, Value: v})
+		return Receipt[K, V]{}, ErrClosed
+	}
+	return lr.grantLocked(priority, v, d), nil
+}
+
+// Ack removes a leased element for good. It returns ErrLeaseExpired if the lease is no longer held, which includes
+// leases whose deadline has passed even if their element has yet to be returned to the queue.
+func (lr *Leaser[K, V]) Ack(r Receipt[K, V]) error {
+	return lr.settle(r, func() error { return nil })
+}
+
+// Nack releases a lease early, pushing its element back onto the queue with the provided priority. It returns
+// ErrLeaseExpired if the lease is no longer held, or the queue's error if the element cannot be pushed back, in which
+// case the lease is still held.
+func (lr *Leaser[K, V]) Nack(r Receipt[K, V], priority K) error {
+	return lr.settle(r, func() error { return lr.queue.Push(r.Value, priority) })
+}
+
+// settle ends a lease once release, which is called with the Leaser's lock held, has dealt with its element. It returns
+// ErrLeaseExpired if the lease is no longer held, after reclaiming every expired lease so that a lease whose deadline
+// has passed is never settled, and release's error, in which case the lease is still held.
+func (lr *Leaser[K, V]) settle(r Receipt[K, V], release func() error) error {
+	lr.l.Lock()
+	defer lr.l.Unlock()
+
+	lr.reclaimLocked()
+	if _, ok := lr.leases[r.id]; !ok {
+		return ErrLeaseExpired
+	}
+
+	if err := release(); err != nil {
+		return err
+	}
+	delete(lr.leases, r.id)
+	return nil
+}
+
+// Leased returns the number of elements that are currently leased.
+func (lr *Leaser[K, V]) Leased() int {
+	lr.l.Lock()
+	defer lr.l.Unlock()
+
+	lr.reclaimLocked()
+	return len(lr.leases)
+}
+
+// Close stops the goroutine that returns expired leases to the queue, and immediately pushes back every element that is
+// still leased, so that none are lost. Subsequent calls to Lease find no element, and LeaseWait fails with ErrClosed.
+// The queue itself is left open. Calling Close more than once is a no-op.
+func (lr *Leaser[K, V]) Close() {
+	lr.stop()
+	<-lr.stopped
+
+	lr.l.Lock()
+	defer lr.l.Unlock()
+
+	for _, r := range lr.leases {
+		lr.expire(r)
+	}
+	clear(lr.leases)
+	lr.expiries.Clear()
+}
+
+// reap returns leases to the queue as they expire, until ctx is done.
+func (lr *Leaser[K, V]) reap(ctx context.Context) {
+	defer close(lr.stopped)
+
+	for {
+		id, err := lr.expiries.Take(ctx)
+		if err != nil {
+			return
+		}
+
+		lr.l.Lock()
+		lr.expireLocked(id)
+		lr.l.Unlock()
+	}
+}
+
+// reclaimLocked returns every lease that has expired to the queue. The reaping goroutine does so too, but it may lag
+// behind the clock; reclaiming eagerly guarantees that an expired element is visible again as soon as its deadline
+// passes.
+func (lr *Leaser[K, V]) reclaimLocked() {
+	for {
+		id, ok := lr.expiries.PopReady()
+		if !ok {
+			return
+		}
+		lr.expireLocked(id)
+	}
+}
+
+// expireLocked returns the element of a lease to the queue, unless the lease has already been settled.
+func (lr *Leaser[K, V]) expireLocked(id uint64) {
+	r, ok := lr.leases[id]
+	if !ok {
+		return
+	}
+
+	delete(lr.leases, id)
+	lr.expire(r)
+}
+
+// expire pushes the element of a lease back onto the queue with its original priority.
+func (lr *Leaser[K, V]) expire(r Receipt[K, V]) {
+	_ = lr.queue.Push(r.Value, r.Priority)
+}
+
+func (lr *Leaser[K, V]) grantLocked(priority K, v V, d time.Duration) Receipt[K, V] {
+	lr.nextID++
+	r := Receipt[K, V]{
+		Priority: priority,
+		Value:    v,
+		Deadline: lr.clock.Now().Add(d),
+		id:       lr.nextID,
+	}
+
+	lr.leases[r.id] = r
+	_ = lr.expiries.Push(r.id, r.Deadline)
+	return r
+}
@@ -0,0 +1,226 @@
+package pqueue
+
+import (
+	"iter"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of a queue's operations, and can be attached to any queue with WithMetrics. Its methods
+// are called with the queue's lock held, so they should be cheap and must not call back into the queue. A single
+// Metrics may be shared by several queues, in which case it must be safe for concurrent use, and Depth reports the size
+// of whichever queue changed last. LockWaited is also called by operations that only hold the lock for reading, so it
+// must be safe for concurrent use regardless.
+//
+// The methods map directly onto counters, a gauge and a histogram, which makes Metrics straightforward to adapt to most
+// metrics libraries; the expvarmetrics package provides an implementation backed by expvar.
+type Metrics interface {
+	// Pushed counts n elements pushed onto the queue.
+	Pushed(n int)
+	// Popped counts n elements popped from the queue, or removed from it with Remove.
+	Popped(n int)
+	// Melded counts a meld that moved n elements into the queue.
+	Melded(n int)
+	// Cleared counts a call to Clear.
+	Cleared()
+	// Depth reports the number of elements in the queue whenever it changes.
+	Depth(n int)
+	// Waited observes how long a popped element spent in the queue since it was pushed.
+	Waited(d time.Duration)
+	// LockWaited observes how long an operation waited to acquire the queue's lock, which is zero if the lock was free.
+	LockWaited(d time.Duration)
+}
+
+// WithMetrics makes the queue report its operations to m. Recording how long each element waits costs a map entry per
+// element, so queues without Metrics do not pay for it. Elements that are melded in keep the time at which they were
+// pushed onto a queue of the same type with Metrics; elements that come from a queue without Metrics or of a different
+// type, or that are decoded, are treated as if they were pushed at that moment. Decoding a queue only reports its new
+// depth, and does not count its elements as pushed. Clones of the queue have no Metrics, so that m only ever describes
+// the queue it was attached to. It is supported by every queue, but not by Leaser or Retrier.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.set |= optMetrics
+		o.metrics = m
+	}
+}
+
+// meter reports a queue's operations to its Metrics, if it has any. Every method is a no-op otherwise. Its methods must
+// be called with the queue's lock held.
+type meter struct {
+	metrics Metrics
+	// pushedAt holds the time at which each element in the queue was pushed, keyed by the element's node.
+	pushedAt map[any]time.Time
+}
+
+func newMeter(o options) meter {
+	if o.metrics == nil {
+		return meter{}
+	}
+	return meter{metrics: o.metrics, pushedAt: make(map[any]time.Time)}
+}
+
+// stamp records that the element of node has entered the queue.
+func (mt *meter) stamp(node any) {
+	if mt.metrics == nil {
+		return
+	}
+	mt.pushedAt[node] = time.Now()
+}
+
+// stampAt records that the element of node entered the queue at the provided time, e.g., when an element that was
+// taken out of the queue with taken is put back. A zero time is treated as now.
+func (mt *meter) stampAt(node any, at time.Time) {
+	if mt.metrics == nil {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	mt.pushedAt[node] = at
+}
+
+// pushed counts n pushed elements, whose nodes must have been stamped unless they are popped with poppedUntimed.
+func (mt *meter) pushed(n int) {
+	if mt.metrics == nil {
+		return
+	}
+	mt.metrics.Pushed(n)
+}
+
+// popped counts the element of node as popped, and observes how long it waited.
+func (mt *meter) popped(node any) {
+	mt.poppedAt(mt.taken(node))
+}
+
+// poppedUntimed counts an element as popped without observing how long it waited, for queues that do not stamp their
+// elements when they are pushed.
+func (mt *meter) poppedUntimed() {
+	mt.poppedAt(time.Time{})
+}
+
+// taken drops the push time of an element that left the queue, and returns it. The element is not counted as popped
+// until it is passed to poppedAt, which lets Stream count elements only once they are delivered.
+func (mt *meter) taken(node any) time.Time {
+	if mt.metrics == nil {
+		return time.Time{}
+	}
+
+	t := mt.pushedAt[node]
+	delete(mt.pushedAt, node)
+	return t
+}
+
+// poppedAt counts an element that was pushed at the provided time as popped, and observes how long it waited. The wait
+// is not observed if the time is zero.
+func (mt *meter) poppedAt(pushedAt time.Time) {
+	if mt.metrics == nil {
+		return
+	}
+
+	if !pushedAt.IsZero() {
+		mt.metrics.Waited(time.Since(pushedAt))
+	}
+	mt.metrics.Popped(1)
+}
+
+// melded counts a meld of n elements.
+func (mt *meter) melded(n int) {
+	if mt.metrics == nil {
+		return
+	}
+	mt.metrics.Melded(n)
+}
+
+// adopt records the push times of nodes that are melded into mt's queue as they are, without being reinserted. Nodes
+// keep the push times recorded by from, the meter of the queue they come from, and are stamped with the current time
+// otherwise.
+func adopt[N any](mt, from *meter, nodes iter.Seq[N]) {
+	if mt.metrics == nil {
+		return
+	}
+
+	now := time.Now()
+	for node := range nodes {
+		t, ok := from.pushedAt[node]
+		if !ok {
+			t = now
+		}
+		mt.pushedAt[node] = t
+	}
+}
+
+// cleared counts a call to Clear.
+func (mt *meter) cleared() {
+	if mt.metrics == nil {
+		return
+	}
+	mt.metrics.Cleared()
+}
+
+// depth reports the size of the queue.
+func (mt *meter) depth(size int) {
+	if mt.metrics == nil {
+		return
+	}
+	mt.metrics.Depth(size)
+}
+
+// forget drops the push time of an element that left the queue without being popped.
+func (mt *meter) forget(node any) {
+	if mt.metrics == nil {
+		return
+	}
+	delete(mt.pushedAt, node)
+}
+
+// forgetAll drops the push time of every element.
+func (mt *meter) forgetAll() {
+	if mt.metrics == nil {
+		return
+	}
+	clear(mt.pushedAt)
+}
+
+// lock write-locks l, and observes how long that took.
+func (mt *meter) lock(l *sync.RWMutex) {
+	if mt.metrics == nil {
+		l.Lock()
+		return
+	}
+
+	if l.TryLock() {
+		mt.metrics.LockWaited(0)
+		return
+	}
+	start := time.Now()
+	l.Lock()
+	mt.metrics.LockWaited(time.Since(start))
+}
+
+// rlock read-locks l, and observes how long that took.
+func (mt *meter) rlock(l *sync.RWMutex) {
+	if mt.metrics == nil {
+		l.RLock()
+		return
+	}
+
+	if l.TryRLock() {
+		mt.metrics.LockWaited(0)
+		return
+	}
+	start := time.Now()
+	l.RLock()
+	mt.metrics.LockWaited(time.Since(start))
+}
+
+// lockPair is like the lockPair function, and observes how long it took to lock both queues.
+func (mt *meter) lockPair(a *sync.RWMutex, aID uint64, b *sync.RWMutex, bID uint64) {
+	if mt.metrics == nil {
+		lockPair(a, aID, b, bID)
+		return
+	}
+
+	start := time.Now()
+	lockPair(a, aID, b, bID)
+	mt.metrics.LockWaited(time.Since(start))
+}
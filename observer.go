@@ -0,0 +1,132 @@
+package pqueue
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Observer is notified of every operation on a queue, which makes it possible to trace individual elements through the
+// queue. It can be attached to Binary, Pairing, Skew, SkewBinomial and CircularBuffer with WithObserver. Its methods
+// are called with the queue's lock held, in the order in which the operations take effect, so they should be cheap and
+// must not call back into the queue. A single Observer may be shared by several queues, in which case it must be safe
+// for concurrent use.
+//
+// Priorities and values are passed as they are; CircularBuffer, which has no priorities, passes a nil priority.
+type Observer interface {
+	// OnPush is called for every element pushed onto the queue.
+	OnPush(priority, value any)
+	// OnPop is called for every element popped from the queue, or removed from it with Remove.
+	OnPop(priority, value any)
+	// OnMeld is called when n elements are melded into the queue.
+	OnMeld(n int)
+	// OnClear is called when the queue is cleared, with the number of elements it held.
+	OnClear(n int)
+	// OnDrop is called for every element that is discarded because the queue is full, whether it is the incoming
+	// element or one that was evicted to make room for it; see WithCapacity.
+	OnDrop(priority, value any)
+}
+
+// WithObserver makes the queue notify obs of its operations. It is supported by heap-based queues and CircularBuffer.
+func WithObserver(obs Observer) Option {
+	return func(o *options) {
+		o.set |= optObserver
+		o.observer = obs
+	}
+}
+
+// observer notifies a queue's Observer, if it has any. Every method is a no-op otherwise, and does not box its
+// arguments.
+type observer[K, V any] struct {
+	obs Observer
+}
+
+func newObserver[K, V any](o options) observer[K, V] {
+	return observer[K, V]{obs: o.observer}
+}
+
+func (ob *observer[K, V]) push(priority K, v V) {
+	if ob.obs != nil {
+		ob.obs.OnPush(priority, v)
+	}
+}
+
+func (ob *observer[K, V]) pop(priority K, v V) {
+	if ob.obs != nil {
+		ob.obs.OnPop(priority, v)
+	}
+}
+
+func (ob *observer[K, V]) meld(n int) {
+	if ob.obs != nil {
+		ob.obs.OnMeld(n)
+	}
+}
+
+func (ob *observer[K, V]) clear(n int) {
+	if ob.obs != nil {
+		ob.obs.OnClear(n)
+	}
+}
+
+func (ob *observer[K, V]) drop(priority K, v V) {
+	if ob.obs != nil {
+		ob.obs.OnDrop(priority, v)
+	}
+}
+
+// SlogObserver is an Observer that logs operations with log/slog. To keep the volume of logs manageable on busy
+// queues, it can sample operations, logging only one in every n of them.
+type SlogObserver struct {
+	logger *slog.Logger
+	level  slog.Level
+	every  uint64
+	count  atomic.Uint64
+}
+
+// NewSlogObserver creates a SlogObserver that logs one in every n operations to logger at the provided level. Every
+// operation is logged if n is 1 or less. A nil logger logs to slog.Default.
+func NewSlogObserver(logger *slog.Logger, level slog.Level, n int) *SlogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &SlogObserver{
+		logger: logger,
+		level:  level,
+		every:  uint64(max(n, 1)),
+	}
+}
+
+func (so *SlogObserver) OnPush(priority, value any) {
+	so.log("pqueue: push", slog.Any("priority", priority), slog.Any("value", value))
+}
+
+func (so *SlogObserver) OnPop(priority, value any) {
+	so.log("pqueue: pop", slog.Any("priority", priority), slog.Any("value", value))
+}
+
+func (so *SlogObserver) OnMeld(n int) {
+	so.log("pqueue: meld", slog.Int("n", n))
+}
+
+func (so *SlogObserver) OnClear(n int) {
+	so.log("pqueue: clear", slog.Int("n", n))
+}
+
+func (so *SlogObserver) OnDrop(priority, value any) {
+	so.log("pqueue: drop", slog.Any("priority", priority), slog.Any("value", value))
+}
+
+// log logs an operation if it is sampled and the logger is enabled for the observer's level.
+func (so *SlogObserver) log(msg string, attrs ...slog.Attr) {
+	if (so.count.Add(1)-1)%so.every != 0 {
+		return
+	}
+
+	ctx := context.Background()
+	if !so.logger.Enabled(ctx, so.level) {
+		return
+	}
+	so.logger.LogAttrs(ctx, so.level, msg, attrs...)
+}
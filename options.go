@@ -0,0 +1,108 @@
+package pqueue
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
+
+// ErrUnsupportedOption is the panic value used when a constructor is passed an Option that does not apply to what it
+// constructs, e.g., WithSpillDir to anything but an External queue.
+var ErrUnsupportedOption = errors.New("pqueue: option is not supported by this constructor")
+
+// Option configures a queue at construction time. Every option documents the constructors that support it, and any
+// other constructor panics with ErrUnsupportedOption if it is passed the option.
+type Option func(*options)
+
+type options struct {
+	// set holds every option that was passed, so that constructors can reject the ones they do not support.
+	set optionSet
+
+	tieBreak TieBreak
+	capacity int
+	overflow OverflowPolicy
+	spillDir string
+	clock    Clock
+
+	backoff     Backoff
+	maxFailures int
+
+	metrics  Metrics
+	observer Observer
+
+	// keyCodec and valueCodec hold Codecs of the queue's key and value types, which are only known to the queue's
+	// constructor.
+	keyCodec   any
+	valueCodec any
+}
+
+// optionSet is a set of options, with one bit per function that returns an Option.
+type optionSet uint
+
+const (
+	optTieBreak optionSet = 1 << iota
+	optCapacity
+	optSpillDir
+	optClock
+	optBackoff
+	optMaxFailures
+	optMetrics
+	optObserver
+	optKeyCodec
+	optValueCodec
+)
+
+// heapOptions are the options supported by every heap-based queue.
+const heapOptions = optTieBreak | optCapacity | optMetrics | optObserver | optKeyCodec | optValueCodec
+
+// newOptions applies opts, and panics with ErrUnsupportedOption if any of them is not in supported.
+func newOptions(opts []Option, supported optionSet) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.set&^supported != 0 {
+		panic(ErrUnsupportedOption)
+	}
+	return o
+}
+
+// TieBreak determines the order in which elements with equal priorities are popped.
+type TieBreak int
+
+const (
+	// TieBreakNone leaves the order of elements with equal priorities unspecified. This is the default, and the order
+	// differs between heap types.
+	TieBreakNone TieBreak = iota
+	// TieBreakFIFO pops elements with equal priorities in the order they were pushed.
+	TieBreakFIFO
+	// TieBreakLIFO pops elements with equal priorities in the reverse order they were pushed.
+	TieBreakLIFO
+)
+
+// WithTieBreak makes the queue break ties between equal priorities as described by t. Stability is achieved by
+// stamping every element with a sequence number when it is pushed, so the asymptotic costs of every operation are
+// unchanged. Sequence numbers are drawn from a single counter shared by every queue, so insertion order is also
+// preserved across melds between queues that use the same TieBreak. It is supported by heap-based queues, External and
+// DelayQueue.
+func WithTieBreak(t TieBreak) Option {
+	return func(o *options) {
+		o.set |= optTieBreak
+		o.tieBreak = t
+	}
+}
+
+// seqCounter hands out sequence numbers to elements pushed onto stable queues.
+var seqCounter atomic.Uint64
+
+// nextSeq returns the sequence number for a newly pushed element. A sequence number of zero leaves ties unordered.
+func (t TieBreak) nextSeq() uint64 {
+	switch t {
+	case TieBreakFIFO:
+		return seqCounter.Add(1)
+	case TieBreakLIFO:
+		return math.MaxUint64 - seqCounter.Add(1)
+	default:
+		return 0
+	}
+}
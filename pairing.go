@@ -2,104 +2,654 @@ package pqueue
 
 import (
 	"cmp"
+	"context"
+	"io"
+	"iter"
 	"sync"
-	"sync/atomic"
 
 	"github.com/AndrewChon/pqueue/pairing"
 )
 
-var pairingIDCounter atomic.Uint64
-
 // Pairing is a concurrency-safe, min-priority queue built on a pairing heap.
-type Pairing[K cmp.Ordered, V any] struct {
+type Pairing[K, V any] struct {
 	// A locking order needs to be defined and strictly followed for safety; thus, we do not want to expose the mutex.
 	l  sync.RWMutex
 	id uint64
 
-	root *pairing.Tree[K, V]
-	size int
+	notEmpty waiters
+	bound    bound
+	closer   closer
+
+	root     *pairing.Tree[K, V]
+	size     int
+	less     func(a, b K) bool
+	tieBreak TieBreak
+	codec    codecs[K, V]
+	meter    meter
+	observer observer[K, V]
+}
+
+func NewPairing[K cmp.Ordered, V any](opts ...Option) *Pairing[K, V] {
+	return NewPairingFunc[K, V](cmp.Less[K], opts...)
 }
 
-func NewPairing[K cmp.Ordered, V any]() *Pairing[K, V] {
+// NewPairingFunc is like NewPairing, but orders keys using the provided less function rather than requiring K to be
+// ordered. For example, a max-priority queue can be built by providing a less function that reports whether a > b.
+// Queues that are melded together are expected to order keys the same way.
+func NewPairingFunc[K, V any](less func(a, b K) bool, opts ...Option) *Pairing[K, V] {
+	o := newOptions(opts, heapOptions)
+
 	return &Pairing[K, V]{
-		id:   pairingIDCounter.Add(1),
-		root: nil,
-		size: 0,
+		id:       idCounter.Add(1),
+		bound:    newBound(o, heapPolicies...),
+		closer:   newCloser(),
+		root:     nil,
+		size:     0,
+		less:     less,
+		tieBreak: o.tieBreak,
+		codec:    newCodecs[K, V](o),
+		meter:    newMeter(o),
+		observer: newObserver[K, V](o),
 	}
 }
 
+// NewPairingFrom builds a Pairing queue from the provided items in linear time by melding them together in pairs.
+// If there are more items than the queue's capacity, the items with the lowest priorities are discarded.
+func NewPairingFrom[K cmp.Ordered, V any](items []Item[K, V], opts ...Option) *Pairing[K, V] {
+	return NewPairingFromFunc(items, cmp.Less[K], opts...)
+}
+
+// NewPairingFromFunc is like NewPairingFrom, but orders keys using the provided less function.
+func NewPairingFromFunc[K, V any](items []Item[K, V], less func(a, b K) bool, opts ...Option) *Pairing[K, V] {
+	p := NewPairingFunc[K, V](less, opts...)
+	p.insertLocked(entriesOf(items))
+	p.trimLocked()
+
+	return p
+}
+
+// CollectPairing builds a Pairing queue from the key-value pairs of seq, where each key is a priority.
+func CollectPairing[K cmp.Ordered, V any](seq iter.Seq2[K, V], opts ...Option) *Pairing[K, V] {
+	return NewPairingFrom(collect(seq), opts...)
+}
+
+// CollectPairingFunc is like CollectPairing, but orders keys using the provided less function.
+func CollectPairingFunc[K, V any](seq iter.Seq2[K, V], less func(a, b K) bool, opts ...Option) *Pairing[K, V] {
+	return NewPairingFromFunc(collect(seq), less, opts...)
+}
+
 func (p *Pairing[K, V]) Size() int {
-	p.l.RLock()
+	p.meter.rlock(&p.l)
 	defer p.l.RUnlock()
 
 	return p.size
 }
 
 func (p *Pairing[K, V]) Clear() {
-	p.l.Lock()
+	p.meter.lock(&p.l)
 	defer p.l.Unlock()
 
-	p.root = nil
-	p.size = 0
+	p.observer.clear(p.sizeLocked())
+	p.clearLocked()
+	p.meter.cleared()
 }
 
 func (p *Pairing[K, V]) Peek() V {
-	p.l.RLock()
+	_, v, _ := p.PeekItem()
+	return v
+}
+
+// PeekItem returns the priority and value of the element with the highest priority without removing it. ok is false if
+// the queue is empty.
+func (p *Pairing[K, V]) PeekItem() (priority K, v V, ok bool) {
+	p.meter.rlock(&p.l)
 	defer p.l.RUnlock()
 
-	minNode := pairing.FindMin(p.root)
-	if minNode == nil {
-		var zero V
-		return zero
+	return p.peekLocked()
+}
+
+func (p *Pairing[K, V]) Pop() (v V, ok bool) {
+	_, v, ok = p.PopItem()
+	return v, ok
+}
+
+// PopItem removes and returns the priority and value of the element with the highest priority. ok is false if the
+// queue is empty.
+func (p *Pairing[K, V]) PopItem() (priority K, v V, ok bool) {
+	p.meter.lock(&p.l)
+	defer p.l.Unlock()
+
+	return p.popLocked()
+}
+
+// Push inserts a value with the provided priority. If the queue is full, Push follows the queue's OverflowPolicy; see
+// WithCapacity.
+func (p *Pairing[K, V]) Push(v V, priority K) error {
+	return p.PushWait(context.Background(), v, priority)
+}
+
+// PushWait is like Push, but returns ctx's error if ctx is done while blocked on a full queue.
+func (p *Pairing[K, V]) PushWait(ctx context.Context, v V, priority K) error {
+	p.meter.lock(&p.l)
+	defer p.l.Unlock()
+
+	_, err := p.admitLocked(ctx, v, priority)
+	return err
+}
+
+// PopWait removes and returns the value with the highest priority, blocking until the queue is non-empty. If ctx is
+// done first, PopWait returns ctx's error.
+func (p *Pairing[K, V]) PopWait(ctx context.Context) (V, error) {
+	_, v, err := p.PopItemWait(ctx)
+	return v, err
+}
+
+// PopItemWait is like PopWait, but also returns the priority of the element.
+func (p *Pairing[K, V]) PopItemWait(ctx context.Context) (priority K, v V, err error) {
+	e, err := p.popEntryWait(ctx)
+	return e.key, e.value, err
+}
+
+// Close closes the queue. Subsequent pushes and melds into the queue fail with ErrClosed, and every goroutine blocked
+// in PopWait or PushWait is woken. Elements that remain in the queue can still be popped, and PopWait returns ErrClosed
+// once the queue is empty. Calling Close more than once has no effect.
+func (p *Pairing[K, V]) Close() {
+	p.meter.lock(&p.l)
+	defer p.l.Unlock()
+
+	p.closer.close(p.size)
+	p.notEmpty.broadcast()
+	p.bound.notFull.broadcast()
+}
+
+// IsClosed reports whether Close has been called.
+func (p *Pairing[K, V]) IsClosed() bool {
+	p.meter.rlock(&p.l)
+	defer p.l.RUnlock()
+
+	return p.closer.closed
+}
+
+// Done returns a channel that is closed once the queue has been closed and every remaining element has been removed.
+func (p *Pairing[K, V]) Done() <-chan struct{} {
+	return p.closer.done
+}
+
+// TieBreak returns the TieBreak the queue was created with (see WithTieBreak).
+func (p *Pairing[K, V]) TieBreak() TieBreak {
+	return p.tieBreak
+}
+
+// All returns an iterator over every element in priority order, without modifying the queue.
+func (p *Pairing[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		p.meter.rlock(&p.l)
+		entries := p.entriesLocked()
+		p.l.RUnlock()
+
+		orderedSeq(entries, p.less)(yield)
+	}
+}
+
+// Unordered returns an iterator over every element in no particular order, without modifying the queue. It is cheaper
+// than All, as the elements do not need to be ordered.
+func (p *Pairing[K, V]) Unordered() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		p.meter.rlock(&p.l)
+		entries := p.entriesLocked()
+		p.l.RUnlock()
+
+		unorderedSeq(entries)(yield)
 	}
+}
 
-	return minNode.Value()
+// Drain returns an iterator that pops every element in priority order until the queue is empty.
+func (p *Pairing[K, V]) Drain() iter.Seq2[K, V] {
+	return drainSeq(p.PopItem)
 }
 
-func (p *Pairing[K, V]) Pop() (v V, ok bool) {
-	p.l.Lock()
+// PushMany inserts every provided item while only taking the lock once. If the items do not all fit, PushMany fails
+// with ErrFull without inserting any of them under OverflowError; under every other policy, each item is pushed in turn
+// as if by Push. In that case the batch is not atomic: under OverflowBlock, the lock is released while waiting for
+// room, so other operations may run between items, and items pushed before an error stay in the queue.
+func (p *Pairing[K, V]) PushMany(items []Item[K, V]) error {
+	return p.PushManyWait(context.Background(), items)
+}
+
+// PushManyWait is like PushMany, but returns ctx's error if ctx is done while blocked on a full queue.
+func (p *Pairing[K, V]) PushManyWait(ctx context.Context, items []Item[K, V]) error {
+	p.meter.lock(&p.l)
 	defer p.l.Unlock()
 
-	t := pairing.FindMin(p.root)
+	if p.closer.closed {
+		return ErrClosed
+	}
+	if p.bound.room(p.size, len(items)) {
+		p.insertLocked(entriesOf(items))
+		for _, item := range items {
+			p.observer.push(item.Priority, item.Value)
+		}
+		p.meter.pushed(len(items))
+		return nil
+	}
+	if p.bound.policy == OverflowError {
+		return ErrFull
+	}
+
+	for _, item := range items {
+		if _, err := p.admitLocked(ctx, item.Value, item.Priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PopN removes and returns up to n items in priority order while only taking the lock once.
+func (p *Pairing[K, V]) PopN(n int) []Item[K, V] {
+	p.meter.lock(&p.l)
+	defer p.l.Unlock()
+
+	return popN(p.popLocked, p.size, n)
+}
+
+// PushHandle inserts a value with the provided priority and returns a Handle to it. If the queue is full, PushHandle
+// behaves like Push, and the returned Handle is nil if the value was not inserted.
+func (p *Pairing[K, V]) PushHandle(v V, priority K) (*Handle[K, V], error) {
+	return p.PushHandleWait(context.Background(), v, priority)
+}
+
+// PushHandleWait is like PushHandle, but returns ctx's error if ctx is done while blocked on a full queue.
+func (p *Pairing[K, V]) PushHandleWait(ctx context.Context, v V, priority K) (*Handle[K, V], error) {
+	p.meter.lock(&p.l)
+	defer p.l.Unlock()
+
+	t, err := p.admitLocked(ctx, v, priority)
 	if t == nil {
-		return
+		return nil, err
 	}
+	return &Handle[K, V]{node: t}, nil
+}
 
-	v = t.Value()
-	p.root = pairing.RemoveMin(p.root)
-	p.size--
+// Update changes the priority of the element referred to by h. It returns false if h does not refer to an element in
+// this queue. Validating h costs time proportional to the depth of its element.
+func (p *Pairing[K, V]) Update(h *Handle[K, V], priority K) bool {
+	p.meter.lock(&p.l)
+	defer p.l.Unlock()
+
+	t := p.nodeOf(h)
+	if t == nil {
+		return false
+	}
 
-	return v, true
+	if p.less(priority, t.Key()) {
+		p.root = pairing.DecreaseKeyFunc(p.root, t, priority, p.less)
+	} else if p.less(t.Key(), priority) {
+		p.root = pairing.IncreaseKeyFunc(p.root, t, priority, p.less)
+	}
+	return true
 }
 
-func (p *Pairing[K, V]) Push(v V, priority K) {
-	p.l.Lock()
+// Remove removes the element referred to by h in O(log n) amortized. It returns false if h does not refer to an element
+// in this queue. Validating h costs time proportional to the depth of its element.
+func (p *Pairing[K, V]) Remove(h *Handle[K, V]) bool {
+	p.meter.lock(&p.l)
 	defer p.l.Unlock()
 
-	newNode := pairing.NewTree(priority, v)
-	p.root = pairing.Insert(p.root, newNode)
+	t := p.nodeOf(h)
+	if t == nil {
+		return false
+	}
 
-	p.size++
+	p.root = pairing.DeleteFunc(p.root, t, p.less)
+	p.size--
+	p.meter.popped(t)
+	p.observer.pop(t.Key(), t.Value())
+	p.removedLocked()
+	return true
 }
 
-// Meld merges another Pairing queue into this one and clears it.
-func (p *Pairing[K, V]) Meld(other *Pairing[K, V]) {
-	if p.id < other.id {
-		p.l.Lock()
-		other.l.Lock()
-	} else if p.id > other.id {
-		other.l.Lock()
-		p.l.Lock()
-	} else {
-		panic(ConcurrencySafetyError)
+// nodeOf returns the node referred to by h, or nil if h does not refer to an element in this queue.
+func (p *Pairing[K, V]) nodeOf(h *Handle[K, V]) *pairing.Tree[K, V] {
+	if h == nil || p.root == nil {
+		return nil
+	}
+
+	t, ok := h.node.(*pairing.Tree[K, V])
+	if !ok || t.Root() != p.root {
+		return nil
 	}
+	return t
+}
 
+// Meld merges another Pairing queue into this one and clears it.
+func (p *Pairing[K, V]) Meld(other *Pairing[K, V]) error {
+	p.meter.lockPair(&p.l, p.id, &other.l, other.id)
 	defer p.l.Unlock()
 	defer other.l.Unlock()
 
-	p.root = pairing.Meld(p.root, other.root)
+	if err := p.acceptsLocked(other.size); err != nil {
+		return err
+	}
+
+	adopt(&p.meter, &other.meter, other.root.Nodes())
+	p.meter.melded(other.sizeLocked())
+	p.observer.meld(other.sizeLocked())
+	p.root = pairing.MeldFunc(p.root, other.root, p.less)
 	p.size += other.size
+	other.clearLocked()
+	p.trimLocked()
+	p.meter.depth(p.size)
+	p.notEmpty.broadcast()
+	return nil
+}
+
+// CrossMeld merges any other queue from this package into this one and clears it. See CrossMeldable.
+func (p *Pairing[K, V]) CrossMeld(other CrossMeldable) error {
+	if o, ok := other.(*Pairing[K, V]); ok {
+		return p.Meld(o)
+	}
+
+	return crossMeld[K, V](p, other)
+}
+
+// Clone returns an independent copy of the queue. Handles to the queue's elements do not refer to the copy's elements.
+func (p *Pairing[K, V]) Clone() *Pairing[K, V] {
+	return p.CloneFunc(nil)
+}
+
+// CloneFunc is like Clone, but copies every value with copyValue.
+func (p *Pairing[K, V]) CloneFunc(copyValue func(V) V) *Pairing[K, V] {
+	p.meter.rlock(&p.l)
+	defer p.l.RUnlock()
+
+	return &Pairing[K, V]{
+		id:       idCounter.Add(1),
+		bound:    p.bound.clone(),
+		closer:   newCloser(),
+		root:     pairing.Clone(p.root, copyValue),
+		size:     p.size,
+		less:     p.less,
+		tieBreak: p.tieBreak,
+		codec:    p.codec,
+		observer: p.observer,
+	}
+}
+
+// MarshalBinary encodes every element of the queue in a versioned binary format that is shared by every heap-based
+// queue, using the queue's codecs (see WithKeyCodec).
+func (p *Pairing[K, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary[K, V](p)
+}
+
+// UnmarshalBinary replaces the contents of the queue with the elements encoded in data by the MarshalBinary method of
+// any heap-based queue. The queue must have been created by its constructor, and keeps its own comparator and options.
+func (p *Pairing[K, V]) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary[K, V](p, data)
+}
+
+// MarshalJSON is like MarshalBinary, but encodes the elements as JSON.
+func (p *Pairing[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON[K, V](p)
+}
+
+// UnmarshalJSON is like UnmarshalBinary, but decodes elements encoded by MarshalJSON.
+func (p *Pairing[K, V]) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON[K, V](p, data)
+}
+
+// GobEncode encodes the queue in the same format as MarshalBinary.
+func (p *Pairing[K, V]) GobEncode() ([]byte, error) {
+	return p.MarshalBinary()
+}
+
+// GobDecode decodes the queue in the same way as UnmarshalBinary.
+func (p *Pairing[K, V]) GobDecode(data []byte) error {
+	return p.UnmarshalBinary(data)
+}
+
+// Inspect returns the Stats of the queue, with up to top of its elements with the highest priorities. It costs Θ(n),
+// plus O(log n) for every element returned.
+func (p *Pairing[K, V]) Inspect(top int) Stats {
+	p.meter.rlock(&p.l)
+	entries := p.entriesLocked()
+	stats := Stats{
+		Kind:  "Pairing",
+		ID:    p.id,
+		Size:  p.size,
+		Shape: map[string]any{"rootChildren": p.root.Degree()},
+	}
+	p.l.RUnlock()
+
+	stats.Top = topOf(entries, p.less, top)
+	return stats
+}
+
+// WriteDOT writes the heap that backs the queue to w as a Graphviz graph, as described by pairing.Tree.WriteDOT, to
+// help diagnose the shape of the heap. The queue is read-locked until the whole graph has been written.
+func (p *Pairing[K, V]) WriteDOT(w io.Writer) error {
+	p.meter.rlock(&p.l)
+	defer p.l.RUnlock()
+
+	return p.root.WriteDOT(w)
+}
+
+// Validate checks the invariants of the heap that backs the queue (see pairing.Validate), that the queue's size matches
+// the number of elements in its heap, and that its size fits within its capacity.
+func (p *Pairing[K, V]) Validate() error {
+	p.meter.rlock(&p.l)
+	defer p.l.RUnlock()
+
+	if err := pairing.ValidateFunc(p.root, p.less); err != nil {
+		return invalid(err)
+	}
+	return validateSize(countOf(p.root.Nodes()), p.size, &p.bound)
+}
+
+func (p *Pairing[K, V]) queueID() uint64 {
+	return p.id
+}
+
+func (p *Pairing[K, V]) mutex() *sync.RWMutex {
+	return &p.l
+}
+
+func (p *Pairing[K, V]) sizeLocked() int {
+	return p.size
+}
+
+func (p *Pairing[K, V]) acceptsLocked(incoming int) error {
+	if p.closer.closed {
+		return ErrClosed
+	}
+	return p.bound.fits(p.size, incoming)
+}
+
+// trimLocked evicts the elements with the lowest priorities until the queue fits within its capacity.
+func (p *Pairing[K, V]) trimLocked() {
+	excess := p.bound.excess(p.size)
+	if excess == 0 {
+		return
+	}
+
+	for _, t := range lowestN(p.root.Nodes(), p.less, excess) {
+		p.root = pairing.DeleteFunc(p.root, t, p.less)
+		p.size--
+		p.meter.forget(t)
+		p.observer.drop(t.Key(), t.Value())
+	}
+	p.meter.depth(p.size)
+}
+
+// removedLocked is called whenever elements are removed from the queue.
+func (p *Pairing[K, V]) removedLocked() {
+	p.bound.notFull.broadcast()
+	p.closer.settle(p.size)
+	p.meter.depth(p.size)
+}
+
+func (p *Pairing[K, V]) meterLocked() *meter {
+	return &p.meter
+}
+
+func (p *Pairing[K, V]) observerLocked() *observer[K, V] {
+	return &p.observer
+}
+
+func (p *Pairing[K, V]) codecs() codecs[K, V] {
+	return p.codec
+}
+
+func (p *Pairing[K, V]) initialized() bool {
+	return p.less != nil
+}
+
+func (p *Pairing[K, V]) drainLocked() []entry[K, V] {
+	entries := p.entriesLocked()
+	p.clearLocked()
+
+	return entries
+}
+
+// entriesLocked returns a snapshot of every element in the queue, in no particular order.
+func (p *Pairing[K, V]) entriesLocked() []entry[K, V] {
+	entries := make([]entry[K, V], 0, p.size)
+	for t := range p.root.Nodes() {
+		entries = append(entries, entry[K, V]{t.Key(), t.Value(), t.Seq()})
+	}
+
+	return entries
+}
+
+func (p *Pairing[K, V]) clearLocked() {
+	p.root = nil
+	p.size = 0
+	p.meter.forgetAll()
+	p.removedLocked()
+}
+
+func (p *Pairing[K, V]) insertLocked(entries []entry[K, V]) {
+	nodes := make([]*pairing.Tree[K, V], 0, len(entries))
+	for _, e := range entries {
+		t := pairing.NewSequencedTree(e.key, e.value, p.seqOf(e))
+		p.meter.stamp(t)
+		nodes = append(nodes, t)
+	}
+
+	p.root = pairing.MeldFunc(p.root, pairing.BuildFunc(nodes, p.less), p.less)
+	p.size += len(entries)
+	p.meter.depth(p.size)
+	p.notEmpty.broadcast()
+}
+
+// seqOf returns the sequence number to give an element that is being moved into this queue. The element keeps its
+// sequence number if it has one, so that insertion order survives a CrossMeld.
+func (p *Pairing[K, V]) seqOf(e entry[K, V]) uint64 {
+	if e.seq == 0 {
+		return p.tieBreak.nextSeq()
+	}
+	return e.seq
+}
+
+// admitLocked pushes a value once the queue has room for it, as dictated by its overflow policy. It returns nil if the
+// value was not pushed.
+func (p *Pairing[K, V]) admitLocked(ctx context.Context, v V, priority K) (*pairing.Tree[K, V], error) {
+	ok, err := p.bound.admit(ctx, &p.meter, &p.l, &p.closer, p.sizeLocked, func() bool {
+		return p.evictLocked(priority)
+	})
+	if !ok {
+		if err == nil {
+			p.observer.drop(priority, v)
+		}
+		return nil, err
+	}
+
+	return p.pushLocked(v, priority), nil
+}
+
+// evictLocked removes the element with the lowest priority to make room for an incoming element with the provided
+// priority. It returns false, leaving the queue untouched, if the incoming element would have the lowest priority.
+func (p *Pairing[K, V]) evictLocked(priority K) bool {
+	t, ok := lowest(p.root.Nodes(), p.less)
+	if !ok || !outranks(priority, t, p.less, p.tieBreak) {
+		return false
+	}
+
+	p.root = pairing.DeleteFunc(p.root, t, p.less)
+	p.size--
+	p.meter.forget(t)
+	p.observer.drop(t.Key(), t.Value())
+	return true
+}
+
+func (p *Pairing[K, V]) pushLocked(v V, priority K) *pairing.Tree[K, V] {
+	newNode := pairing.NewSequencedTree(priority, v, p.tieBreak.nextSeq())
+	p.root = pairing.InsertFunc(p.root, newNode, p.less)
+	p.size++
+	p.meter.stamp(newNode)
+	p.meter.pushed(1)
+	p.observer.push(priority, v)
+	p.meter.depth(p.size)
+	p.notEmpty.broadcast()
+
+	return newNode
+}
+
+func (p *Pairing[K, V]) peekLocked() (priority K, v V, ok bool) {
+	minNode := pairing.FindMin(p.root)
+	if minNode == nil {
+		return
+	}
+
+	return minNode.Key(), minNode.Value(), true
+}
+
+func (p *Pairing[K, V]) popLocked() (priority K, v V, ok bool) {
+	e, ok := p.popEntryLocked()
+	return e.key, e.value, ok
+}
+
+// popEntryLocked is like popLocked, but returns the element as an entry, along with its sequence number.
+func (p *Pairing[K, V]) popEntryLocked() (entry[K, V], bool) {
+	h, ok := p.takeLocked()
+	if ok {
+		p.meter.poppedAt(h.pushedAt)
+		p.observer.pop(h.key, h.value)
+	}
+	return h.entry, ok
+}
+
+func (p *Pairing[K, V]) popEntryWait(ctx context.Context) (entry[K, V], error) {
+	return waitFor(ctx, &p.meter, &p.l, &p.notEmpty, &p.closer, p.popEntryLocked)
+}
+
+// takeLocked removes the element with the highest priority without counting it as popped.
+func (p *Pairing[K, V]) takeLocked() (held[K, V], bool) {
+	t := pairing.FindMin(p.root)
+	if t == nil {
+		return held[K, V]{}, false
+	}
+
+	p.root = pairing.RemoveMinFunc(p.root, p.less)
+	p.size--
+	p.removedLocked()
+	return held[K, V]{entry[K, V]{t.Key(), t.Value(), t.Seq()}, p.meter.taken(t)}, true
+}
+
+func (p *Pairing[K, V]) takeWait(ctx context.Context) (held[K, V], error) {
+	return waitFor(ctx, &p.meter, &p.l, &p.notEmpty, &p.closer, p.takeLocked)
+}
+
+// requeueLocked puts an element that was removed with takeLocked back into the queue, without counting it as pushed.
+func (p *Pairing[K, V]) requeueLocked(h held[K, V]) {
+	t := pairing.NewSequencedTree(h.key, h.value, h.seq)
+	p.root = pairing.InsertFunc(p.root, t, p.less)
+	p.size++
+	p.meter.stampAt(t, h.pushedAt)
+	p.meter.depth(p.size)
+	p.notEmpty.broadcast()
+}
 
-	other.root = nil
-	other.size = 0
+// pushedLocked returns a channel that is closed the next time elements are added to the queue.
+func (p *Pairing[K, V]) pushedLocked() <-chan struct{} {
+	return p.notEmpty.wait()
 }
@@ -2,21 +2,30 @@ package pairing
 
 import (
 	"cmp"
+	"iter"
 )
 
-type Tree[K cmp.Ordered, V any] struct {
+type Tree[K, V any] struct {
 	key   K
 	value V
+	seq   uint64
 
 	parent           *Tree[K, V]
 	nextOlderSibling *Tree[K, V]
 	youngestChild    *Tree[K, V]
 }
 
-func NewTree[K cmp.Ordered, V any](key K, value V) *Tree[K, V] {
+func NewTree[K, V any](key K, value V) *Tree[K, V] {
+	return NewSequencedTree(key, value, 0)
+}
+
+// NewSequencedTree is like NewTree, but also assigns the node a sequence number. Nodes with equal keys are ordered by
+// ascending sequence number.
+func NewSequencedTree[K, V any](key K, value V, seq uint64) *Tree[K, V] {
 	return &Tree[K, V]{
 		key:              key,
 		value:            value,
+		seq:              seq,
 		parent:           nil,
 		nextOlderSibling: nil,
 		youngestChild:    nil,
@@ -31,23 +40,144 @@ func (t *Tree[K, V]) Value() V {
 	return t.value
 }
 
+func (t *Tree[K, V]) Seq() uint64 {
+	return t.seq
+}
+
+// Root returns the root of the Tree that t currently belongs to. The cost is proportional to the depth of t.
+func (t *Tree[K, V]) Root() *Tree[K, V] {
+	if t == nil {
+		return nil
+	}
+
+	for t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
+
+// Degree returns the number of children of t, or zero if t is nil. The cost is proportional to the number of children.
+func (t *Tree[K, V]) Degree() int {
+	if t == nil {
+		return 0
+	}
+
+	n := 0
+	for c := t.youngestChild; c != nil; c = c.nextOlderSibling {
+		n++
+	}
+	return n
+}
+
+// Nodes returns an iterator over every node in the Tree rooted at t, in no particular order.
+func (t *Tree[K, V]) Nodes() iter.Seq[*Tree[K, V]] {
+	return func(yield func(*Tree[K, V]) bool) {
+		if t == nil {
+			return
+		}
+
+		stack := []*Tree[K, V]{t}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if !yield(n) {
+				return
+			}
+
+			for c := n.youngestChild; c != nil; c = c.nextOlderSibling {
+				stack = append(stack, c)
+			}
+		}
+	}
+}
+
+// Build forms a Tree from standalone nodes in linear time by repeatedly melding them in pairs, which also keeps the
+// resulting Tree balanced. Build reuses the provided slice as scratch space.
+func Build[K cmp.Ordered, V any](nodes []*Tree[K, V]) *Tree[K, V] {
+	return BuildFunc(nodes, cmp.Less[K])
+}
+
+// BuildFunc is like Build, but orders keys using the provided less function.
+func BuildFunc[K, V any](nodes []*Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	for len(nodes) > 1 {
+		n := 0
+		for i := 0; i+1 < len(nodes); i += 2 {
+			nodes[n] = MeldFunc(nodes[i], nodes[i+1], less)
+			n++
+		}
+
+		if len(nodes)%2 == 1 {
+			nodes[n] = nodes[len(nodes)-1]
+			n++
+		}
+
+		nodes = nodes[:n]
+	}
+
+	return nodes[0]
+}
+
 // FindMin returns the root node of the Tree, or nil if the Tree is empty.
-func FindMin[K cmp.Ordered, V any](t *Tree[K, V]) *Tree[K, V] {
+func FindMin[K, V any](t *Tree[K, V]) *Tree[K, V] {
 	if t == nil {
 		return nil
 	}
 	return t
 }
 
+// Clone returns a structural copy of the Tree rooted at t in Θ(n). If copyValue is not nil, it is used to copy every
+// value.
+func Clone[K, V any](t *Tree[K, V], copyValue func(V) V) *Tree[K, V] {
+	if t == nil {
+		return nil
+	}
+
+	root := cloneNode(t, copyValue)
+
+	// The tree is copied iteratively, as long sibling lists would otherwise make for deep recursion.
+	stack := [][2]*Tree[K, V]{{t, root}}
+	for len(stack) > 0 {
+		n, c := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		var prev *Tree[K, V]
+		for child := n.youngestChild; child != nil; child = child.nextOlderSibling {
+			cc := cloneNode(child, copyValue)
+			cc.parent = c
+
+			if prev == nil {
+				c.youngestChild = cc
+			} else {
+				prev.nextOlderSibling = cc
+			}
+			prev = cc
+
+			stack = append(stack, [2]*Tree[K, V]{child, cc})
+		}
+	}
+
+	return root
+}
+
 // Meld forms a new Tree from two other trees, with the largest becoming parent to the smallest.
 func Meld[K cmp.Ordered, V any](a, b *Tree[K, V]) *Tree[K, V] {
+	return MeldFunc(a, b, cmp.Less[K])
+}
+
+// MeldFunc is like Meld, but orders keys using the provided less function.
+func MeldFunc[K, V any](a, b *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
 	if a == nil {
 		return b
 	} else if b == nil {
 		return a
 	}
 
-	if a.key < b.key {
+	if precedes(a, b, less) {
 		a.addChild(b)
 		return a
 	}
@@ -61,9 +191,19 @@ func Insert[K cmp.Ordered, V any](t *Tree[K, V], new *Tree[K, V]) *Tree[K, V] {
 	return Meld(t, new)
 }
 
+// InsertFunc is like Insert, but orders keys using the provided less function.
+func InsertFunc[K, V any](t *Tree[K, V], new *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	return MeldFunc(t, new, less)
+}
+
 // RemoveMin removes the root node (in other words, the smallest node) from the provided Tree, rebuilds the Tree, and
 // returns the new root node.
 func RemoveMin[K cmp.Ordered, V any](t *Tree[K, V]) *Tree[K, V] {
+	return RemoveMinFunc(t, cmp.Less[K])
+}
+
+// RemoveMinFunc is like RemoveMin, but orders keys using the provided less function.
+func RemoveMinFunc[K, V any](t *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
 	if t == nil {
 		return nil
 	}
@@ -77,18 +217,24 @@ func RemoveMin[K cmp.Ordered, V any](t *Tree[K, V]) *Tree[K, V] {
 		current.parent = nil
 		current = next
 	}
+	t.youngestChild = nil
 
-	return twoPassMerge(youngestChild)
+	return twoPassMerge(youngestChild, less)
 }
 
 // DecreaseKey decreases the target node's key to the provided new key. The new key must be less than the target node's
 // current key.
 func DecreaseKey[K cmp.Ordered, V any](t *Tree[K, V], targetNode *Tree[K, V], newKey K) *Tree[K, V] {
+	return DecreaseKeyFunc(t, targetNode, newKey, cmp.Less[K])
+}
+
+// DecreaseKeyFunc is like DecreaseKey, but orders keys using the provided less function.
+func DecreaseKeyFunc[K, V any](t *Tree[K, V], targetNode *Tree[K, V], newKey K, less func(a, b K) bool) *Tree[K, V] {
 	if t == nil || targetNode == nil {
 		return t
 	}
 
-	if newKey >= targetNode.key {
+	if !less(newKey, targetNode.key) {
 		return t
 	}
 	targetNode.key = newKey
@@ -98,11 +244,77 @@ func DecreaseKey[K cmp.Ordered, V any](t *Tree[K, V], targetNode *Tree[K, V], ne
 	}
 	emancipate(targetNode)
 
-	return Meld(t, targetNode)
+	return MeldFunc(t, targetNode, less)
+}
+
+// IncreaseKey increases the target node's key to the provided new key. The new key must be greater than the target
+// node's current key. Since the target node may no longer be smaller than its children, its children are detached and
+// melded back into the Tree, and the target node is reinserted on its own.
+func IncreaseKey[K cmp.Ordered, V any](t *Tree[K, V], targetNode *Tree[K, V], newKey K) *Tree[K, V] {
+	return IncreaseKeyFunc(t, targetNode, newKey, cmp.Less[K])
+}
+
+// IncreaseKeyFunc is like IncreaseKey, but orders keys using the provided less function.
+func IncreaseKeyFunc[K, V any](t *Tree[K, V], targetNode *Tree[K, V], newKey K, less func(a, b K) bool) *Tree[K, V] {
+	if t == nil || targetNode == nil {
+		return t
+	}
+
+	if !less(targetNode.key, newKey) {
+		return t
+	}
+
+	t = DeleteFunc(t, targetNode, less)
+	targetNode.key = newKey
+
+	return MeldFunc(t, targetNode, less)
+}
+
+// Delete removes an arbitrary node from the Tree and returns the new root node. The removed node is left as a
+// standalone Tree.
+func Delete[K cmp.Ordered, V any](t *Tree[K, V], targetNode *Tree[K, V]) *Tree[K, V] {
+	return DeleteFunc(t, targetNode, cmp.Less[K])
+}
+
+// DeleteFunc is like Delete, but orders keys using the provided less function.
+func DeleteFunc[K, V any](t *Tree[K, V], targetNode *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	if t == nil || targetNode == nil {
+		return t
+	}
+
+	if targetNode == t {
+		return RemoveMinFunc(t, less)
+	}
+
+	if targetNode.parent == nil {
+		return t
+	}
+	emancipate(targetNode)
+
+	return MeldFunc(t, RemoveMinFunc(targetNode, less), less)
+}
+
+// precedes reports whether node a has a higher priority than node b. Ties between equal keys are broken by sequence
+// number.
+func precedes[K, V any](a, b *Tree[K, V], less func(a, b K) bool) bool {
+	if less(a.key, b.key) {
+		return true
+	}
+	return a.seq < b.seq && !less(b.key, a.key)
+}
+
+// cloneNode is a helper function that copies a node without any of its links.
+func cloneNode[K, V any](t *Tree[K, V], copyValue func(V) V) *Tree[K, V] {
+	value := t.value
+	if copyValue != nil {
+		value = copyValue(value)
+	}
+
+	return NewSequencedTree(t.key, value, t.seq)
 }
 
 // emancipate is a helper function that detaches a node from its parent.
-func emancipate[K cmp.Ordered, V any](t *Tree[K, V]) {
+func emancipate[K, V any](t *Tree[K, V]) {
 	defer func() {
 		t.parent = nil
 		t.nextOlderSibling = nil
@@ -126,7 +338,7 @@ func emancipate[K cmp.Ordered, V any](t *Tree[K, V]) {
 
 // twoPassMerge reconstitutes a rootless Tree given its youngest child (and by extension, all of its children) into a
 // new Tree, with its smallest member as its root.
-func twoPassMerge[K cmp.Ordered, V any](yc *Tree[K, V]) *Tree[K, V] {
+func twoPassMerge[K, V any](yc *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
 	if yc == nil || yc.nextOlderSibling == nil {
 		return yc
 	}
@@ -143,7 +355,7 @@ func twoPassMerge[K cmp.Ordered, V any](yc *Tree[K, V]) *Tree[K, V] {
 			cur = b.nextOlderSibling
 			a.nextOlderSibling = nil
 			b.nextOlderSibling = nil
-			firstPassPairs = append(firstPassPairs, Meld(a, b))
+			firstPassPairs = append(firstPassPairs, MeldFunc(a, b, less))
 		} else {
 			cur = nil
 			a.nextOlderSibling = nil
@@ -154,7 +366,7 @@ func twoPassMerge[K cmp.Ordered, V any](yc *Tree[K, V]) *Tree[K, V] {
 	// Meld together the first-pass pairs, but in the opposite direction to prevent the overall Tree from becoming
 	// lopsided. The resulting Tree will now have the smallest as its Root.
 	for i := len(firstPassPairs) - 2; i >= 0; i-- {
-		firstPassPairs[i] = Meld(firstPassPairs[i], firstPassPairs[i+1])
+		firstPassPairs[i] = MeldFunc(firstPassPairs[i], firstPassPairs[i+1], less)
 	}
 
 	return firstPassPairs[0]
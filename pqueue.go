@@ -1,15 +1,252 @@
 // Package pqueue provides concurrency-safe priority queue implementations.
+//
+// # Iteration
+//
+// Every queue can be iterated over without being modified through All (in priority order) and Unordered. Both take a
+// snapshot of the queue under its read lock when iteration begins, and then release the lock. As a result, the queue
+// may be freely modified while iterating, including from within the loop body, but such modifications are not
+// observed by iterations that have already begun. Drain, on the other hand, pops one element at a time, taking the
+// lock for each element. Elements pushed by other goroutines while draining are observed, and breaking out of the loop
+// leaves every remaining element in the queue.
+//
+// # Melding and cloning
+//
+// Meld moves every element of another queue of the same type into the queue and clears the other queue, while
+// CrossMeld accepts any queue from this package (see CrossMeldable). If a bounded queue would overflow, both behave as
+// described by WithCapacity.
+//
+// Clone returns an independent copy of a queue in Θ(n), without modifying it. The copy has its own ID, and orders keys,
+// breaks ties and bounds its size the same way as the original, but it is open even if the original has been closed,
+// and it has no Metrics. CloneFunc is like Clone, but copies every value with a function, which makes it possible to
+// deep-copy values that hold pointers.
+//
+// # Validation
+//
+// Binary, Pairing, Skew, SkewBinomial, CircularBuffer and DelayQueue have a Validate method, which checks the queue's
+// invariants in Θ(n) and returns an error wrapping ErrInvalid if they do not hold. It is meant to catch corruption
+// early, e.g., by calling it after every operation in tests or staging.
 package pqueue
 
 import (
+	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 )
 
 var (
 	ConcurrencySafetyError = errors.New("concurrency-safety error: one or more queues share the same underlying" +
 		"ID. ensure that all queues are being created via their designated constructors")
+	IncompatibleQueueError = errors.New("incompatible queue error: queues can only be cross-melded with queues from " +
+		"this package that share the same key and value types")
 )
 
+// idCounter hands out queue IDs. Every queue type draws from the same counter so that IDs are unique across types,
+// which gives a single global locking order for operations that involve two queues.
+var idCounter atomic.Uint64
+
+// lockPair write-locks two queues in ascending order of their IDs. It panics with ConcurrencySafetyError if both queues
+// share the same ID.
+func lockPair(a *sync.RWMutex, aID uint64, b *sync.RWMutex, bID uint64) {
+	if aID < bID {
+		a.Lock()
+		b.Lock()
+	} else if aID > bID {
+		b.Lock()
+		a.Lock()
+	} else {
+		panic(ConcurrencySafetyError)
+	}
+}
+
+// Queue is the set of operations shared by every min-priority queue in this package. Meld is deliberately left out, as
+// a queue can only be structurally melded with another queue of its own type; CrossMeld works across types instead.
+type Queue[K, V any] interface {
+	CrossMeldable
+
+	// Size returns the number of elements in the queue.
+	Size() int
+	// Clear removes every element from the queue.
+	Clear()
+	// Peek returns the value with the highest priority (i.e., the smallest key) without removing it, or the zero value
+	// if the queue is empty.
+	Peek() V
+	// PeekItem returns the priority and value of the element with the highest priority without removing it. ok is
+	// false if the queue is empty.
+	PeekItem() (priority K, v V, ok bool)
+	// Pop removes and returns the value with the highest priority. ok is false if the queue is empty.
+	Pop() (v V, ok bool)
+	// PopItem removes and returns the priority and value of the element with the highest priority. ok is false if the
+	// queue is empty.
+	PopItem() (priority K, v V, ok bool)
+	// PopWait removes and returns the value with the highest priority, blocking until the queue is non-empty. It
+	// returns ctx's error if ctx is done first, or ErrClosed once a closed queue has been drained.
+	PopWait(ctx context.Context) (V, error)
+	// PopItemWait is like PopWait, but also returns the priority of the element.
+	PopItemWait(ctx context.Context) (priority K, v V, err error)
+	// Push inserts a value with the provided priority. If the queue is full, Push follows the queue's OverflowPolicy.
+	Push(v V, priority K) error
+	// PushWait is like Push, but returns ctx's error if ctx is done while blocked on a full queue.
+	PushWait(ctx context.Context, v V, priority K) error
+	// Close closes the queue. Subsequent pushes fail with ErrClosed, but remaining elements can still be popped.
+	Close()
+	// IsClosed reports whether Close has been called.
+	IsClosed() bool
+	// Done returns a channel that is closed once the queue has been closed and drained.
+	Done() <-chan struct{}
+}
+
+// FIFO is the first-in-first-out counterpart to Queue, implemented by CircularBuffer.
+type FIFO[T any] interface {
+	// Size returns the number of elements in the queue.
+	Size() int
+	// Clear removes every element from the queue.
+	Clear()
+	// Peek returns the oldest value without removing it, or the zero value if the queue is empty.
+	Peek() T
+	// PeekItem returns the oldest value without removing it. ok is false if the queue is empty.
+	PeekItem() (v T, ok bool)
+	// Pop removes and returns the oldest value. ok is false if the queue is empty.
+	Pop() (v T, ok bool)
+	// PopWait removes and returns the oldest value, blocking until the queue is non-empty. It returns ctx's error if
+	// ctx is done first, or ErrClosed once a closed queue has been drained.
+	PopWait(ctx context.Context) (T, error)
+	// Push appends a value to the back of the queue. If the queue is full, Push follows the queue's OverflowPolicy.
+	Push(v T) error
+	// PushWait is like Push, but returns ctx's error if ctx is done while blocked on a full queue.
+	PushWait(ctx context.Context, v T) error
+	// Close closes the queue. Subsequent pushes fail with ErrClosed, but remaining values can still be popped.
+	Close()
+	// IsClosed reports whether Close has been called.
+	IsClosed() bool
+	// Done returns a channel that is closed once the queue has been closed and drained.
+	Done() <-chan struct{}
+}
+
+// CrossMeldable is implemented by every min-priority queue in this package. CrossMeld moves every element of other into
+// the receiver and clears other, regardless of which kind of heap either queue is built on. Queues of the same type are
+// melded structurally, exactly as Meld would; otherwise, other is drained and its elements are reinserted.
+//
+// CrossMeld panics with IncompatibleQueueError if other does not come from this package or does not share the
+// receiver's key and value types. If the receiver is bounded, CrossMeld can fail with ErrFull; see WithCapacity.
 type CrossMeldable interface {
-	CrossMeld(other CrossMeldable)
+	CrossMeld(other CrossMeldable) error
+}
+
+// Item is a value paired with its priority.
+type Item[K, V any] struct {
+	Priority K
+	Value    V
+}
+
+// Handle refers to an element that was pushed onto a queue with PushHandle, and can be passed to the queue's Update and
+// Remove methods. A Handle stays valid until its element is popped or removed, or the queue is cleared. If the queue is
+// melded into another queue of the same type, the Handle follows its element into that queue. CrossMeld between queues
+// of different types reinserts every element, which invalidates their handles.
+type Handle[K, V any] struct {
+	node any
+}
+
+// entry is a key-value pair that has been detached from its underlying heap.
+type entry[K, V any] struct {
+	key   K
+	value V
+	seq   uint64
+}
+
+// entriesOf converts items into entries that have yet to be assigned sequence numbers.
+func entriesOf[K, V any](items []Item[K, V]) []entry[K, V] {
+	entries := make([]entry[K, V], len(items))
+	for i, item := range items {
+		entries[i] = entry[K, V]{key: item.Priority, value: item.Value}
+	}
+	return entries
+}
+
+// popN pops up to n items using the provided pop function, which is expected to be called with the queue's lock held.
+func popN[K, V any](pop func() (K, V, bool), size, n int) []Item[K, V] {
+	n = min(n, size)
+	if n <= 0 {
+		return nil
+	}
+
+	items := make([]Item[K, V], 0, n)
+	for range n {
+		priority, v, _ := pop()
+		items = append(items, Item[K, V]{priority, v})
+	}
+	return items
 }
+
+// crossMelder is implemented by every queue that can take part in a CrossMeld. With the exception of queueID and mutex,
+// its methods must only be called while the queue's lock is held.
+type crossMelder[K, V any] interface {
+	CrossMeldable
+
+	queueID() uint64
+	mutex() *sync.RWMutex
+
+	// sizeLocked returns the number of elements in the queue.
+	sizeLocked() int
+	// acceptsLocked returns ErrClosed if the queue has been closed, or ErrFull if it cannot take incoming more elements
+	// without violating its overflow policy.
+	acceptsLocked(incoming int) error
+	// trimLocked evicts elements until the queue fits within its capacity.
+	trimLocked()
+	// drainLocked removes every element from the queue and returns them in no particular order.
+	drainLocked() []entry[K, V]
+	// insertLocked inserts every provided element into the queue.
+	insertLocked(entries []entry[K, V])
+	// meterLocked returns the meter that reports the queue's operations. Its lock, rlock and lockPair methods are the
+	// exception to the rule above, as they are the ones that acquire the lock.
+	meterLocked() *meter
+	// observerLocked returns the observer that notifies the queue's Observer.
+	observerLocked() *observer[K, V]
+}
+
+// crossMeld drains other into dst while holding both locks. Callers are expected to have already handled the case where
+// other shares dst's concrete type.
+func crossMeld[K, V any](dst crossMelder[K, V], other CrossMeldable) error {
+	src, ok := other.(crossMelder[K, V])
+	if !ok {
+		panic(IncompatibleQueueError)
+	}
+
+	dst.meterLocked().lockPair(dst.mutex(), dst.queueID(), src.mutex(), src.queueID())
+	defer dst.mutex().Unlock()
+	defer src.mutex().Unlock()
+
+	if err := dst.acceptsLocked(src.sizeLocked()); err != nil {
+		return err
+	}
+
+	entries := src.drainLocked()
+	dst.insertLocked(entries)
+	dst.meterLocked().melded(len(entries))
+	dst.observerLocked().meld(len(entries))
+	dst.trimLocked()
+	return nil
+}
+
+var (
+	_ Queue[int, any] = (*Binary[int, any])(nil)
+	_ Queue[int, any] = (*Pairing[int, any])(nil)
+	_ Queue[int, any] = (*Skew[int, any])(nil)
+	_ Queue[int, any] = (*SkewBinomial[int, any])(nil)
+	_ FIFO[any]       = (*CircularBuffer[any])(nil)
+
+	_ crossMelder[int, any] = (*Binary[int, any])(nil)
+	_ crossMelder[int, any] = (*Pairing[int, any])(nil)
+	_ crossMelder[int, any] = (*Skew[int, any])(nil)
+	_ crossMelder[int, any] = (*SkewBinomial[int, any])(nil)
+
+	_ streamer[int, any] = (*Binary[int, any])(nil)
+	_ streamer[int, any] = (*Pairing[int, any])(nil)
+	_ streamer[int, any] = (*Skew[int, any])(nil)
+	_ streamer[int, any] = (*SkewBinomial[int, any])(nil)
+
+	_ serializable[int, any] = (*Binary[int, any])(nil)
+	_ serializable[int, any] = (*Pairing[int, any])(nil)
+	_ serializable[int, any] = (*Skew[int, any])(nil)
+	_ serializable[int, any] = (*SkewBinomial[int, any])(nil)
+)
@@ -0,0 +1,161 @@
+package pqueue
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff determines how long to wait before retrying an element that has failed a number of times.
+type Backoff interface {
+	// Delay returns the delay before the next attempt at processing an element that has failed the given number of
+	// times, which is at least 1.
+	Delay(failures int) time.Duration
+}
+
+// BackoffFunc adapts a function to a Backoff.
+type BackoffFunc func(failures int) time.Duration
+
+func (f BackoffFunc) Delay(failures int) time.Duration {
+	return f(failures)
+}
+
+// FixedBackoff waits d before every retry.
+func FixedBackoff(d time.Duration) Backoff {
+	return BackoffFunc(func(int) time.Duration {
+		return d
+	})
+}
+
+// ExponentialBackoff waits initial before the first retry, and doubles the delay after every subsequent failure, up to
+// limit.
+func ExponentialBackoff(initial, limit time.Duration) Backoff {
+	return BackoffFunc(func(failures int) time.Duration {
+		d := initial
+		for i := 1; i < failures && d < limit; i++ {
+			if d > limit/2 {
+				d = limit
+			} else {
+				d *= 2
+			}
+		}
+		return min(d, limit)
+	})
+}
+
+// JitteredBackoff randomizes the delays of b by up to the given fraction in either direction (e.g., a fraction of 0.2
+// turns a delay of 10s into one between 8s and 12s), which keeps elements that failed together from being retried
+// together. The fraction is clamped to [0, 1], so that delays are never negative.
+func JitteredBackoff(b Backoff, fraction float64) Backoff {
+	fraction = min(max(fraction, 0), 1)
+	return BackoffFunc(func(failures int) time.Duration {
+		d := float64(b.Delay(failures))
+		return time.Duration(d + d*fraction*(2*rand.Float64()-1))
+	})
+}
+
+// WithBackoff makes a Retrier wait according to b between attempts. Only NewRetrier supports it.
+func WithBackoff(b Backoff) Option {
+	return func(o *options) {
+		o.set |= optBackoff
+		o.backoff = b
+	}
+}
+
+// WithMaxFailures makes a Retrier move an element to its dead-letter queue once it has failed n times. Only NewRetrier
+// supports it.
+func WithMaxFailures(n int) Option {
+	return func(o *options) {
+		o.set |= optMaxFailures
+		o.maxFailures = n
+	}
+}
+
+// Attempt is a value that is processed with retries, along with the number of times processing it has failed so far.
+type Attempt[V any] struct {
+	Value    V
+	Failures int
+}
+
+// Retrier re-enqueues elements that failed to be processed, with a priority that reflects when they should be retried.
+// Elements are stored as Attempts, which track how many times each element has failed; once an element has failed too
+// many times (see WithMaxFailures), it is moved to a dead-letter queue instead.
+//
+// How long to wait before a retry is determined by a Backoff (see WithBackoff), and a schedule function turns that
+// delay into the element's new priority. RetryAt and RetryAtUnixNano provide schedule functions for queues keyed by
+// time. By default, a Retrier uses an ExponentialBackoff from 100ms up to one minute, and gives up after 5 failures.
+type Retrier[K, V any] struct {
+	queue       Queue[K, Attempt[V]]
+	dead        Queue[K, Attempt[V]]
+	schedule    func(priority K, delay time.Duration) K
+	backoff     Backoff
+	maxFailures int
+}
+
+// NewRetrier creates a Retrier that re-enqueues failed elements onto q, and moves elements that failed too many times
+// onto dead.
+func NewRetrier[K, V any](q, dead Queue[K, Attempt[V]], schedule func(priority K, delay time.Duration) K, opts ...Option) *Retrier[K, V] {
+	o := newOptions(opts, optBackoff|optMaxFailures)
+
+	backoff := o.backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(100*time.Millisecond, time.Minute)
+	}
+
+	return &Retrier[K, V]{
+		queue:       q,
+		dead:        dead,
+		schedule:    schedule,
+		backoff:     backoff,
+		maxFailures: cmp.Or(o.maxFailures, 5),
+	}
+}
+
+// Push inserts a value onto the queue for its first attempt.
+func (r *Retrier[K, V]) Push(v V, priority K) error {
+	return r.queue.Push(Attempt[V]{Value: v}, priority)
+}
+
+// Fail records that processing an element, which was popped from the queue with the provided priority, has failed. The
+// element is pushed back onto the queue with a priority computed from the backoff, or onto the dead-letter queue if it
+// has failed too many times, in which case dead is true.
+func (r *Retrier[K, V]) Fail(a Attempt[V], priority K) (dead bool, err error) {
+	a.Failures++
+	if a.Failures >= r.maxFailures {
+		return true, r.dead.Push(a, priority)
+	}
+	return false, r.queue.Push(a, r.schedule(priority, r.backoff.Delay(a.Failures)))
+}
+
+// FailLease is like Fail, but for an element that was leased from the queue with lr, whose lease is acknowledged.
+// It returns ErrLeaseExpired if the lease is no longer held, including if its deadline has passed, since the element
+// is then returned to the queue by lr. The lease is left untouched if the element cannot be pushed onto either queue.
+func (r *Retrier[K, V]) FailLease(lr *Leaser[K, Attempt[V]], receipt Receipt[K, Attempt[V]]) (dead bool, err error) {
+	err = lr.settle(receipt, func() error {
+		dead, err = r.Fail(receipt.Value, receipt.Priority)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return dead, nil
+}
+
+// RetryAt returns a schedule function for queues keyed by time, which schedules retries once the delay has elapsed
+// from the current time according to c. A nil Clock uses the system clock.
+func RetryAt(c Clock) func(priority time.Time, delay time.Duration) time.Time {
+	if c == nil {
+		c = systemClock{}
+	}
+	return func(_ time.Time, delay time.Duration) time.Time {
+		return c.Now().Add(delay)
+	}
+}
+
+// RetryAtUnixNano is like RetryAt, but for queues keyed by Unix time in nanoseconds.
+func RetryAtUnixNano(c Clock) func(priority int64, delay time.Duration) int64 {
+	at := RetryAt(c)
+	return func(_ int64, delay time.Duration) int64 {
+		return at(time.Time{}, delay).UnixNano()
+	}
+}
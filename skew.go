@@ -2,104 +2,653 @@ package pqueue
 
 import (
 	"cmp"
+	"context"
+	"io"
+	"iter"
 	"sync"
-	"sync/atomic"
 
 	"github.com/AndrewChon/pqueue/skew"
 )
 
-var skewIDCounter atomic.Uint64
-
 // Skew is a concurrency-safe, min-priority queue built on a skew heap.
-type Skew[K cmp.Ordered, V any] struct {
+type Skew[K, V any] struct {
 	// A locking order needs to be defined and strictly followed for safety; thus, we do not want to expose the mutex.
 	l  sync.RWMutex
 	id uint64
 
-	root *skew.Tree[K, V]
-	size int
+	notEmpty waiters
+	bound    bound
+	closer   closer
+
+	root     *skew.Tree[K, V]
+	size     int
+	less     func(a, b K) bool
+	tieBreak TieBreak
+	codec    codecs[K, V]
+	meter    meter
+	observer observer[K, V]
+}
+
+func NewSkew[K cmp.Ordered, V any](opts ...Option) *Skew[K, V] {
+	return NewSkewFunc[K, V](cmp.Less[K], opts...)
 }
 
-func NewSkew[K cmp.Ordered, V any]() *Skew[K, V] {
+// NewSkewFunc is like NewSkew, but orders keys using the provided less function rather than requiring K to be ordered.
+// For example, a max-priority queue can be built by providing a less function that reports whether a > b. Queues that
+// are melded together are expected to order keys the same way.
+func NewSkewFunc[K, V any](less func(a, b K) bool, opts ...Option) *Skew[K, V] {
+	o := newOptions(opts, heapOptions)
+
 	return &Skew[K, V]{
-		id:   skewIDCounter.Add(1),
-		root: nil,
-		size: 0,
+		id:       idCounter.Add(1),
+		bound:    newBound(o, heapPolicies...),
+		closer:   newCloser(),
+		root:     nil,
+		size:     0,
+		less:     less,
+		tieBreak: o.tieBreak,
+		codec:    newCodecs[K, V](o),
+		meter:    newMeter(o),
+		observer: newObserver[K, V](o),
 	}
 }
 
+// NewSkewFrom builds a Skew queue from the provided items in linear time by melding them together in pairs.
+// If there are more items than the queue's capacity, the items with the lowest priorities are discarded.
+func NewSkewFrom[K cmp.Ordered, V any](items []Item[K, V], opts ...Option) *Skew[K, V] {
+	return NewSkewFromFunc(items, cmp.Less[K], opts...)
+}
+
+// NewSkewFromFunc is like NewSkewFrom, but orders keys using the provided less function.
+func NewSkewFromFunc[K, V any](items []Item[K, V], less func(a, b K) bool, opts ...Option) *Skew[K, V] {
+	s := NewSkewFunc[K, V](less, opts...)
+	s.insertLocked(entriesOf(items))
+	s.trimLocked()
+
+	return s
+}
+
+// CollectSkew builds a Skew queue from the key-value pairs of seq, where each key is a priority.
+func CollectSkew[K cmp.Ordered, V any](seq iter.Seq2[K, V], opts ...Option) *Skew[K, V] {
+	return NewSkewFrom(collect(seq), opts...)
+}
+
+// CollectSkewFunc is like CollectSkew, but orders keys using the provided less function.
+func CollectSkewFunc[K, V any](seq iter.Seq2[K, V], less func(a, b K) bool, opts ...Option) *Skew[K, V] {
+	return NewSkewFromFunc(collect(seq), less, opts...)
+}
+
 func (s *Skew[K, V]) Size() int {
-	s.l.RLock()
+	s.meter.rlock(&s.l)
 	defer s.l.RUnlock()
 
 	return s.size
 }
 
 func (s *Skew[K, V]) Clear() {
-	s.l.Lock()
+	s.meter.lock(&s.l)
 	defer s.l.Unlock()
 
-	s.root = nil
-	s.size = 0
+	s.observer.clear(s.sizeLocked())
+	s.clearLocked()
+	s.meter.cleared()
 }
 
 func (s *Skew[K, V]) Peek() V {
-	s.l.RLock()
+	_, v, _ := s.PeekItem()
+	return v
+}
+
+// PeekItem returns the priority and value of the element with the highest priority without removing it. ok is false if
+// the queue is empty.
+func (s *Skew[K, V]) PeekItem() (priority K, v V, ok bool) {
+	s.meter.rlock(&s.l)
 	defer s.l.RUnlock()
 
-	minNode := skew.FindMin(s.root)
-	if minNode == nil {
-		var zero V
-		return zero
+	return s.peekLocked()
+}
+
+func (s *Skew[K, V]) Pop() (v V, ok bool) {
+	_, v, ok = s.PopItem()
+	return v, ok
+}
+
+// PopItem removes and returns the priority and value of the element with the highest priority. ok is false if the
+// queue is empty.
+func (s *Skew[K, V]) PopItem() (priority K, v V, ok bool) {
+	s.meter.lock(&s.l)
+	defer s.l.Unlock()
+
+	return s.popLocked()
+}
+
+// Push inserts a value with the provided priority. If the queue is full, Push follows the queue's OverflowPolicy; see
+// WithCapacity.
+func (s *Skew[K, V]) Push(v V, priority K) error {
+	return s.PushWait(context.Background(), v, priority)
+}
+
+// PushWait is like Push, but returns ctx's error if ctx is done while blocked on a full queue.
+func (s *Skew[K, V]) PushWait(ctx context.Context, v V, priority K) error {
+	s.meter.lock(&s.l)
+	defer s.l.Unlock()
+
+	_, err := s.admitLocked(ctx, v, priority)
+	return err
+}
+
+// PopWait removes and returns the value with the highest priority, blocking until the queue is non-empty. If ctx is
+// done first, PopWait returns ctx's error.
+func (s *Skew[K, V]) PopWait(ctx context.Context) (V, error) {
+	_, v, err := s.PopItemWait(ctx)
+	return v, err
+}
+
+// PopItemWait is like PopWait, but also returns the priority of the element.
+func (s *Skew[K, V]) PopItemWait(ctx context.Context) (priority K, v V, err error) {
+	e, err := s.popEntryWait(ctx)
+	return e.key, e.value, err
+}
+
+// Close closes the queue. Subsequent pushes and melds into the queue fail with ErrClosed, and every goroutine blocked
+// in PopWait or PushWait is woken. Elements that remain in the queue can still be popped, and PopWait returns ErrClosed
+// once the queue is empty. Calling Close more than once has no effect.
+func (s *Skew[K, V]) Close() {
+	s.meter.lock(&s.l)
+	defer s.l.Unlock()
+
+	s.closer.close(s.size)
+	s.notEmpty.broadcast()
+	s.bound.notFull.broadcast()
+}
+
+// IsClosed reports whether Close has been called.
+func (s *Skew[K, V]) IsClosed() bool {
+	s.meter.rlock(&s.l)
+	defer s.l.RUnlock()
+
+	return s.closer.closed
+}
+
+// Done returns a channel that is closed once the queue has been closed and every remaining element has been removed.
+func (s *Skew[K, V]) Done() <-chan struct{} {
+	return s.closer.done
+}
+
+// TieBreak returns the TieBreak the queue was created with (see WithTieBreak).
+func (s *Skew[K, V]) TieBreak() TieBreak {
+	return s.tieBreak
+}
+
+// All returns an iterator over every element in priority order, without modifying the queue.
+func (s *Skew[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.meter.rlock(&s.l)
+		entries := s.entriesLocked()
+		s.l.RUnlock()
+
+		orderedSeq(entries, s.less)(yield)
+	}
+}
+
+// Unordered returns an iterator over every element in no particular order, without modifying the queue. It is cheaper
+// than All, as the elements do not need to be ordered.
+func (s *Skew[K, V]) Unordered() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.meter.rlock(&s.l)
+		entries := s.entriesLocked()
+		s.l.RUnlock()
+
+		unorderedSeq(entries)(yield)
 	}
+}
 
-	return minNode.Value()
+// Drain returns an iterator that pops every element in priority order until the queue is empty.
+func (s *Skew[K, V]) Drain() iter.Seq2[K, V] {
+	return drainSeq(s.PopItem)
 }
 
-func (s *Skew[K, V]) Pop() (v V, ok bool) {
-	s.l.Lock()
+// PushMany inserts every provided item while only taking the lock once. If the items do not all fit, PushMany fails
+// with ErrFull without inserting any of them under OverflowError; under every other policy, each item is pushed in turn
+// as if by Push. In that case the batch is not atomic: under OverflowBlock, the lock is released while waiting for
+// room, so other operations may run between items, and items pushed before an error stay in the queue.
+func (s *Skew[K, V]) PushMany(items []Item[K, V]) error {
+	return s.PushManyWait(context.Background(), items)
+}
+
+// PushManyWait is like PushMany, but returns ctx's error if ctx is done while blocked on a full queue.
+func (s *Skew[K, V]) PushManyWait(ctx context.Context, items []Item[K, V]) error {
+	s.meter.lock(&s.l)
 	defer s.l.Unlock()
 
-	t := skew.FindMin(s.root)
+	if s.closer.closed {
+		return ErrClosed
+	}
+	if s.bound.room(s.size, len(items)) {
+		s.insertLocked(entriesOf(items))
+		for _, item := range items {
+			s.observer.push(item.Priority, item.Value)
+		}
+		s.meter.pushed(len(items))
+		return nil
+	}
+	if s.bound.policy == OverflowError {
+		return ErrFull
+	}
+
+	for _, item := range items {
+		if _, err := s.admitLocked(ctx, item.Value, item.Priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PopN removes and returns up to n items in priority order while only taking the lock once.
+func (s *Skew[K, V]) PopN(n int) []Item[K, V] {
+	s.meter.lock(&s.l)
+	defer s.l.Unlock()
+
+	return popN(s.popLocked, s.size, n)
+}
+
+// PushHandle inserts a value with the provided priority and returns a Handle to it. If the queue is full, PushHandle
+// behaves like Push, and the returned Handle is nil if the value was not inserted.
+func (s *Skew[K, V]) PushHandle(v V, priority K) (*Handle[K, V], error) {
+	return s.PushHandleWait(context.Background(), v, priority)
+}
+
+// PushHandleWait is like PushHandle, but returns ctx's error if ctx is done while blocked on a full queue.
+func (s *Skew[K, V]) PushHandleWait(ctx context.Context, v V, priority K) (*Handle[K, V], error) {
+	s.meter.lock(&s.l)
+	defer s.l.Unlock()
+
+	t, err := s.admitLocked(ctx, v, priority)
 	if t == nil {
-		return
+		return nil, err
 	}
+	return &Handle[K, V]{node: t}, nil
+}
 
-	v = t.Value()
-	s.root = skew.RemoveMin(s.root)
-	s.size--
+// Update changes the priority of the element referred to by h. It returns false if h does not refer to an element in
+// this queue. Validating h costs time proportional to the depth of its element.
+func (s *Skew[K, V]) Update(h *Handle[K, V], priority K) bool {
+	s.meter.lock(&s.l)
+	defer s.l.Unlock()
+
+	t := s.nodeOf(h)
+	if t == nil {
+		return false
+	}
 
-	return v, true
+	if s.less(priority, t.Key()) {
+		s.root = skew.DecreaseKeyFunc(s.root, t, priority, s.less)
+	} else if s.less(t.Key(), priority) {
+		s.root = skew.IncreaseKeyFunc(s.root, t, priority, s.less)
+	}
+	return true
 }
 
-func (s *Skew[K, V]) Push(v V, priority K) {
-	s.l.Lock()
+// Remove removes the element referred to by h in O(log n) amortized. It returns false if h does not refer to an element
+// in this queue. Validating h costs time proportional to the depth of its element.
+func (s *Skew[K, V]) Remove(h *Handle[K, V]) bool {
+	s.meter.lock(&s.l)
 	defer s.l.Unlock()
 
-	newNode := skew.NewTree(priority, v)
-	s.root = skew.Insert(s.root, newNode)
+	t := s.nodeOf(h)
+	if t == nil {
+		return false
+	}
 
-	s.size++
+	s.root = skew.DeleteFunc(s.root, t, s.less)
+	s.size--
+	s.meter.popped(t)
+	s.observer.pop(t.Key(), t.Value())
+	s.removedLocked()
+	return true
 }
 
-// Meld merges another Skew queue into this one and clears it.
-func (s *Skew[K, V]) Meld(other *Skew[K, V]) {
-	if s.id < other.id {
-		s.l.Lock()
-		other.l.Lock()
-	} else if s.id > other.id {
-		other.l.Lock()
-		s.l.Lock()
-	} else {
-		panic(ConcurrencySafetyError)
+// nodeOf returns the node referred to by h, or nil if h does not refer to an element in this queue.
+func (s *Skew[K, V]) nodeOf(h *Handle[K, V]) *skew.Tree[K, V] {
+	if h == nil || s.root == nil {
+		return nil
+	}
+
+	t, ok := h.node.(*skew.Tree[K, V])
+	if !ok || t.Root() != s.root {
+		return nil
 	}
+	return t
+}
 
+// Meld merges another Skew queue into this one and clears it.
+func (s *Skew[K, V]) Meld(other *Skew[K, V]) error {
+	s.meter.lockPair(&s.l, s.id, &other.l, other.id)
 	defer s.l.Unlock()
 	defer other.l.Unlock()
 
-	s.root = skew.Meld(s.root, other.root)
+	if err := s.acceptsLocked(other.size); err != nil {
+		return err
+	}
+
+	adopt(&s.meter, &other.meter, other.root.Nodes())
+	s.meter.melded(other.sizeLocked())
+	s.observer.meld(other.sizeLocked())
+	s.root = skew.MeldFunc(s.root, other.root, s.less)
 	s.size += other.size
+	other.clearLocked()
+	s.trimLocked()
+	s.meter.depth(s.size)
+	s.notEmpty.broadcast()
+	return nil
+}
+
+// CrossMeld merges any other queue from this package into this one and clears it. See CrossMeldable.
+func (s *Skew[K, V]) CrossMeld(other CrossMeldable) error {
+	if o, ok := other.(*Skew[K, V]); ok {
+		return s.Meld(o)
+	}
+
+	return crossMeld[K, V](s, other)
+}
+
+// Clone returns an independent copy of the queue. Handles to the queue's elements do not refer to the copy's elements.
+func (s *Skew[K, V]) Clone() *Skew[K, V] {
+	return s.CloneFunc(nil)
+}
+
+// CloneFunc is like Clone, but copies every value with copyValue.
+func (s *Skew[K, V]) CloneFunc(copyValue func(V) V) *Skew[K, V] {
+	s.meter.rlock(&s.l)
+	defer s.l.RUnlock()
+
+	return &Skew[K, V]{
+		id:       idCounter.Add(1),
+		bound:    s.bound.clone(),
+		closer:   newCloser(),
+		root:     skew.Clone(s.root, copyValue),
+		size:     s.size,
+		less:     s.less,
+		tieBreak: s.tieBreak,
+		codec:    s.codec,
+		observer: s.observer,
+	}
+}
+
+// MarshalBinary encodes every element of the queue in a versioned binary format that is shared by every heap-based
+// queue, using the queue's codecs (see WithKeyCodec).
+func (s *Skew[K, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary[K, V](s)
+}
+
+// UnmarshalBinary replaces the contents of the queue with the elements encoded in data by the MarshalBinary method of
+// any heap-based queue. The queue must have been created by its constructor, and keeps its own comparator and options.
+func (s *Skew[K, V]) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary[K, V](s, data)
+}
+
+// MarshalJSON is like MarshalBinary, but encodes the elements as JSON.
+func (s *Skew[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON[K, V](s)
+}
+
+// UnmarshalJSON is like UnmarshalBinary, but decodes elements encoded by MarshalJSON.
+func (s *Skew[K, V]) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON[K, V](s, data)
+}
+
+// GobEncode encodes the queue in the same format as MarshalBinary.
+func (s *Skew[K, V]) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode decodes the queue in the same way as UnmarshalBinary.
+func (s *Skew[K, V]) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
+
+// Inspect returns the Stats of the queue, with up to top of its elements with the highest priorities. It costs Θ(n),
+// plus O(log n) for every element returned.
+func (s *Skew[K, V]) Inspect(top int) Stats {
+	s.meter.rlock(&s.l)
+	entries := s.entriesLocked()
+	stats := Stats{
+		Kind: "Skew",
+		ID:   s.id,
+		Size: s.size,
+	}
+	s.l.RUnlock()
+
+	stats.Top = topOf(entries, s.less, top)
+	return stats
+}
+
+// WriteDOT writes the heap that backs the queue to w as a Graphviz graph, as described by skew.Tree.WriteDOT, to help
+// diagnose the shape of the heap. The queue is read-locked until the whole graph has been written.
+func (s *Skew[K, V]) WriteDOT(w io.Writer) error {
+	s.meter.rlock(&s.l)
+	defer s.l.RUnlock()
+
+	return s.root.WriteDOT(w)
+}
+
+// Validate checks the invariants of the heap that backs the queue (see skew.Validate), that the queue's size matches
+// the number of elements in its heap, and that its size fits within its capacity.
+func (s *Skew[K, V]) Validate() error {
+	s.meter.rlock(&s.l)
+	defer s.l.RUnlock()
+
+	if err := skew.ValidateFunc(s.root, s.less); err != nil {
+		return invalid(err)
+	}
+	return validateSize(countOf(s.root.Nodes()), s.size, &s.bound)
+}
+
+func (s *Skew[K, V]) queueID() uint64 {
+	return s.id
+}
+
+func (s *Skew[K, V]) mutex() *sync.RWMutex {
+	return &s.l
+}
+
+func (s *Skew[K, V]) sizeLocked() int {
+	return s.size
+}
+
+func (s *Skew[K, V]) acceptsLocked(incoming int) error {
+	if s.closer.closed {
+		return ErrClosed
+	}
+	return s.bound.fits(s.size, incoming)
+}
+
+// trimLocked evicts the elements with the lowest priorities until the queue fits within its capacity.
+func (s *Skew[K, V]) trimLocked() {
+	excess := s.bound.excess(s.size)
+	if excess == 0 {
+		return
+	}
+
+	for _, t := range lowestN(s.root.Nodes(), s.less, excess) {
+		s.root = skew.DeleteFunc(s.root, t, s.less)
+		s.size--
+		s.meter.forget(t)
+		s.observer.drop(t.Key(), t.Value())
+	}
+	s.meter.depth(s.size)
+}
+
+// removedLocked is called whenever elements are removed from the queue.
+func (s *Skew[K, V]) removedLocked() {
+	s.bound.notFull.broadcast()
+	s.closer.settle(s.size)
+	s.meter.depth(s.size)
+}
+
+func (s *Skew[K, V]) meterLocked() *meter {
+	return &s.meter
+}
+
+func (s *Skew[K, V]) observerLocked() *observer[K, V] {
+	return &s.observer
+}
+
+func (s *Skew[K, V]) codecs() codecs[K, V] {
+	return s.codec
+}
+
+func (s *Skew[K, V]) initialized() bool {
+	return s.less != nil
+}
+
+func (s *Skew[K, V]) drainLocked() []entry[K, V] {
+	entries := s.entriesLocked()
+	s.clearLocked()
+
+	return entries
+}
+
+// entriesLocked returns a snapshot of every element in the queue, in no particular order.
+func (s *Skew[K, V]) entriesLocked() []entry[K, V] {
+	entries := make([]entry[K, V], 0, s.size)
+	for t := range s.root.Nodes() {
+		entries = append(entries, entry[K, V]{t.Key(), t.Value(), t.Seq()})
+	}
+
+	return entries
+}
+
+func (s *Skew[K, V]) clearLocked() {
+	s.root = nil
+	s.size = 0
+	s.meter.forgetAll()
+	s.removedLocked()
+}
+
+func (s *Skew[K, V]) insertLocked(entries []entry[K, V]) {
+	nodes := make([]*skew.Tree[K, V], 0, len(entries))
+	for _, e := range entries {
+		t := skew.NewSequencedTree(e.key, e.value, s.seqOf(e))
+		s.meter.stamp(t)
+		nodes = append(nodes, t)
+	}
+
+	s.root = skew.MeldFunc(s.root, skew.BuildFunc(nodes, s.less), s.less)
+	s.size += len(entries)
+	s.meter.depth(s.size)
+	s.notEmpty.broadcast()
+}
+
+// seqOf returns the sequence number to give an element that is being moved into this queue. The element keeps its
+// sequence number if it has one, so that insertion order survives a CrossMeld.
+func (s *Skew[K, V]) seqOf(e entry[K, V]) uint64 {
+	if e.seq == 0 {
+		return s.tieBreak.nextSeq()
+	}
+	return e.seq
+}
+
+// admitLocked pushes a value once the queue has room for it, as dictated by its overflow policy. It returns nil if the
+// value was not pushed.
+func (s *Skew[K, V]) admitLocked(ctx context.Context, v V, priority K) (*skew.Tree[K, V], error) {
+	ok, err := s.bound.admit(ctx, &s.meter, &s.l, &s.closer, s.sizeLocked, func() bool {
+		return s.evictLocked(priority)
+	})
+	if !ok {
+		if err == nil {
+			s.observer.drop(priority, v)
+		}
+		return nil, err
+	}
+
+	return s.pushLocked(v, priority), nil
+}
+
+// evictLocked removes the element with the lowest priority to make room for an incoming element with the provided
+// priority. It returns false, leaving the queue untouched, if the incoming element would have the lowest priority.
+func (s *Skew[K, V]) evictLocked(priority K) bool {
+	t, ok := lowest(s.root.Nodes(), s.less)
+	if !ok || !outranks(priority, t, s.less, s.tieBreak) {
+		return false
+	}
+
+	s.root = skew.DeleteFunc(s.root, t, s.less)
+	s.size--
+	s.meter.forget(t)
+	s.observer.drop(t.Key(), t.Value())
+	return true
+}
+
+func (s *Skew[K, V]) pushLocked(v V, priority K) *skew.Tree[K, V] {
+	newNode := skew.NewSequencedTree(priority, v, s.tieBreak.nextSeq())
+	s.root = skew.InsertFunc(s.root, newNode, s.less)
+	s.size++
+	s.meter.stamp(newNode)
+	s.meter.pushed(1)
+	s.observer.push(priority, v)
+	s.meter.depth(s.size)
+	s.notEmpty.broadcast()
+
+	return newNode
+}
+
+func (s *Skew[K, V]) peekLocked() (priority K, v V, ok bool) {
+	minNode := skew.FindMin(s.root)
+	if minNode == nil {
+		return
+	}
+
+	return minNode.Key(), minNode.Value(), true
+}
+
+func (s *Skew[K, V]) popLocked() (priority K, v V, ok bool) {
+	e, ok := s.popEntryLocked()
+	return e.key, e.value, ok
+}
+
+// popEntryLocked is like popLocked, but returns the element as an entry, along with its sequence number.
+func (s *Skew[K, V]) popEntryLocked() (entry[K, V], bool) {
+	h, ok := s.takeLocked()
+	if ok {
+		s.meter.poppedAt(h.pushedAt)
+		s.observer.pop(h.key, h.value)
+	}
+	return h.entry, ok
+}
+
+func (s *Skew[K, V]) popEntryWait(ctx context.Context) (entry[K, V], error) {
+	return waitFor(ctx, &s.meter, &s.l, &s.notEmpty, &s.closer, s.popEntryLocked)
+}
+
+// takeLocked removes the element with the highest priority without counting it as popped.
+func (s *Skew[K, V]) takeLocked() (held[K, V], bool) {
+	t := skew.FindMin(s.root)
+	if t == nil {
+		return held[K, V]{}, false
+	}
+
+	s.root = skew.RemoveMinFunc(s.root, s.less)
+	s.size--
+	s.removedLocked()
+	return held[K, V]{entry[K, V]{t.Key(), t.Value(), t.Seq()}, s.meter.taken(t)}, true
+}
+
+func (s *Skew[K, V]) takeWait(ctx context.Context) (held[K, V], error) {
+	return waitFor(ctx, &s.meter, &s.l, &s.notEmpty, &s.closer, s.takeLocked)
+}
+
+// requeueLocked puts an element that was removed with takeLocked back into the queue, without counting it as pushed.
+func (s *Skew[K, V]) requeueLocked(h held[K, V]) {
+	t := skew.NewSequencedTree(h.key, h.value, h.seq)
+	s.root = skew.InsertFunc(s.root, t, s.less)
+	s.size++
+	s.meter.stampAt(t, h.pushedAt)
+	s.meter.depth(s.size)
+	s.notEmpty.broadcast()
+}
 
-	other.root = nil
-	other.size = 0
+// pushedLocked returns a channel that is closed the next time elements are added to the queue.
+func (s *Skew[K, V]) pushedLocked() <-chan struct{} {
+	return s.notEmpty.wait()
 }
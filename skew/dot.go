@@ -0,0 +1,39 @@
+package skew
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteDOT writes the Tree rooted at t to w as a graph in the DOT language of Graphviz. Every node is labelled with its
+// key, and is linked to its left and right children with edges labelled L and R, and to its parent with a dotted edge.
+// Keys are formatted with fmt. A nil Tree is written as an empty graph.
+func (t *Tree[K, V]) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	ids := make(map[*Tree[K, V]]int)
+	for n := range t.Nodes() {
+		ids[n] = len(ids)
+	}
+
+	fmt.Fprintln(bw, "digraph skew {")
+	fmt.Fprintln(bw, "\tnode [shape=circle];")
+	for n := range t.Nodes() {
+		fmt.Fprintf(bw, "\tn%d [label=%q];\n", ids[n], fmt.Sprint(n.key))
+	}
+	for n := range t.Nodes() {
+		if n.left != nil {
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"L\"];\n", ids[n], ids[n.left])
+		}
+		if n.right != nil {
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"R\"];\n", ids[n], ids[n.right])
+		}
+		if n.parent != nil {
+			fmt.Fprintf(bw, "\tn%d -> n%d [style=dotted, constraint=false];\n", ids[n], ids[n.parent])
+		}
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
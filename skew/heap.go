@@ -2,21 +2,32 @@ package skew
 
 import (
 	"cmp"
+	"iter"
 )
 
-type Tree[K cmp.Ordered, V any] struct {
-	key   K
-	value V
-	left  *Tree[K, V]
-	right *Tree[K, V]
+type Tree[K, V any] struct {
+	key    K
+	value  V
+	seq    uint64
+	parent *Tree[K, V]
+	left   *Tree[K, V]
+	right  *Tree[K, V]
 }
 
-func NewTree[K cmp.Ordered, V any](key K, value V) *Tree[K, V] {
+func NewTree[K, V any](key K, value V) *Tree[K, V] {
+	return NewSequencedTree(key, value, 0)
+}
+
+// NewSequencedTree is like NewTree, but also assigns the node a sequence number. Nodes with equal keys are ordered by
+// ascending sequence number.
+func NewSequencedTree[K, V any](key K, value V, seq uint64) *Tree[K, V] {
 	return &Tree[K, V]{
-		key:   key,
-		value: value,
-		left:  nil,
-		right: nil,
+		key:    key,
+		value:  value,
+		seq:    seq,
+		parent: nil,
+		left:   nil,
+		right:  nil,
 	}
 }
 
@@ -28,7 +39,109 @@ func (t *Tree[K, V]) Value() V {
 	return t.value
 }
 
-func FindMin[K cmp.Ordered, V any](t *Tree[K, V]) *Tree[K, V] {
+func (t *Tree[K, V]) Seq() uint64 {
+	return t.seq
+}
+
+// Root returns the root of the Tree that t currently belongs to. The cost is proportional to the depth of t.
+func (t *Tree[K, V]) Root() *Tree[K, V] {
+	if t == nil {
+		return nil
+	}
+
+	for t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
+
+// Nodes returns an iterator over every node in the Tree rooted at t, in no particular order.
+func (t *Tree[K, V]) Nodes() iter.Seq[*Tree[K, V]] {
+	return func(yield func(*Tree[K, V]) bool) {
+		if t == nil {
+			return
+		}
+
+		stack := []*Tree[K, V]{t}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if !yield(n) {
+				return
+			}
+
+			if n.left != nil {
+				stack = append(stack, n.left)
+			}
+			if n.right != nil {
+				stack = append(stack, n.right)
+			}
+		}
+	}
+}
+
+// Build forms a Tree from standalone nodes in linear time by repeatedly melding them in pairs, which also keeps the
+// resulting Tree balanced. Build reuses the provided slice as scratch space.
+func Build[K cmp.Ordered, V any](nodes []*Tree[K, V]) *Tree[K, V] {
+	return BuildFunc(nodes, cmp.Less[K])
+}
+
+// BuildFunc is like Build, but orders keys using the provided less function.
+func BuildFunc[K, V any](nodes []*Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	for len(nodes) > 1 {
+		n := 0
+		for i := 0; i+1 < len(nodes); i += 2 {
+			nodes[n] = MeldFunc(nodes[i], nodes[i+1], less)
+			n++
+		}
+
+		if len(nodes)%2 == 1 {
+			nodes[n] = nodes[len(nodes)-1]
+			n++
+		}
+
+		nodes = nodes[:n]
+	}
+
+	return nodes[0]
+}
+
+// Clone returns a structural copy of the Tree rooted at t in Θ(n). If copyValue is not nil, it is used to copy every
+// value.
+func Clone[K, V any](t *Tree[K, V], copyValue func(V) V) *Tree[K, V] {
+	if t == nil {
+		return nil
+	}
+
+	root := cloneNode(t, copyValue)
+
+	// The tree is copied iteratively, as the left spine of a skew heap may be arbitrarily long.
+	stack := [][2]*Tree[K, V]{{t, root}}
+	for len(stack) > 0 {
+		n, c := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		if n.left != nil {
+			c.left = cloneNode(n.left, copyValue)
+			c.left.parent = c
+			stack = append(stack, [2]*Tree[K, V]{n.left, c.left})
+		}
+		if n.right != nil {
+			c.right = cloneNode(n.right, copyValue)
+			c.right.parent = c
+			stack = append(stack, [2]*Tree[K, V]{n.right, c.right})
+		}
+	}
+
+	return root
+}
+
+func FindMin[K, V any](t *Tree[K, V]) *Tree[K, V] {
 	if t == nil {
 		return nil
 	}
@@ -36,17 +149,23 @@ func FindMin[K cmp.Ordered, V any](t *Tree[K, V]) *Tree[K, V] {
 }
 
 func Meld[K cmp.Ordered, V any](a, b *Tree[K, V]) *Tree[K, V] {
+	return MeldFunc(a, b, cmp.Less[K])
+}
+
+// MeldFunc is like Meld, but orders keys using the provided less function.
+func MeldFunc[K, V any](a, b *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
 	if a == nil {
 		return b
 	} else if b == nil {
 		return a
 	}
 
-	if b.key < a.key {
+	if precedes(b, a, less) {
 		a, b = b, a
 	}
 
-	a.right, a.left = a.left, Meld(b, a.right)
+	a.right, a.left = a.left, MeldFunc(b, a.right, less)
+	a.left.parent = a
 	return a
 }
 
@@ -54,9 +173,136 @@ func Insert[K cmp.Ordered, V any](t *Tree[K, V], new *Tree[K, V]) *Tree[K, V] {
 	return Meld(t, new)
 }
 
+// InsertFunc is like Insert, but orders keys using the provided less function.
+func InsertFunc[K, V any](t *Tree[K, V], new *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	return MeldFunc(t, new, less)
+}
+
 func RemoveMin[K cmp.Ordered, V any](t *Tree[K, V]) *Tree[K, V] {
+	return RemoveMinFunc(t, cmp.Less[K])
+}
+
+// RemoveMinFunc is like RemoveMin, but orders keys using the provided less function.
+func RemoveMinFunc[K, V any](t *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
 	if t == nil {
 		return nil
 	}
-	return Meld(t.left, t.right)
+
+	left, right := t.left, t.right
+	t.left, t.right = nil, nil
+
+	if left != nil {
+		left.parent = nil
+	}
+	if right != nil {
+		right.parent = nil
+	}
+
+	return MeldFunc(left, right, less)
+}
+
+// DecreaseKey decreases the target node's key to the provided new key. The new key must be less than the target node's
+// current key. The target node's subtree is cut from its parent and melded back into the Tree.
+func DecreaseKey[K cmp.Ordered, V any](t *Tree[K, V], targetNode *Tree[K, V], newKey K) *Tree[K, V] {
+	return DecreaseKeyFunc(t, targetNode, newKey, cmp.Less[K])
+}
+
+// DecreaseKeyFunc is like DecreaseKey, but orders keys using the provided less function.
+func DecreaseKeyFunc[K, V any](t *Tree[K, V], targetNode *Tree[K, V], newKey K, less func(a, b K) bool) *Tree[K, V] {
+	if t == nil || targetNode == nil {
+		return t
+	}
+
+	if !less(newKey, targetNode.key) {
+		return t
+	}
+	targetNode.key = newKey
+
+	if targetNode.parent == nil {
+		return t
+	}
+	detach(targetNode, nil)
+
+	return MeldFunc(t, targetNode, less)
+}
+
+// IncreaseKey increases the target node's key to the provided new key. The new key must be greater than the target
+// node's current key. The target node is deleted and then reinserted with its new key.
+func IncreaseKey[K cmp.Ordered, V any](t *Tree[K, V], targetNode *Tree[K, V], newKey K) *Tree[K, V] {
+	return IncreaseKeyFunc(t, targetNode, newKey, cmp.Less[K])
+}
+
+// IncreaseKeyFunc is like IncreaseKey, but orders keys using the provided less function.
+func IncreaseKeyFunc[K, V any](t *Tree[K, V], targetNode *Tree[K, V], newKey K, less func(a, b K) bool) *Tree[K, V] {
+	if t == nil || targetNode == nil {
+		return t
+	}
+
+	if !less(targetNode.key, newKey) {
+		return t
+	}
+
+	t = DeleteFunc(t, targetNode, less)
+	targetNode.key = newKey
+
+	return MeldFunc(t, targetNode, less)
+}
+
+// Delete removes an arbitrary node from the Tree and returns the new root node. The removed node is left as a
+// standalone Tree.
+func Delete[K cmp.Ordered, V any](t *Tree[K, V], targetNode *Tree[K, V]) *Tree[K, V] {
+	return DeleteFunc(t, targetNode, cmp.Less[K])
+}
+
+// DeleteFunc is like Delete, but orders keys using the provided less function.
+func DeleteFunc[K, V any](t *Tree[K, V], targetNode *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	if t == nil || targetNode == nil {
+		return t
+	}
+
+	if targetNode == t {
+		return RemoveMinFunc(t, less)
+	}
+
+	if targetNode.parent == nil {
+		return t
+	}
+
+	detach(targetNode, RemoveMinFunc(targetNode, less))
+	return t
+}
+
+// precedes reports whether node a has a higher priority than node b. Ties between equal keys are broken by sequence
+// number.
+func precedes[K, V any](a, b *Tree[K, V], less func(a, b K) bool) bool {
+	if less(a.key, b.key) {
+		return true
+	}
+	return a.seq < b.seq && !less(b.key, a.key)
+}
+
+// cloneNode is a helper function that copies a node without any of its links.
+func cloneNode[K, V any](t *Tree[K, V], copyValue func(V) V) *Tree[K, V] {
+	value := t.value
+	if copyValue != nil {
+		value = copyValue(value)
+	}
+
+	return NewSequencedTree(t.key, value, t.seq)
+}
+
+// detach is a helper function that detaches a node from its parent, putting replacement (which may be nil) in its
+// place.
+func detach[K, V any](t *Tree[K, V], replacement *Tree[K, V]) {
+	parent := t.parent
+	if parent.left == t {
+		parent.left = replacement
+	} else {
+		parent.right = replacement
+	}
+
+	if replacement != nil {
+		replacement.parent = parent
+	}
+	t.parent = nil
 }
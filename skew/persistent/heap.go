@@ -0,0 +1,181 @@
+// Package persistent provides an immutable skew heap. Every operation that modifies a Heap returns a new version
+// instead, which copies the right spine that the operation walks down and shares every other node with the old version.
+// Old versions stay valid and unchanged, so taking a snapshot costs O(1), and any number of goroutines may read a
+// version without locking while another goroutine derives new versions from it.
+//
+// The O(log n) amortized bounds of a skew heap only hold when versions are used linearly (i.e., when every version is
+// derived from the previous one). Repeatedly deriving new versions from the same expensive version can cost O(n) per
+// operation; the skewbinomial/persistent package offers worst-case bounds instead.
+package persistent
+
+import (
+	"cmp"
+	"iter"
+)
+
+type Heap[K, V any] struct {
+	root *Tree[K, V]
+	size int
+	less func(a, b K) bool
+}
+
+func New[K cmp.Ordered, V any]() *Heap[K, V] {
+	return NewFunc[K, V](cmp.Less[K])
+}
+
+// NewFunc is like New, but orders keys using the provided less function.
+func NewFunc[K, V any](less func(a, b K) bool) *Heap[K, V] {
+	return &Heap[K, V]{
+		less: less,
+	}
+}
+
+func (h *Heap[K, V]) Size() int {
+	return h.size
+}
+
+// Insert returns a new version of the Heap that also holds the provided key-value pair.
+func (h *Heap[K, V]) Insert(key K, value V) *Heap[K, V] {
+	return h.InsertSequenced(key, value, 0)
+}
+
+// InsertSequenced is like Insert, but also assigns the new node a sequence number. Nodes with equal keys are ordered by
+// ascending sequence number.
+func (h *Heap[K, V]) InsertSequenced(key K, value V, seq uint64) *Heap[K, V] {
+	newTree := &Tree[K, V]{
+		key:   key,
+		value: value,
+		seq:   seq,
+	}
+
+	return h.with(meld(h.root, newTree, h.less), h.size+1)
+}
+
+// Meld returns a new version of the Heap that holds the elements of both h and other. The new version orders keys the
+// same way as h.
+func (h *Heap[K, V]) Meld(other *Heap[K, V]) *Heap[K, V] {
+	return h.with(meld(h.root, other.root, h.less), h.size+other.size)
+}
+
+// FindMin returns the node with the smallest key, or nil if the Heap is empty, in Θ(1).
+func (h *Heap[K, V]) FindMin() *Tree[K, V] {
+	return h.root
+}
+
+// RemoveMin returns a new version of the Heap without the node with the smallest key. If the Heap is empty, h itself is
+// returned.
+func (h *Heap[K, V]) RemoveMin() *Heap[K, V] {
+	if h.root == nil {
+		return h
+	}
+
+	return h.with(meld(h.root.left, h.root.right, h.less), h.size-1)
+}
+
+// Nodes returns an iterator over every node in the Heap, in no particular order.
+func (h *Heap[K, V]) Nodes() iter.Seq[*Tree[K, V]] {
+	return func(yield func(*Tree[K, V]) bool) {
+		if h.root == nil {
+			return
+		}
+
+		stack := []*Tree[K, V]{h.root}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if !yield(n) {
+				return
+			}
+
+			if n.left != nil {
+				stack = append(stack, n.left)
+			}
+			if n.right != nil {
+				stack = append(stack, n.right)
+			}
+		}
+	}
+}
+
+// All returns an iterator over every key-value pair in the Heap in priority order, which works by removing the minimum
+// from successive versions of the Heap. h itself is left untouched.
+func (h *Heap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for v := h; v.root != nil; v = v.RemoveMin() {
+			if !yield(v.root.key, v.root.value) {
+				return
+			}
+		}
+	}
+}
+
+func (h *Heap[K, V]) with(root *Tree[K, V], size int) *Heap[K, V] {
+	return &Heap[K, V]{
+		root: root,
+		size: size,
+		less: h.less,
+	}
+}
+
+// Tree is an immutable node of a skew heap. Trees are never modified once they are linked into a Heap, which is what
+// lets different versions of a Heap share them.
+type Tree[K, V any] struct {
+	key   K
+	value V
+	seq   uint64
+	left  *Tree[K, V]
+	right *Tree[K, V]
+}
+
+func (t *Tree[K, V]) Key() K {
+	return t.key
+}
+
+func (t *Tree[K, V]) Value() V {
+	return t.value
+}
+
+func (t *Tree[K, V]) Seq() uint64 {
+	return t.seq
+}
+
+// meld melds two trees by copying every node along the merged right spines, swapping the children of each copy as it
+// goes. It works top-down, so the depth of the trees does not affect the depth of the stack.
+func meld[K, V any](a, b *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	var root *Tree[K, V]
+	hole := &root
+
+	for a != nil && b != nil {
+		if precedes(b, a, less) {
+			a, b = b, a
+		}
+
+		// The copy's left child is the meld of a's right child with b, which is filled in by the next iteration.
+		c := &Tree[K, V]{
+			key:   a.key,
+			value: a.value,
+			seq:   a.seq,
+			right: a.left,
+		}
+		*hole = c
+		hole = &c.left
+		a = a.right
+	}
+
+	if a != nil {
+		*hole = a
+	} else {
+		*hole = b
+	}
+	return root
+}
+
+// precedes reports whether node a has a higher priority than node b. Ties between equal keys are broken by sequence
+// number.
+func precedes[K, V any](a, b *Tree[K, V], less func(a, b K) bool) bool {
+	if less(a.key, b.key) {
+		return true
+	}
+	return a.seq < b.seq && !less(b.key, a.key)
+}
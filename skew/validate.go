@@ -0,0 +1,53 @@
+package skew
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalid is returned by Validate when a Tree's invariants do not hold.
+var ErrInvalid = errors.New("skew: invalid tree")
+
+// Validate checks the invariants of the Tree rooted at t in Θ(n): that t is a root, that every node is linked to its
+// parent, that no node has a higher priority than its parent, and that no node is reachable twice. It returns an error
+// wrapping ErrInvalid that describes the first violation found, or nil if the Tree is valid. A nil Tree is valid.
+func Validate[K cmp.Ordered, V any](t *Tree[K, V]) error {
+	return ValidateFunc(t, cmp.Less[K])
+}
+
+// ValidateFunc is like Validate, but orders keys using the provided less function.
+func ValidateFunc[K, V any](t *Tree[K, V], less func(a, b K) bool) error {
+	if t == nil {
+		return nil
+	}
+	if t.parent != nil {
+		return fmt.Errorf("%w: root with key %v has a parent", ErrInvalid, t.key)
+	}
+
+	seen := map[*Tree[K, V]]bool{t: true}
+	stack := []*Tree[K, V]{t}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, c := range []*Tree[K, V]{n.left, n.right} {
+			if c == nil {
+				continue
+			}
+			if seen[c] {
+				return fmt.Errorf("%w: node with key %v is reachable twice", ErrInvalid, c.key)
+			}
+			seen[c] = true
+
+			if c.parent != n {
+				return fmt.Errorf("%w: node with key %v is not linked to its parent with key %v", ErrInvalid, c.key, n.key)
+			}
+			if precedes(c, n, less) {
+				return fmt.Errorf("%w: node with key %v precedes its parent with key %v", ErrInvalid, c.key, n.key)
+			}
+			stack = append(stack, c)
+		}
+	}
+	return nil
+}
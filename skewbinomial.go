@@ -2,101 +2,649 @@ package pqueue
 
 import (
 	"cmp"
+	"context"
+	"io"
+	"iter"
 	"sync"
-	"sync/atomic"
 
 	"github.com/AndrewChon/pqueue/skewbinomial"
 )
 
-var skewBinomialIDCounter atomic.Uint64
-
 // SkewBinomial is a concurrency-safe, min-priority queue built on a skew binomial heap.
-type SkewBinomial[K cmp.Ordered, V any] struct {
+type SkewBinomial[K, V any] struct {
 	l  sync.RWMutex
 	id uint64
 
-	heap *skewbinomial.Forest[K, V]
-	size int
+	notEmpty waiters
+	bound    bound
+	closer   closer
+
+	heap     *skewbinomial.Forest[K, V]
+	size     int
+	less     func(a, b K) bool
+	tieBreak TieBreak
+	codec    codecs[K, V]
+	meter    meter
+	observer observer[K, V]
+}
+
+func NewSkewBinomial[K cmp.Ordered, V any](opts ...Option) *SkewBinomial[K, V] {
+	return NewSkewBinomialFunc[K, V](cmp.Less[K], opts...)
 }
 
-func NewSkewBinomial[K cmp.Ordered, V any]() *SkewBinomial[K, V] {
+// NewSkewBinomialFunc is like NewSkewBinomial, but orders keys using the provided less function rather than requiring K
+// to be ordered. For example, a max-priority queue can be built by providing a less function that reports whether a >
+// b. Queues that are melded together are expected to order keys the same way.
+func NewSkewBinomialFunc[K, V any](less func(a, b K) bool, opts ...Option) *SkewBinomial[K, V] {
+	o := newOptions(opts, heapOptions)
+
 	return &SkewBinomial[K, V]{
-		id:   skewBinomialIDCounter.Add(1),
-		heap: skewbinomial.NewForest[K, V](),
-		size: 0,
+		id:       idCounter.Add(1),
+		bound:    newBound(o, heapPolicies...),
+		closer:   newCloser(),
+		heap:     skewbinomial.NewForestFunc[K, V](less),
+		size:     0,
+		less:     less,
+		tieBreak: o.tieBreak,
+		codec:    newCodecs[K, V](o),
+		meter:    newMeter(o),
+		observer: newObserver[K, V](o),
 	}
 }
 
+// NewSkewBinomialFrom builds a SkewBinomial queue from the provided items in linear time, as each insertion costs Θ(1)
+// in the worst case. If there are more items than the queue's capacity, the items with the lowest priorities are
+// discarded.
+func NewSkewBinomialFrom[K cmp.Ordered, V any](items []Item[K, V], opts ...Option) *SkewBinomial[K, V] {
+	return NewSkewBinomialFromFunc(items, cmp.Less[K], opts...)
+}
+
+// NewSkewBinomialFromFunc is like NewSkewBinomialFrom, but orders keys using the provided less function.
+func NewSkewBinomialFromFunc[K, V any](items []Item[K, V], less func(a, b K) bool, opts ...Option) *SkewBinomial[K, V] {
+	sb := NewSkewBinomialFunc[K, V](less, opts...)
+	sb.insertLocked(entriesOf(items))
+	sb.trimLocked()
+
+	return sb
+}
+
+// CollectSkewBinomial builds a SkewBinomial queue from the key-value pairs of seq, where each key is a priority.
+func CollectSkewBinomial[K cmp.Ordered, V any](seq iter.Seq2[K, V], opts ...Option) *SkewBinomial[K, V] {
+	return NewSkewBinomialFrom(collect(seq), opts...)
+}
+
+// CollectSkewBinomialFunc is like CollectSkewBinomial, but orders keys using the provided less function.
+func CollectSkewBinomialFunc[K, V any](seq iter.Seq2[K, V], less func(a, b K) bool, opts ...Option) *SkewBinomial[K, V] {
+	return NewSkewBinomialFromFunc(collect(seq), less, opts...)
+}
+
 func (sb *SkewBinomial[K, V]) Size() int {
-	sb.l.RLock()
+	sb.meter.rlock(&sb.l)
 	defer sb.l.RUnlock()
 
 	return sb.size
 }
 
 func (sb *SkewBinomial[K, V]) Clear() {
-	sb.l.Lock()
+	sb.meter.lock(&sb.l)
 	defer sb.l.Unlock()
 
-	sb.heap = skewbinomial.NewForest[K, V]()
-	sb.size = 0
+	sb.observer.clear(sb.sizeLocked())
+	sb.clearLocked()
+	sb.meter.cleared()
 }
 
 func (sb *SkewBinomial[K, V]) Peek() V {
-	sb.l.RLock()
+	_, v, _ := sb.PeekItem()
+	return v
+}
+
+// PeekItem returns the priority and value of the element with the highest priority without removing it. ok is false if
+// the queue is empty.
+func (sb *SkewBinomial[K, V]) PeekItem() (priority K, v V, ok bool) {
+	sb.meter.rlock(&sb.l)
 	defer sb.l.RUnlock()
 
-	minTree, _ := sb.heap.FindMin()
-	if minTree == nil {
-		var zero V
-		return zero
+	return sb.peekLocked()
+}
+
+func (sb *SkewBinomial[K, V]) Pop() (v V, ok bool) {
+	_, v, ok = sb.PopItem()
+	return v, ok
+}
+
+// PopItem removes and returns the priority and value of the element with the highest priority. ok is false if the
+// queue is empty.
+func (sb *SkewBinomial[K, V]) PopItem() (priority K, v V, ok bool) {
+	sb.meter.lock(&sb.l)
+	defer sb.l.Unlock()
+
+	return sb.popLocked()
+}
+
+// Push inserts a value with the provided priority. If the queue is full, Push follows the queue's OverflowPolicy; see
+// WithCapacity.
+func (sb *SkewBinomial[K, V]) Push(v V, priority K) error {
+	return sb.PushWait(context.Background(), v, priority)
+}
+
+// PushWait is like Push, but returns ctx's error if ctx is done while blocked on a full queue.
+func (sb *SkewBinomial[K, V]) PushWait(ctx context.Context, v V, priority K) error {
+	sb.meter.lock(&sb.l)
+	defer sb.l.Unlock()
+
+	_, err := sb.admitLocked(ctx, v, priority)
+	return err
+}
+
+// PopWait removes and returns the value with the highest priority, blocking until the queue is non-empty. If ctx is
+// done first, PopWait returns ctx's error.
+func (sb *SkewBinomial[K, V]) PopWait(ctx context.Context) (V, error) {
+	_, v, err := sb.PopItemWait(ctx)
+	return v, err
+}
+
+// PopItemWait is like PopWait, but also returns the priority of the element.
+func (sb *SkewBinomial[K, V]) PopItemWait(ctx context.Context) (priority K, v V, err error) {
+	e, err := sb.popEntryWait(ctx)
+	return e.key, e.value, err
+}
+
+// Close closes the queue. Subsequent pushes and melds into the queue fail with ErrClosed, and every goroutine blocked
+// in PopWait or PushWait is woken. Elements that remain in the queue can still be popped, and PopWait returns ErrClosed
+// once the queue is empty. Calling Close more than once has no effect.
+func (sb *SkewBinomial[K, V]) Close() {
+	sb.meter.lock(&sb.l)
+	defer sb.l.Unlock()
+
+	sb.closer.close(sb.size)
+	sb.notEmpty.broadcast()
+	sb.bound.notFull.broadcast()
+}
+
+// IsClosed reports whether Close has been called.
+func (sb *SkewBinomial[K, V]) IsClosed() bool {
+	sb.meter.rlock(&sb.l)
+	defer sb.l.RUnlock()
+
+	return sb.closer.closed
+}
+
+// Done returns a channel that is closed once the queue has been closed and every remaining element has been removed.
+func (sb *SkewBinomial[K, V]) Done() <-chan struct{} {
+	return sb.closer.done
+}
+
+// TieBreak returns the TieBreak the queue was created with (see WithTieBreak).
+func (sb *SkewBinomial[K, V]) TieBreak() TieBreak {
+	return sb.tieBreak
+}
+
+// All returns an iterator over every element in priority order, without modifying the queue.
+func (sb *SkewBinomial[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		sb.meter.rlock(&sb.l)
+		entries := sb.entriesLocked()
+		sb.l.RUnlock()
+
+		orderedSeq(entries, sb.less)(yield)
 	}
+}
+
+// Unordered returns an iterator over every element in no particular order, without modifying the queue. It is cheaper
+// than All, as the elements do not need to be ordered.
+func (sb *SkewBinomial[K, V]) Unordered() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		sb.meter.rlock(&sb.l)
+		entries := sb.entriesLocked()
+		sb.l.RUnlock()
 
-	return minTree.Value()
+		unorderedSeq(entries)(yield)
+	}
 }
 
-func (sb *SkewBinomial[K, V]) Pop() (v V, ok bool) {
-	sb.l.Lock()
+// Drain returns an iterator that pops every element in priority order until the queue is empty.
+func (sb *SkewBinomial[K, V]) Drain() iter.Seq2[K, V] {
+	return drainSeq(sb.PopItem)
+}
+
+// PushMany inserts every provided item while only taking the lock once. If the items do not all fit, PushMany fails
+// with ErrFull without inserting any of them under OverflowError; under every other policy, each item is pushed in turn
+// as if by Push. In that case the batch is not atomic: under OverflowBlock, the lock is released while waiting for
+// room, so other operations may run between items, and items pushed before an error stay in the queue.
+func (sb *SkewBinomial[K, V]) PushMany(items []Item[K, V]) error {
+	return sb.PushManyWait(context.Background(), items)
+}
+
+// PushManyWait is like PushMany, but returns ctx's error if ctx is done while blocked on a full queue.
+func (sb *SkewBinomial[K, V]) PushManyWait(ctx context.Context, items []Item[K, V]) error {
+	sb.meter.lock(&sb.l)
 	defer sb.l.Unlock()
 
-	minTree, i := sb.heap.FindMin()
-	if minTree == nil {
-		return
+	if sb.closer.closed {
+		return ErrClosed
+	}
+	if sb.bound.room(sb.size, len(items)) {
+		sb.insertLocked(entriesOf(items))
+		for _, item := range items {
+			sb.observer.push(item.Priority, item.Value)
+		}
+		sb.meter.pushed(len(items))
+		return nil
+	}
+	if sb.bound.policy == OverflowError {
+		return ErrFull
 	}
 
-	v = minTree.Value()
+	for _, item := range items {
+		if _, err := sb.admitLocked(ctx, item.Value, item.Priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	sb.heap.Remove(minTree, i)
+// PopN removes and returns up to n items in priority order while only taking the lock once.
+func (sb *SkewBinomial[K, V]) PopN(n int) []Item[K, V] {
+	sb.meter.lock(&sb.l)
+	defer sb.l.Unlock()
 
-	sb.size--
-	return v, true
+	return popN(sb.popLocked, sb.size, n)
+}
+
+// PushHandle inserts a value with the provided priority and returns a Handle to it. If the queue is full, PushHandle
+// behaves like Push, and the returned Handle is nil if the value was not inserted.
+func (sb *SkewBinomial[K, V]) PushHandle(v V, priority K) (*Handle[K, V], error) {
+	return sb.PushHandleWait(context.Background(), v, priority)
 }
 
-func (sb *SkewBinomial[K, V]) Push(v V, priority K) {
-	sb.l.Lock()
+// PushHandleWait is like PushHandle, but returns ctx's error if ctx is done while blocked on a full queue.
+func (sb *SkewBinomial[K, V]) PushHandleWait(ctx context.Context, v V, priority K) (*Handle[K, V], error) {
+	sb.meter.lock(&sb.l)
 	defer sb.l.Unlock()
 
-	sb.heap.Insert(priority, v)
-	sb.size++
+	t, err := sb.admitLocked(ctx, v, priority)
+	if t == nil {
+		return nil, err
+	}
+	return &Handle[K, V]{node: t}, nil
 }
 
-func (sb *SkewBinomial[K, V]) Meld(other *SkewBinomial[K, V]) {
-	if sb.id < other.id {
-		sb.l.Lock()
-		other.l.Lock()
-	} else if sb.id > other.id {
-		other.l.Lock()
-		sb.l.Lock()
-	} else {
-		panic(ConcurrencySafetyError)
+// Update changes the priority of the element referred to by h. It returns false if h does not refer to an element in
+// this queue. Decreasing a priority costs O(log² n), as the element is sifted up by swapping places with its ancestors;
+// increasing a priority costs the same as a Remove followed by a Push.
+func (sb *SkewBinomial[K, V]) Update(h *Handle[K, V], priority K) bool {
+	sb.meter.lock(&sb.l)
+	defer sb.l.Unlock()
+
+	t := sb.nodeOf(h)
+	if t == nil {
+		return false
 	}
 
+	if sb.less(priority, t.Key()) {
+		sb.heap.DecreaseKey(t, priority)
+	} else if sb.less(t.Key(), priority) {
+		sb.heap.IncreaseKey(t, priority)
+	}
+	return true
+}
+
+// Remove removes the element referred to by h in O(log² n). It returns false if h does not refer to an element in this
+// queue.
+func (sb *SkewBinomial[K, V]) Remove(h *Handle[K, V]) bool {
+	sb.meter.lock(&sb.l)
+	defer sb.l.Unlock()
+
+	t := sb.nodeOf(h)
+	if t == nil {
+		return false
+	}
+
+	sb.heap.Delete(t)
+	sb.size--
+	sb.meter.popped(t)
+	sb.observer.pop(t.Key(), t.Value())
+	sb.removedLocked()
+	return true
+}
+
+// nodeOf returns the node referred to by h, or nil if h does not refer to an element in this queue.
+func (sb *SkewBinomial[K, V]) nodeOf(h *Handle[K, V]) *skewbinomial.Tree[K, V] {
+	if h == nil {
+		return nil
+	}
+
+	t, ok := h.node.(*skewbinomial.Tree[K, V])
+	if !ok || !sb.heap.Contains(t) {
+		return nil
+	}
+	return t
+}
+
+// Meld merges other into sb and clears other.
+func (sb *SkewBinomial[K, V]) Meld(other *SkewBinomial[K, V]) error {
+	sb.meter.lockPair(&sb.l, sb.id, &other.l, other.id)
 	defer sb.l.Unlock()
 	defer other.l.Unlock()
 
+	if err := sb.acceptsLocked(other.size); err != nil {
+		return err
+	}
+
+	adopt(&sb.meter, &other.meter, other.heap.Nodes())
+	sb.meter.melded(other.sizeLocked())
+	sb.observer.meld(other.sizeLocked())
 	sb.heap.Merge(other.heap)
 	sb.size += other.size
+	other.clearLocked()
+	sb.trimLocked()
+	sb.meter.depth(sb.size)
+	sb.notEmpty.broadcast()
+	return nil
+}
+
+// CrossMeld merges any other queue from this package into this one and clears it. See CrossMeldable.
+func (sb *SkewBinomial[K, V]) CrossMeld(other CrossMeldable) error {
+	if o, ok := other.(*SkewBinomial[K, V]); ok {
+		return sb.Meld(o)
+	}
+
+	return crossMeld[K, V](sb, other)
+}
+
+// Clone returns an independent copy of the queue. Handles to the queue's elements do not refer to the copy's elements.
+func (sb *SkewBinomial[K, V]) Clone() *SkewBinomial[K, V] {
+	return sb.CloneFunc(nil)
+}
+
+// CloneFunc is like Clone, but copies every value with copyValue.
+func (sb *SkewBinomial[K, V]) CloneFunc(copyValue func(V) V) *SkewBinomial[K, V] {
+	sb.meter.rlock(&sb.l)
+	defer sb.l.RUnlock()
+
+	return &SkewBinomial[K, V]{
+		id:       idCounter.Add(1),
+		bound:    sb.bound.clone(),
+		closer:   newCloser(),
+		heap:     sb.heap.Clone(copyValue),
+		size:     sb.size,
+		less:     sb.less,
+		tieBreak: sb.tieBreak,
+		codec:    sb.codec,
+		observer: sb.observer,
+	}
+}
+
+// MarshalBinary encodes every element of the queue in a versioned binary format that is shared by every heap-based
+// queue, using the queue's codecs (see WithKeyCodec).
+func (sb *SkewBinomial[K, V]) MarshalBinary() ([]byte, error) {
+	return marshalBinary[K, V](sb)
+}
+
+// UnmarshalBinary replaces the contents of the queue with the elements encoded in data by the MarshalBinary method of
+// any heap-based queue. The queue must have been created by its constructor, and keeps its own comparator and options.
+func (sb *SkewBinomial[K, V]) UnmarshalBinary(data []byte) error {
+	return unmarshalBinary[K, V](sb, data)
+}
+
+// MarshalJSON is like MarshalBinary, but encodes the elements as JSON.
+func (sb *SkewBinomial[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON[K, V](sb)
+}
+
+// UnmarshalJSON is like UnmarshalBinary, but decodes elements encoded by MarshalJSON.
+func (sb *SkewBinomial[K, V]) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON[K, V](sb, data)
+}
+
+// GobEncode encodes the queue in the same format as MarshalBinary.
+func (sb *SkewBinomial[K, V]) GobEncode() ([]byte, error) {
+	return sb.MarshalBinary()
+}
+
+// GobDecode decodes the queue in the same way as UnmarshalBinary.
+func (sb *SkewBinomial[K, V]) GobDecode(data []byte) error {
+	return sb.UnmarshalBinary(data)
+}
+
+// Inspect returns the Stats of the queue, with up to top of its elements with the highest priorities. It costs Θ(n),
+// plus O(log n) for every element returned.
+func (sb *SkewBinomial[K, V]) Inspect(top int) Stats {
+	sb.meter.rlock(&sb.l)
+	entries := sb.entriesLocked()
+	stats := Stats{
+		Kind:  "SkewBinomial",
+		ID:    sb.id,
+		Size:  sb.size,
+		Shape: map[string]any{"treeRanks": sb.heap.Ranks()},
+	}
+	sb.l.RUnlock()
+
+	stats.Top = topOf(entries, sb.less, top)
+	return stats
+}
+
+// WriteDOT writes the heap that backs the queue to w as a Graphviz graph, as described by skewbinomial.Forest.WriteDOT,
+// to help diagnose the shape of the heap. The queue is read-locked until the whole graph has been written.
+func (sb *SkewBinomial[K, V]) WriteDOT(w io.Writer) error {
+	sb.meter.rlock(&sb.l)
+	defer sb.l.RUnlock()
+
+	return sb.heap.WriteDOT(w)
+}
+
+// Validate checks the invariants of the heap that backs the queue (see skewbinomial.Forest.Validate), that the queue's
+// size matches the number of elements in its heap, and that its size fits within its capacity.
+func (sb *SkewBinomial[K, V]) Validate() error {
+	sb.meter.rlock(&sb.l)
+	defer sb.l.RUnlock()
+
+	if err := sb.heap.Validate(); err != nil {
+		return invalid(err)
+	}
+	return validateSize(countOf(sb.heap.Nodes()), sb.size, &sb.bound)
+}
+
+func (sb *SkewBinomial[K, V]) queueID() uint64 {
+	return sb.id
+}
+
+func (sb *SkewBinomial[K, V]) mutex() *sync.RWMutex {
+	return &sb.l
+}
+
+func (sb *SkewBinomial[K, V]) sizeLocked() int {
+	return sb.size
+}
+
+func (sb *SkewBinomial[K, V]) acceptsLocked(incoming int) error {
+	if sb.closer.closed {
+		return ErrClosed
+	}
+	return sb.bound.fits(sb.size, incoming)
+}
+
+// trimLocked evicts the elements with the lowest priorities until the queue fits within its capacity.
+func (sb *SkewBinomial[K, V]) trimLocked() {
+	excess := sb.bound.excess(sb.size)
+	if excess == 0 {
+		return
+	}
+
+	for _, t := range lowestN(sb.heap.Nodes(), sb.less, excess) {
+		sb.heap.Delete(t)
+		sb.size--
+		sb.meter.forget(t)
+		sb.observer.drop(t.Key(), t.Value())
+	}
+	sb.meter.depth(sb.size)
+}
+
+// removedLocked is called whenever elements are removed from the queue.
+func (sb *SkewBinomial[K, V]) removedLocked() {
+	sb.bound.notFull.broadcast()
+	sb.closer.settle(sb.size)
+	sb.meter.depth(sb.size)
+}
+
+func (sb *SkewBinomial[K, V]) meterLocked() *meter {
+	return &sb.meter
+}
+
+func (sb *SkewBinomial[K, V]) observerLocked() *observer[K, V] {
+	return &sb.observer
+}
+
+func (sb *SkewBinomial[K, V]) codecs() codecs[K, V] {
+	return sb.codec
+}
+
+func (sb *SkewBinomial[K, V]) initialized() bool {
+	return sb.less != nil
+}
+
+func (sb *SkewBinomial[K, V]) drainLocked() []entry[K, V] {
+	entries := sb.entriesLocked()
+	sb.clearLocked()
+
+	return entries
+}
+
+// entriesLocked returns a snapshot of every element in the queue, in no particular order.
+func (sb *SkewBinomial[K, V]) entriesLocked() []entry[K, V] {
+	entries := make([]entry[K, V], 0, sb.size)
+	for t := range sb.heap.Nodes() {
+		entries = append(entries, entry[K, V]{t.Key(), t.Value(), t.Seq()})
+	}
+
+	return entries
+}
+
+func (sb *SkewBinomial[K, V]) clearLocked() {
+	sb.heap = skewbinomial.NewForestFunc[K, V](sb.less)
+	sb.size = 0
+	sb.meter.forgetAll()
+	sb.removedLocked()
+}
+
+func (sb *SkewBinomial[K, V]) insertLocked(entries []entry[K, V]) {
+	for _, e := range entries {
+		sb.meter.stamp(sb.heap.InsertSequenced(e.key, e.value, sb.seqOf(e)))
+	}
+
+	sb.size += len(entries)
+	sb.meter.depth(sb.size)
+	sb.notEmpty.broadcast()
+}
+
+// seqOf returns the sequence number to give an element that is being moved into this queue. The element keeps its
+// sequence number if it has one, so that insertion order survives a CrossMeld.
+func (sb *SkewBinomial[K, V]) seqOf(e entry[K, V]) uint64 {
+	if e.seq == 0 {
+		return sb.tieBreak.nextSeq()
+	}
+	return e.seq
+}
+
+// admitLocked pushes a value once the queue has room for it, as dictated by its overflow policy. It returns nil if the
+// value was not pushed.
+func (sb *SkewBinomial[K, V]) admitLocked(ctx context.Context, v V, priority K) (*skewbinomial.Tree[K, V], error) {
+	ok, err := sb.bound.admit(ctx, &sb.meter, &sb.l, &sb.closer, sb.sizeLocked, func() bool {
+		return sb.evictLocked(priority)
+	})
+	if !ok {
+		if err == nil {
+			sb.observer.drop(priority, v)
+		}
+		return nil, err
+	}
+
+	return sb.pushLocked(v, priority), nil
+}
+
+// evictLocked removes the element with the lowest priority to make room for an incoming element with the provided
+// priority. It returns false, leaving the queue untouched, if the incoming element would have the lowest priority.
+func (sb *SkewBinomial[K, V]) evictLocked(priority K) bool {
+	t, ok := lowest(sb.heap.Nodes(), sb.less)
+	if !ok || !outranks(priority, t, sb.less, sb.tieBreak) {
+		return false
+	}
+
+	sb.heap.Delete(t)
+	sb.size--
+	sb.meter.forget(t)
+	sb.observer.drop(t.Key(), t.Value())
+	return true
+}
+
+func (sb *SkewBinomial[K, V]) pushLocked(v V, priority K) *skewbinomial.Tree[K, V] {
+	newTree := sb.heap.InsertSequenced(priority, v, sb.tieBreak.nextSeq())
+	sb.size++
+	sb.meter.stamp(newTree)
+	sb.meter.pushed(1)
+	sb.observer.push(priority, v)
+	sb.meter.depth(sb.size)
+	sb.notEmpty.broadcast()
+
+	return newTree
+}
+
+func (sb *SkewBinomial[K, V]) peekLocked() (priority K, v V, ok bool) {
+	minTree, _ := sb.heap.FindMin()
+	if minTree == nil {
+		return
+	}
+
+	return minTree.Key(), minTree.Value(), true
+}
+
+func (sb *SkewBinomial[K, V]) popLocked() (priority K, v V, ok bool) {
+	e, ok := sb.popEntryLocked()
+	return e.key, e.value, ok
+}
+
+// popEntryLocked is like popLocked, but returns the element as an entry, along with its sequence number.
+func (sb *SkewBinomial[K, V]) popEntryLocked() (entry[K, V], bool) {
+	h, ok := sb.takeLocked()
+	if ok {
+		sb.meter.poppedAt(h.pushedAt)
+		sb.observer.pop(h.key, h.value)
+	}
+	return h.entry, ok
+}
+
+func (sb *SkewBinomial[K, V]) popEntryWait(ctx context.Context) (entry[K, V], error) {
+	return waitFor(ctx, &sb.meter, &sb.l, &sb.notEmpty, &sb.closer, sb.popEntryLocked)
+}
+
+// takeLocked removes the element with the highest priority without counting it as popped.
+func (sb *SkewBinomial[K, V]) takeLocked() (held[K, V], bool) {
+	minTree, i := sb.heap.FindMin()
+	if minTree == nil {
+		return held[K, V]{}, false
+	}
+
+	sb.heap.Remove(minTree, i)
+
+	sb.size--
+	sb.removedLocked()
+	return held[K, V]{entry[K, V]{minTree.Key(), minTree.Value(), minTree.Seq()}, sb.meter.taken(minTree)}, true
+}
+
+func (sb *SkewBinomial[K, V]) takeWait(ctx context.Context) (held[K, V], error) {
+	return waitFor(ctx, &sb.meter, &sb.l, &sb.notEmpty, &sb.closer, sb.takeLocked)
+}
+
+// requeueLocked puts an element that was removed with takeLocked back into the queue, without counting it as pushed.
+func (sb *SkewBinomial[K, V]) requeueLocked(h held[K, V]) {
+	sb.meter.stampAt(sb.heap.InsertSequenced(h.key, h.value, h.seq), h.pushedAt)
+	sb.size++
+	sb.meter.depth(sb.size)
+	sb.notEmpty.broadcast()
+}
 
-	other.heap = skewbinomial.NewForest[K, V]()
-	other.size = 0
+// pushedLocked returns a channel that is closed the next time elements are added to the queue.
+func (sb *SkewBinomial[K, V]) pushedLocked() <-chan struct{} {
+	return sb.notEmpty.wait()
 }
@@ -0,0 +1,48 @@
+package skewbinomial
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteDOT writes the Forest to w as a graph in the DOT language of Graphviz. Every node is labelled with its key and
+// its rank, and is linked to its children, in order, and to its parent with a dotted edge. The roots of the trees are
+// placed side by side, in the order in which the trees are stored, and are linked by dashed edges. Keys are formatted
+// with fmt.
+func (f *Forest[K, V]) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	ids := make(map[*Tree[K, V]]int)
+	for n := range f.Nodes() {
+		ids[n] = len(ids)
+	}
+
+	fmt.Fprintln(bw, "digraph skewbinomial {")
+	fmt.Fprintln(bw, "\tnode [shape=circle];")
+	for n := range f.Nodes() {
+		fmt.Fprintf(bw, "\tn%d [label=%q];\n", ids[n], fmt.Sprintf("%v\nr%d", n.key, n.rank))
+	}
+	for n := range f.Nodes() {
+		for _, c := range n.children {
+			fmt.Fprintf(bw, "\tn%d -> n%d;\n", ids[n], ids[c])
+		}
+		if n.parent != nil {
+			fmt.Fprintf(bw, "\tn%d -> n%d [style=dotted, constraint=false];\n", ids[n], ids[n.parent])
+		}
+	}
+
+	if len(f.trees) > 0 {
+		fmt.Fprint(bw, "\t{ rank=same;")
+		for _, t := range f.trees {
+			fmt.Fprintf(bw, " n%d;", ids[t])
+		}
+		fmt.Fprintln(bw, " }")
+	}
+	for i := 1; i < len(f.trees); i++ {
+		fmt.Fprintf(bw, "\tn%d -> n%d [style=dashed];\n", ids[f.trees[i-1]], ids[f.trees[i]])
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
@@ -2,34 +2,98 @@ package skewbinomial
 
 import (
 	"cmp"
+	"iter"
 )
 
-type Forest[K cmp.Ordered, V any] struct {
+type Forest[K, V any] struct {
 	trees []*Tree[K, V]
+	less  func(a, b K) bool
 }
 
 func NewForest[K cmp.Ordered, V any]() *Forest[K, V] {
-	return new(Forest[K, V])
+	return NewForestFunc[K, V](cmp.Less[K])
 }
 
-func (f *Forest[K, V]) Insert(newKey K, newValue V) {
-	var newTree *Tree[K, V]
+// NewForestFunc is like NewForest, but orders keys using the provided less function.
+func NewForestFunc[K, V any](less func(a, b K) bool) *Forest[K, V] {
+	return &Forest[K, V]{
+		less: less,
+	}
+}
+
+// Insert inserts a new key-value pair into the Forest and returns the node that holds it. The node keeps its identity
+// for as long as it remains in the Forest, so it may later be passed to DecreaseKey, IncreaseKey or Delete.
+func (f *Forest[K, V]) Insert(newKey K, newValue V) *Tree[K, V] {
+	return f.InsertSequenced(newKey, newValue, 0)
+}
 
+// InsertSequenced is like Insert, but also assigns the new node a sequence number. Nodes with equal keys are ordered by
+// ascending sequence number.
+func (f *Forest[K, V]) InsertSequenced(newKey K, newValue V, seq uint64) *Tree[K, V] {
+	newTree := &Tree[K, V]{
+		key:   newKey,
+		value: newValue,
+		seq:   seq,
+		rank:  0,
+	}
+	f.insertTree(newTree)
+
+	return newTree
+}
+
+// insertTree inserts a rank-0 tree into the Forest.
+func (f *Forest[K, V]) insertTree(newTree *Tree[K, V]) {
 	if len(f.trees) >= 2 && f.trees[0].rank == f.trees[1].rank {
-		newTree = skewLink(newKey, newValue, f.trees[0], f.trees[1])
+		newTree = skewLink(newTree, f.trees[0], f.trees[1], f.less)
 		f.trees = prepend(f.trees[2:], newTree)
 	} else {
-		newTree = &Tree[K, V]{
-			key:   newKey,
-			value: newValue,
-			rank:  0,
-		}
 		f.trees = prepend(f.trees, newTree)
 	}
 }
 
 func (f *Forest[K, V]) Merge(other *Forest[K, V]) {
-	f.trees = Merge(f.trees, other.trees)
+	f.trees = MergeFunc(f.trees, other.trees, f.less)
+}
+
+// Clone returns a structural copy of the Forest in Θ(n). If copyValue is not nil, it is used to copy every value.
+func (f *Forest[K, V]) Clone(copyValue func(V) V) *Forest[K, V] {
+	trees := make([]*Tree[K, V], len(f.trees))
+	for i, t := range f.trees {
+		trees[i] = t.clone(nil, copyValue)
+	}
+
+	return &Forest[K, V]{
+		trees: trees,
+		less:  f.less,
+	}
+}
+
+// Nodes returns an iterator over every node of every tree in the Forest, in no particular order.
+func (f *Forest[K, V]) Nodes() iter.Seq[*Tree[K, V]] {
+	return func(yield func(*Tree[K, V]) bool) {
+		stack := make([]*Tree[K, V], 0, len(f.trees))
+		stack = append(stack, f.trees...)
+
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if !yield(n) {
+				return
+			}
+
+			stack = append(stack, n.children...)
+		}
+	}
+}
+
+// Ranks returns the rank of every tree in the Forest, in the order in which the trees are stored.
+func (f *Forest[K, V]) Ranks() []int {
+	ranks := make([]int, len(f.trees))
+	for i, t := range f.trees {
+		ranks[i] = t.rank
+	}
+	return ranks
 }
 
 func (f *Forest[K, V]) FindMin() (*Tree[K, V], int) {
@@ -40,7 +104,7 @@ func (f *Forest[K, V]) FindMin() (*Tree[K, V], int) {
 	minTree := f.trees[0]
 	minI := 0
 	for i := 1; i < len(f.trees); i++ {
-		if f.trees[i].key < minTree.key {
+		if precedes(f.trees[i], minTree, f.less) {
 			minTree, minI = f.trees[i], i
 		}
 	}
@@ -57,26 +121,26 @@ func (f *Forest[K, V]) RemoveMin() {
 	f.Remove(minTree, minI)
 }
 
+// Remove removes the root of the i-th tree in the Forest, which must be tree.
 func (f *Forest[K, V]) Remove(tree *Tree[K, V], i int) {
 	f.trees = append(f.trees[:i], f.trees[i+1:]...)
 
 	children := tree.children
+	tree.children = nil
+	tree.rank = 0
 	if len(children) == 0 {
 		return
 	}
 
-	type kvp struct {
-		key   K
-		value V
-	}
-
-	zeroRankKVPs := make([]*kvp, 0)
+	zeroRanked := make([]*Tree[K, V], 0)
 	nonZeroRanked := children[:0]
 
 	// Separate zero-rank children from non-zero-rank children.
 	for _, child := range children {
+		child.parent = nil
+
 		if child.rank == 0 {
-			zeroRankKVPs = prepend(zeroRankKVPs, &kvp{child.key, child.value})
+			zeroRanked = prepend(zeroRanked, child)
 		} else {
 			nonZeroRanked = prepend(nonZeroRanked, child)
 		}
@@ -84,23 +148,111 @@ func (f *Forest[K, V]) Remove(tree *Tree[K, V], i int) {
 
 	// Merge non-zero-rank children into the forest.
 	if len(nonZeroRanked) > 0 {
-		f.trees = Merge(f.trees, nonZeroRanked)
+		f.trees = MergeFunc(f.trees, nonZeroRanked, f.less)
 	}
 
 	// Push zero-rank children back into the forest.
-	if len(zeroRankKVPs) == 0 {
+	for _, z := range zeroRanked {
+		f.insertTree(z)
+	}
+}
+
+// Contains reports whether the node is currently stored in the Forest. The cost is proportional to the depth of the
+// node plus the number of trees in the Forest, both of which are O(log n).
+func (f *Forest[K, V]) Contains(t *Tree[K, V]) bool {
+	return t != nil && f.indexOf(t.root()) >= 0
+}
+
+// DecreaseKey decreases the node's key to the provided new key and sifts it up towards the root of its tree. The new
+// key must be less than the node's current key, and the node must be stored in the Forest (see Contains).
+func (f *Forest[K, V]) DecreaseKey(t *Tree[K, V], newKey K) {
+	if !f.less(newKey, t.key) {
+		return
+	}
+	t.key = newKey
+
+	for t.parent != nil && precedes(t, t.parent, f.less) {
+		f.swapWithParent(t)
+	}
+}
+
+// IncreaseKey increases the node's key to the provided new key. The new key must be greater than the node's current
+// key, and the node must be stored in the Forest (see Contains). The node is deleted and then reinserted with its new
+// key.
+func (f *Forest[K, V]) IncreaseKey(t *Tree[K, V], newKey K) {
+	if !f.less(t.key, newKey) {
+		return
+	}
+
+	f.Delete(t)
+	t.key = newKey
+	f.insertTree(t)
+}
+
+// Delete removes an arbitrary node from the Forest. The node must be stored in the Forest (see Contains).
+func (f *Forest[K, V]) Delete(t *Tree[K, V]) {
+	// Treat the node as though its key had been decreased to negative infinity.
+	for t.parent != nil {
+		f.swapWithParent(t)
+	}
+
+	f.Remove(t, f.indexOf(t))
+}
+
+// indexOf returns the index of a root in the Forest, or -1 if it is not one of the Forest's roots.
+func (f *Forest[K, V]) indexOf(root *Tree[K, V]) int {
+	for i, t := range f.trees {
+		if t == root {
+			return i
+		}
+	}
+	return -1
+}
+
+// swapWithParent swaps a node with its parent. Rather than swapping the contents of the two nodes, the nodes themselves
+// trade places (along with their ranks and children), so that every node keeps its identity.
+func (f *Forest[K, V]) swapWithParent(t *Tree[K, V]) {
+	parent := t.parent
+	grandparent := parent.parent
+
+	parentChildren := parent.children
+	for i, c := range parentChildren {
+		if c == t {
+			parentChildren[i] = parent
+			break
+		}
+	}
+
+	parent.children, t.children = t.children, parentChildren
+	parent.rank, t.rank = t.rank, parent.rank
+
+	for _, c := range parent.children {
+		c.parent = parent
+	}
+	for _, c := range t.children {
+		c.parent = t
+	}
+
+	t.parent = grandparent
+	if grandparent == nil {
+		f.trees[f.indexOf(parent)] = t
 		return
 	}
 
-	for _, z := range zeroRankKVPs {
-		f.Insert(z.key, z.value)
+	for i, c := range grandparent.children {
+		if c == parent {
+			grandparent.children[i] = t
+			break
+		}
 	}
 }
 
-type Tree[K cmp.Ordered, V any] struct {
+type Tree[K, V any] struct {
 	key      K
 	value    V
+	seq      uint64
 	rank     int
+	parent   *Tree[K, V]
 	children []*Tree[K, V]
 }
 
@@ -112,17 +264,62 @@ func (t *Tree[K, V]) Value() V {
 	return t.value
 }
 
+func (t *Tree[K, V]) Seq() uint64 {
+	return t.seq
+}
+
+// Rank returns the rank of the tree rooted at t.
+func (t *Tree[K, V]) Rank() int {
+	return t.rank
+}
+
+// clone returns a copy of the tree rooted at t, attached to the provided parent. Trees have a depth of O(log n), so
+// recursion is safe.
+func (t *Tree[K, V]) clone(parent *Tree[K, V], copyValue func(V) V) *Tree[K, V] {
+	c := &Tree[K, V]{
+		key:    t.key,
+		value:  t.value,
+		seq:    t.seq,
+		rank:   t.rank,
+		parent: parent,
+	}
+	if copyValue != nil {
+		c.value = copyValue(c.value)
+	}
+
+	if len(t.children) > 0 {
+		c.children = make([]*Tree[K, V], len(t.children))
+		for i, child := range t.children {
+			c.children[i] = child.clone(c, copyValue)
+		}
+	}
+	return c
+}
+
+// root returns the root of the tree that t currently belongs to.
+func (t *Tree[K, V]) root() *Tree[K, V] {
+	for t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
+
 func Merge[K cmp.Ordered, V any](a, b []*Tree[K, V]) []*Tree[K, V] {
-	return mergeUnique[K, V](uniquify(a), uniquify(b))
+	return MergeFunc(a, b, cmp.Less[K])
+}
+
+// MergeFunc is like Merge, but orders keys using the provided less function.
+func MergeFunc[K, V any](a, b []*Tree[K, V], less func(a, b K) bool) []*Tree[K, V] {
+	return mergeUnique[K, V](uniquify(a, less), uniquify(b, less), less)
 }
 
 // simpleLink links together two trees of the same rank, with one becoming the leftmost child of the other. The
 // resulting tree will have a rank of one greater than the rank of the two trees.
-func simpleLink[K cmp.Ordered, V any](a, b *Tree[K, V]) *Tree[K, V] {
+func simpleLink[K, V any](a, b *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
 	var parent *Tree[K, V]
 	var child *Tree[K, V]
 
-	if a.key <= b.key {
+	if !precedes(b, a, less) {
 		parent, child = a, b
 	} else {
 		parent, child = b, a
@@ -130,51 +327,58 @@ func simpleLink[K cmp.Ordered, V any](a, b *Tree[K, V]) *Tree[K, V] {
 
 	parent.children = prepend(parent.children, child)
 	parent.rank++
+	child.parent = parent
 
 	return parent
 }
 
 // skewLink links together three trees, one tree, a, having a rank of 0, and two trees, b and c, having the same rank as
 // each other.
-func skewLink[K cmp.Ordered, V any](aKey K, aValue V, b, c *Tree[K, V]) *Tree[K, V] {
-	a := &Tree[K, V]{
-		key:   aKey,
-		value: aValue,
-		rank:  0,
-	}
-
+func skewLink[K, V any](a, b, c *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
 	// Type A
-	if aKey <= b.key && aKey <= c.key {
+	if !precedes(b, a, less) && !precedes(c, a, less) {
 		a.rank = b.rank + 1
 		a.children = append(a.children, b, c)
+		b.parent, c.parent = a, a
 		return a
 	}
 
 	// Type B
-	if b.key <= c.key {
+	if !precedes(c, b, less) {
 		b.rank++
 		b.children = prepend(b.children, a, c)
+		a.parent, c.parent = b, b
 		return b
 	} else {
 		c.rank++
 		c.children = prepend(c.children, a, b)
+		a.parent, b.parent = c, c
 		return c
 	}
 }
 
-func uniquify[K cmp.Ordered, V any](trees []*Tree[K, V]) []*Tree[K, V] {
+// precedes reports whether node a has a higher priority than node b. Ties between equal keys are broken by sequence
+// number.
+func precedes[K, V any](a, b *Tree[K, V], less func(a, b K) bool) bool {
+	if less(a.key, b.key) {
+		return true
+	}
+	return a.seq < b.seq && !less(b.key, a.key)
+}
+
+func uniquify[K, V any](trees []*Tree[K, V], less func(a, b K) bool) []*Tree[K, V] {
 	if len(trees) < 2 {
 		return trees
 	}
 
 	if trees[0].rank == trees[1].rank {
-		return uniquify[K, V](prepend(trees[2:], simpleLink(trees[0], trees[1])))
+		return uniquify[K, V](prepend(trees[2:], simpleLink(trees[0], trees[1], less)), less)
 	} else {
-		return append(trees[0:1], uniquify[K, V](trees[1:])...)
+		return append(trees[0:1], uniquify[K, V](trees[1:], less)...)
 	}
 }
 
-func mergeUnique[K cmp.Ordered, V any](a, b []*Tree[K, V]) []*Tree[K, V] {
+func mergeUnique[K, V any](a, b []*Tree[K, V], less func(a, b K) bool) []*Tree[K, V] {
 	if len(a) == 0 {
 		return b
 	} else if len(b) == 0 {
@@ -182,11 +386,11 @@ func mergeUnique[K cmp.Ordered, V any](a, b []*Tree[K, V]) []*Tree[K, V] {
 	}
 
 	if a[0].rank < b[0].rank {
-		return append(a[0:1], mergeUnique[K, V](a[1:], b)...)
+		return append(a[0:1], mergeUnique[K, V](a[1:], b, less)...)
 	} else if a[0].rank > b[0].rank {
-		return append(b[0:1], mergeUnique[K, V](a, b[1:])...)
+		return append(b[0:1], mergeUnique[K, V](a, b[1:], less)...)
 	} else {
-		return uniquify[K, V](prepend(mergeUnique[K, V](a[1:], b[1:]), simpleLink(a[0], b[0])))
+		return uniquify[K, V](prepend(mergeUnique[K, V](a[1:], b[1:], less), simpleLink(a[0], b[0], less)), less)
 	}
 }
 
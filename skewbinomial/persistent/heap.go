@@ -0,0 +1,298 @@
+// Package persistent provides an immutable skew binomial heap, as described by Brodal and Okasaki. Every operation that
+// modifies a Heap returns a new version instead, which shares most of its structure with the old one. Old versions stay
+// valid and unchanged, so taking a snapshot costs O(1), and any number of goroutines may read a version without locking
+// while another goroutine derives new versions from it.
+package persistent
+
+import (
+	"cmp"
+	"iter"
+)
+
+type Heap[K, V any] struct {
+	// trees is ordered by ascending rank. Only the first two trees may share the same rank.
+	trees *list[*Tree[K, V]]
+	size  int
+	less  func(a, b K) bool
+}
+
+func New[K cmp.Ordered, V any]() *Heap[K, V] {
+	return NewFunc[K, V](cmp.Less[K])
+}
+
+// NewFunc is like New, but orders keys using the provided less function.
+func NewFunc[K, V any](less func(a, b K) bool) *Heap[K, V] {
+	return &Heap[K, V]{
+		less: less,
+	}
+}
+
+func (h *Heap[K, V]) Size() int {
+	return h.size
+}
+
+// Insert returns a new version of the Heap that also holds the provided key-value pair, in Θ(1).
+func (h *Heap[K, V]) Insert(key K, value V) *Heap[K, V] {
+	return h.InsertSequenced(key, value, 0)
+}
+
+// InsertSequenced is like Insert, but also assigns the new node a sequence number. Nodes with equal keys are ordered by
+// ascending sequence number.
+func (h *Heap[K, V]) InsertSequenced(key K, value V, seq uint64) *Heap[K, V] {
+	newTree := &Tree[K, V]{
+		key:   key,
+		value: value,
+		seq:   seq,
+	}
+
+	return h.with(insertTree(newTree, h.trees, h.less), h.size+1)
+}
+
+// Meld returns a new version of the Heap that holds the elements of both h and other, in Θ(log n). The new version
+// orders keys the same way as h.
+func (h *Heap[K, V]) Meld(other *Heap[K, V]) *Heap[K, V] {
+	return h.with(merge(h.trees, other.trees, h.less), h.size+other.size)
+}
+
+// FindMin returns the node with the smallest key, or nil if the Heap is empty, in Θ(log n).
+func (h *Heap[K, V]) FindMin() *Tree[K, V] {
+	minTree, _ := h.findMin()
+	return minTree
+}
+
+// RemoveMin returns a new version of the Heap without the node with the smallest key, in Θ(log n). If the Heap is
+// empty, h itself is returned.
+func (h *Heap[K, V]) RemoveMin() *Heap[K, V] {
+	minTree, rest := h.findMin()
+	if minTree == nil {
+		return h
+	}
+
+	// The children of a tree are ordered by descending rank, interleaved with the rank-0 nodes that were attached by
+	// skew links. The former are reversed into a valid list of trees, and the latter are reinserted one by one.
+	var trees, zeroRanked *list[*Tree[K, V]]
+	for child := range minTree.children.all() {
+		if child.rank == 0 {
+			zeroRanked = cons(child, zeroRanked)
+		} else {
+			trees = cons(child, trees)
+		}
+	}
+
+	trees = merge(rest, trees, h.less)
+	for z := range zeroRanked.all() {
+		trees = insertTree(z, trees, h.less)
+	}
+
+	return h.with(trees, h.size-1)
+}
+
+// Nodes returns an iterator over every node in the Heap, in no particular order.
+func (h *Heap[K, V]) Nodes() iter.Seq[*Tree[K, V]] {
+	return func(yield func(*Tree[K, V]) bool) {
+		stack := make([]*Tree[K, V], 0, h.size)
+		for t := range h.trees.all() {
+			stack = append(stack, t)
+		}
+
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if !yield(n) {
+				return
+			}
+
+			for child := range n.children.all() {
+				stack = append(stack, child)
+			}
+		}
+	}
+}
+
+// All returns an iterator over every key-value pair in the Heap in priority order, which works by removing the minimum
+// from successive versions of the Heap. h itself is left untouched.
+func (h *Heap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for v := h; v.size > 0; v = v.RemoveMin() {
+			minTree := v.FindMin()
+			if !yield(minTree.key, minTree.value) {
+				return
+			}
+		}
+	}
+}
+
+func (h *Heap[K, V]) with(trees *list[*Tree[K, V]], size int) *Heap[K, V] {
+	return &Heap[K, V]{
+		trees: trees,
+		size:  size,
+		less:  h.less,
+	}
+}
+
+// findMin returns the tree whose root has the smallest key, along with a copy of the list of trees without it.
+func (h *Heap[K, V]) findMin() (*Tree[K, V], *list[*Tree[K, V]]) {
+	if h.trees == nil {
+		return nil, nil
+	}
+
+	minTree := h.trees.head
+	for t := range h.trees.tail.all() {
+		if precedes(t, minTree, h.less) {
+			minTree = t
+		}
+	}
+
+	// Only the trees in front of minTree need to be copied; the rest of the list is shared.
+	var prefix []*Tree[K, V]
+	l := h.trees
+	for ; l.head != minTree; l = l.tail {
+		prefix = append(prefix, l.head)
+	}
+
+	rest := l.tail
+	for i := len(prefix) - 1; i >= 0; i-- {
+		rest = cons(prefix[i], rest)
+	}
+	return minTree, rest
+}
+
+// Tree is an immutable node of a skew binomial tree. Trees are never modified once they are linked into a Heap, which
+// is what lets different versions of a Heap share them.
+type Tree[K, V any] struct {
+	key   K
+	value V
+	seq   uint64
+	rank  int
+
+	// children is ordered by descending rank.
+	children *list[*Tree[K, V]]
+}
+
+func (t *Tree[K, V]) Key() K {
+	return t.key
+}
+
+func (t *Tree[K, V]) Value() V {
+	return t.value
+}
+
+func (t *Tree[K, V]) Seq() uint64 {
+	return t.seq
+}
+
+// withChildren returns a copy of t's root with the provided rank and children.
+func (t *Tree[K, V]) withChildren(rank int, children *list[*Tree[K, V]]) *Tree[K, V] {
+	return &Tree[K, V]{
+		key:      t.key,
+		value:    t.value,
+		seq:      t.seq,
+		rank:     rank,
+		children: children,
+	}
+}
+
+// link links together two trees of the same rank, with a copy of the one with the smaller key becoming the parent of
+// the other.
+func link[K, V any](a, b *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	if precedes(b, a, less) {
+		a, b = b, a
+	}
+	return a.withChildren(a.rank+1, cons(b, a.children))
+}
+
+// skewLink links together three trees, one tree, a, having a rank of 0, and two trees, b and c, having the same rank as
+// each other.
+func skewLink[K, V any](a, b, c *Tree[K, V], less func(a, b K) bool) *Tree[K, V] {
+	// Type A
+	if !precedes(b, a, less) && !precedes(c, a, less) {
+		return a.withChildren(b.rank+1, cons(b, cons(c, nil)))
+	}
+
+	// Type B
+	if precedes(c, b, less) {
+		b, c = c, b
+	}
+	return b.withChildren(b.rank+1, cons(a, cons(c, b.children)))
+}
+
+// insertTree inserts a rank-0 tree into a list of trees.
+func insertTree[K, V any](t *Tree[K, V], trees *list[*Tree[K, V]], less func(a, b K) bool) *list[*Tree[K, V]] {
+	if trees != nil && trees.tail != nil && trees.head.rank == trees.tail.head.rank {
+		return cons(skewLink(t, trees.head, trees.tail.head, less), trees.tail.tail)
+	}
+	return cons(t, trees)
+}
+
+// merge merges two lists of trees.
+func merge[K, V any](a, b *list[*Tree[K, V]], less func(a, b K) bool) *list[*Tree[K, V]] {
+	return mergeUnique(uniquify(a, less), uniquify(b, less), less)
+}
+
+// uniquify links the first two trees in the list if they share the same rank, so that every rank is unique.
+func uniquify[K, V any](trees *list[*Tree[K, V]], less func(a, b K) bool) *list[*Tree[K, V]] {
+	if trees == nil {
+		return nil
+	}
+	return carry(trees.head, trees.tail, less)
+}
+
+// carry inserts t into a list of trees with unique ranks that are no smaller than t's, linking trees of equal rank like
+// a binary carry.
+func carry[K, V any](t *Tree[K, V], trees *list[*Tree[K, V]], less func(a, b K) bool) *list[*Tree[K, V]] {
+	for trees != nil && trees.head.rank == t.rank {
+		t = link(t, trees.head, less)
+		trees = trees.tail
+	}
+	return cons(t, trees)
+}
+
+func mergeUnique[K, V any](a, b *list[*Tree[K, V]], less func(a, b K) bool) *list[*Tree[K, V]] {
+	if a == nil {
+		return b
+	} else if b == nil {
+		return a
+	}
+
+	if a.head.rank < b.head.rank {
+		return cons(a.head, mergeUnique(a.tail, b, less))
+	} else if a.head.rank > b.head.rank {
+		return cons(b.head, mergeUnique(a, b.tail, less))
+	} else {
+		return carry(link(a.head, b.head, less), mergeUnique(a.tail, b.tail, less), less)
+	}
+}
+
+// precedes reports whether node a has a higher priority than node b. Ties between equal keys are broken by sequence
+// number.
+func precedes[K, V any](a, b *Tree[K, V], less func(a, b K) bool) bool {
+	if less(a.key, b.key) {
+		return true
+	}
+	return a.seq < b.seq && !less(b.key, a.key)
+}
+
+// list is an immutable singly linked list, which lets versions share the tail of a list.
+type list[T any] struct {
+	head T
+	tail *list[T]
+}
+
+func cons[T any](head T, tail *list[T]) *list[T] {
+	return &list[T]{
+		head: head,
+		tail: tail,
+	}
+}
+
+// all returns an iterator over every element in the list, from head to tail.
+func (l *list[T]) all() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for ; l != nil; l = l.tail {
+			if !yield(l.head) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package skewbinomial
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalid is returned by Validate when a Forest's invariants do not hold.
+var ErrInvalid = errors.New("skewbinomial: invalid forest")
+
+// Validate checks the invariants of the Forest in Θ(n): that the ranks of its trees are unique and ascending (except
+// that the first two trees may share a rank, as uniquify would link them), that a tree of rank r has between 2^r and
+// 2^(r+1)-1 nodes, that every node is linked to its parent, that no node has a higher priority than its parent, and
+// that no node is reachable twice. It returns an error wrapping ErrInvalid that describes the first violation found, or
+// nil if the Forest is valid.
+func (f *Forest[K, V]) Validate() error {
+	seen := make(map[*Tree[K, V]]bool)
+	for i, t := range f.trees {
+		if t.parent != nil {
+			return fmt.Errorf("%w: root of tree %d has a parent", ErrInvalid, i)
+		}
+		if i > 0 {
+			prev := f.trees[i-1].rank
+			if t.rank < prev || t.rank == prev && i > 1 {
+				return fmt.Errorf("%w: tree %d has rank %d after a tree of rank %d", ErrInvalid, i, t.rank, prev)
+			}
+		}
+
+		size, err := f.validateTree(t, seen)
+		if err != nil {
+			return err
+		}
+		if t.rank < 0 || t.rank >= 63 || size < 1<<t.rank || size >= 1<<(t.rank+1) {
+			return fmt.Errorf("%w: tree %d has rank %d but %d nodes", ErrInvalid, i, t.rank, size)
+		}
+	}
+	return nil
+}
+
+// validateTree checks the links and heap order of the tree rooted at t, and returns its number of nodes. Trees have a
+// depth of O(log n), so recursion is safe.
+func (f *Forest[K, V]) validateTree(t *Tree[K, V], seen map[*Tree[K, V]]bool) (int, error) {
+	if seen[t] {
+		return 0, fmt.Errorf("%w: node with key %v is reachable twice", ErrInvalid, t.key)
+	}
+	seen[t] = true
+
+	size := 1
+	for _, c := range t.children {
+		if c.parent != t {
+			return 0, fmt.Errorf("%w: node with key %v is not linked to its parent with key %v", ErrInvalid, c.key, t.key)
+		}
+		if precedes(c, t, f.less) {
+			return 0, fmt.Errorf("%w: node with key %v precedes its parent with key %v", ErrInvalid, c.key, t.key)
+		}
+
+		n, err := f.validateTree(c, seen)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+	return size, nil
+}
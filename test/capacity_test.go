@@ -0,0 +1,246 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// fill pushes an element for every priority onto q, with the priority's decimal representation as its value.
+func fill(t *testing.T, q pqueue.Queue[int, string], priorities ...int) {
+	t.Helper()
+
+	for _, p := range priorities {
+		if err := q.Push(strconv.Itoa(p), p); err != nil {
+			t.Fatalf("Push(%d): %v", p, err)
+		}
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		policy pqueue.OverflowPolicy
+		name   string
+		// pushErr is the error returned by pushing 2 onto a full queue holding 1, 4 and 5, and pushed holds the
+		// elements that survive it.
+		pushErr error
+		pushed  []string
+		// meldErr is the error returned by melding a queue holding 0 and 3 into the full queue, and melded holds the
+		// elements that survive it.
+		meldErr error
+		melded  []string
+	}{
+		{pqueue.OverflowError, "Error", pqueue.ErrFull, []string{"1", "4", "5"}, pqueue.ErrFull, []string{"1", "4", "5"}},
+		{pqueue.OverflowDrop, "Drop", nil, []string{"1", "4", "5"}, nil, []string{"0", "1", "3"}},
+		{pqueue.OverflowEvictMax, "EvictMax", nil, []string{"1", "2", "4"}, nil, []string{"0", "1", "3"}},
+		{pqueue.OverflowBlock, "Block", context.DeadlineExceeded, []string{"1", "4", "5"}, pqueue.ErrFull, []string{"1", "4", "5"}},
+	}
+
+	for k, kind := range heapKinds {
+		next := heapKinds[(k+1)%len(heapKinds)]
+
+		for _, tt := range tests {
+			t.Run(kind.name+"/"+tt.name, func(t *testing.T) {
+				newFull := func(t *testing.T) heap {
+					q := kind.new(pqueue.WithCapacity(3, tt.policy))
+					fill(t, q, 5, 1, 4)
+					return q
+				}
+
+				t.Run("Push", func(t *testing.T) {
+					q := newFull(t)
+
+					ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+					defer cancel()
+					if err := q.PushWait(ctx, "2", 2); !errors.Is(err, tt.pushErr) {
+						t.Fatalf("PushWait: got %v, want %v", err, tt.pushErr)
+					}
+					expectPops(t, q, tt.pushed...)
+				})
+
+				t.Run("PushMany", func(t *testing.T) {
+					q := newFull(t)
+
+					ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+					defer cancel()
+					if err := q.PushManyWait(ctx, []pqueue.Item[int, string]{{Priority: 2, Value: "2"}}); !errors.Is(err, tt.pushErr) {
+						t.Fatalf("PushManyWait: got %v, want %v", err, tt.pushErr)
+					}
+					expectPops(t, q, tt.pushed...)
+				})
+
+				t.Run("PushHandle", func(t *testing.T) {
+					q := newFull(t)
+
+					ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+					defer cancel()
+					if _, err := q.PushHandleWait(ctx, "2", 2); !errors.Is(err, tt.pushErr) {
+						t.Fatalf("PushHandleWait: got %v, want %v", err, tt.pushErr)
+					}
+					expectPops(t, q, tt.pushed...)
+				})
+
+				t.Run("Meld", func(t *testing.T) {
+					q, other := newFull(t), kind.new()
+					fill(t, other, 3, 0)
+
+					if err := kind.meld(q, other); !errors.Is(err, tt.meldErr) {
+						t.Fatalf("Meld: got %v, want %v", err, tt.meldErr)
+					}
+					checkMelded(t, q, other, tt.meldErr, tt.melded)
+				})
+
+				t.Run("CrossMeld", func(t *testing.T) {
+					q, other := newFull(t), next.new()
+					fill(t, other, 3, 0)
+
+					if err := q.CrossMeld(other); !errors.Is(err, tt.meldErr) {
+						t.Fatalf("CrossMeld: got %v, want %v", err, tt.meldErr)
+					}
+					checkMelded(t, q, other, tt.meldErr, tt.melded)
+				})
+			})
+		}
+
+		t.Run(kind.name+"/Block/Unblock", func(t *testing.T) {
+			q := kind.new(pqueue.WithCapacity(1, pqueue.OverflowBlock))
+			fill(t, q, 1)
+
+			done := make(chan error, 1)
+			go func() { done <- q.Push("2", 2) }()
+
+			time.Sleep(10 * time.Millisecond)
+			if v, ok := q.Pop(); !ok || v != "1" {
+				t.Fatalf("Pop: got %q, %v", v, ok)
+			}
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Push was not unblocked by Pop")
+			}
+			expectPops(t, q, "2")
+		})
+
+		// PushMany waits for room item by item, so every Pop lets one more item in.
+		t.Run(kind.name+"/Block/UnblockMany", func(t *testing.T) {
+			q := kind.new(pqueue.WithCapacity(1, pqueue.OverflowBlock))
+			fill(t, q, 1)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- q.PushMany([]pqueue.Item[int, string]{{Priority: 3, Value: "3"}, {Priority: 2, Value: "2"}})
+			}()
+
+			for _, want := range []string{"1", "3"} {
+				time.Sleep(10 * time.Millisecond)
+				if v, ok := q.Pop(); !ok || v != want {
+					t.Fatalf("Pop: got %q, %v, want %q", v, ok, want)
+				}
+			}
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("PushMany was not unblocked by Pop")
+			}
+			expectPops(t, q, "2")
+		})
+	}
+}
+
+// checkMelded checks the outcome of melding other into q: if the meld failed, other must be left untouched.
+func checkMelded(t *testing.T, q, other heap, err error, want []string) {
+	t.Helper()
+
+	wantOther := 0
+	if err != nil {
+		wantOther = 2
+	}
+	if other.Size() != wantOther {
+		t.Fatalf("other.Size: got %d, want %d", other.Size(), wantOther)
+	}
+	expectPops(t, q, want...)
+}
+
+func TestCircularBufferCapacity(t *testing.T) {
+	tests := []struct {
+		policy pqueue.OverflowPolicy
+		name   string
+		// pushErr is the error returned by pushing 4 onto a full buffer holding 1, 2 and 3, and pushed holds the
+		// values that survive it.
+		pushErr error
+		pushed  []int
+		// meldErr is the error returned by melding a buffer holding 4 and 5 into the full buffer, and melded holds the
+		// values that survive it.
+		meldErr error
+		melded  []int
+	}{
+		{pqueue.OverflowError, "Error", pqueue.ErrFull, []int{1, 2, 3}, pqueue.ErrFull, []int{1, 2, 3}},
+		{pqueue.OverflowDrop, "Drop", nil, []int{1, 2, 3}, nil, []int{1, 2, 3}},
+		{pqueue.OverflowOverwriteOldest, "OverwriteOldest", nil, []int{2, 3, 4}, nil, []int{3, 4, 5}},
+		{pqueue.OverflowBlock, "Block", context.DeadlineExceeded, []int{1, 2, 3}, pqueue.ErrFull, []int{1, 2, 3}},
+	}
+
+	drain := func(cb *pqueue.CircularBuffer[int]) []int {
+		var values []int
+		for v, ok := cb.Pop(); ok; v, ok = cb.Pop() {
+			values = append(values, v)
+		}
+		return values
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFull := func() *pqueue.CircularBuffer[int] {
+				cb := pqueue.NewCircularBuffer[int](pqueue.WithCapacity(3, tt.policy))
+				for v := 1; v <= 3; v++ {
+					_ = cb.Push(v)
+				}
+				return cb
+			}
+
+			t.Run("Push", func(t *testing.T) {
+				cb := newFull()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+				if err := cb.PushWait(ctx, 4); !errors.Is(err, tt.pushErr) {
+					t.Fatalf("PushWait: got %v, want %v", err, tt.pushErr)
+				}
+				if got := drain(cb); !slices.Equal(got, tt.pushed) {
+					t.Fatalf("values: got %v, want %v", got, tt.pushed)
+				}
+			})
+
+			t.Run("Meld", func(t *testing.T) {
+				cb, other := newFull(), pqueue.NewCircularBuffer[int]()
+				_ = other.Push(4)
+				_ = other.Push(5)
+
+				if err := cb.Meld(other); !errors.Is(err, tt.meldErr) {
+					t.Fatalf("Meld: got %v, want %v", err, tt.meldErr)
+				}
+				wantOther := 0
+				if tt.meldErr != nil {
+					wantOther = 2
+				}
+				if other.Size() != wantOther {
+					t.Fatalf("other.Size: got %d, want %d", other.Size(), wantOther)
+				}
+				if got := drain(cb); !slices.Equal(got, tt.melded) {
+					t.Fatalf("values: got %v, want %v", got, tt.melded)
+				}
+			})
+		})
+	}
+}
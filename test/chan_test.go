@@ -0,0 +1,128 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+	"github.com/AndrewChon/pqueue/expvarmetrics"
+)
+
+// receive returns the next item delivered on out, failing the test if none arrives in time.
+func receive(t *testing.T, out <-chan pqueue.Item[int, string]) (pqueue.Item[int, string], bool) {
+	t.Helper()
+
+	select {
+	case item, ok := <-out:
+		return item, ok
+	case <-time.After(time.Second):
+		t.Fatal("nothing was delivered")
+		panic("unreachable")
+	}
+}
+
+// expectStream receives from out until it is closed and checks that the values come out in the wanted order.
+func expectStream(t *testing.T, out <-chan pqueue.Item[int, string], want ...string) {
+	t.Helper()
+
+	var got []string
+	for item, ok := receive(t, out); ok; item, ok = receive(t, out) {
+		got = append(got, item.Value)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("stream: got %q, want %q", got, want)
+	}
+}
+
+func TestFeedStream(t *testing.T) {
+	for _, kind := range heapKinds {
+		t.Run(kind.name+"/Sorted", func(t *testing.T) {
+			q := kind.new()
+			in := make(chan pqueue.Item[int, string])
+
+			fed := make(chan error, 1)
+			go func() { fed <- pqueue.Feed(context.Background(), q, in) }()
+			for _, p := range []int{5, 3, 9, 1, 7} {
+				in <- pqueue.Item[int, string]{Priority: p, Value: string(rune('0' + p))}
+			}
+			close(in)
+
+			if err := <-fed; err != nil {
+				t.Fatalf("Feed: %v", err)
+			}
+			if !q.IsClosed() {
+				t.Fatal("Feed did not close the queue")
+			}
+			expectStream(t, pqueue.Stream(context.Background(), q), "1", "3", "5", "7", "9")
+		})
+
+		t.Run(kind.name+"/Overtake", func(t *testing.T) {
+			m := expvarmetrics.NewUnpublished()
+			r := &recorder{}
+			q := kind.new(pqueue.WithMetrics(m), pqueue.WithObserver(r))
+			fill(t, q, 2)
+
+			// Stream holds 2 while the consumer is away, and exchanges it for 1 once 1 is pushed.
+			out := pqueue.Stream(context.Background(), q)
+			time.Sleep(10 * time.Millisecond)
+			fill(t, q, 1)
+			time.Sleep(10 * time.Millisecond)
+
+			q.Close()
+			expectStream(t, out, "1", "2")
+
+			// Exchanging the held element is neither a push nor a pop.
+			expectEvents(t, r, "push 2 2", "push 1 1", "pop 1 1", "pop 2 2")
+			vars := readMetrics(t, m)
+			expectMetric(t, vars, "pushes", 2)
+			expectMetric(t, vars, "pops", 2)
+			expectMetric(t, vars, "depth", 0)
+		})
+
+		t.Run(kind.name+"/Cancel", func(t *testing.T) {
+			r := &recorder{}
+			q := kind.new(pqueue.WithObserver(r))
+			fill(t, q, 1, 2)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			out := pqueue.Stream(ctx, q)
+			if item, ok := receive(t, out); !ok || item.Value != "1" {
+				t.Fatalf("Stream: got %v, %v, want 1", item, ok)
+			}
+
+			// The element held by Stream is returned to the queue.
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+			expectStream(t, out)
+			expectPops(t, q, "2")
+			expectEvents(t, r, "push 1 1", "push 2 2", "pop 1 1", "pop 2 2")
+		})
+
+		t.Run(kind.name+"/FeedCancel", func(t *testing.T) {
+			q := kind.new()
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			if err := pqueue.Feed(ctx, q, make(chan pqueue.Item[int, string])); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Feed: got %v, want context.Canceled", err)
+			}
+			if q.IsClosed() {
+				t.Fatal("Feed closed the queue after ctx was canceled")
+			}
+		})
+
+		t.Run(kind.name+"/FeedFull", func(t *testing.T) {
+			q := kind.new(pqueue.WithCapacity(1, pqueue.OverflowError))
+			in := make(chan pqueue.Item[int, string], 2)
+			in <- pqueue.Item[int, string]{Priority: 1, Value: "1"}
+			in <- pqueue.Item[int, string]{Priority: 2, Value: "2"}
+
+			if err := pqueue.Feed(context.Background(), q, in); !errors.Is(err, pqueue.ErrFull) {
+				t.Fatalf("Feed: got %v, want ErrFull", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// isDone reports whether done is closed.
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestClose(t *testing.T) {
+	for k, kind := range heapKinds {
+		next := heapKinds[(k+1)%len(heapKinds)]
+
+		t.Run(kind.name+"/Push", func(t *testing.T) {
+			q := kind.new()
+			fill(t, q, 1)
+			q.Close()
+			q.Close()
+
+			if !q.IsClosed() {
+				t.Fatal("IsClosed: got false after Close")
+			}
+			if err := q.Push("2", 2); !errors.Is(err, pqueue.ErrClosed) {
+				t.Fatalf("Push: got %v, want ErrClosed", err)
+			}
+			if err := q.PushMany([]pqueue.Item[int, string]{{Priority: 2, Value: "2"}}); !errors.Is(err, pqueue.ErrClosed) {
+				t.Fatalf("PushMany: got %v, want ErrClosed", err)
+			}
+			if h, err := q.PushHandle("2", 2); h != nil || !errors.Is(err, pqueue.ErrClosed) {
+				t.Fatalf("PushHandle: got %v, %v, want ErrClosed", h, err)
+			}
+
+			other := kind.new()
+			fill(t, other, 2)
+			if err := kind.meld(q, other); !errors.Is(err, pqueue.ErrClosed) || other.Size() != 1 {
+				t.Fatalf("Meld: got %v with %d elements left in other, want ErrClosed", err, other.Size())
+			}
+			crossOther := next.new()
+			fill(t, crossOther, 2)
+			if err := q.CrossMeld(crossOther); !errors.Is(err, pqueue.ErrClosed) || crossOther.Size() != 1 {
+				t.Fatalf("CrossMeld: got %v with %d elements left in other, want ErrClosed", err, crossOther.Size())
+			}
+
+			// Remaining elements can still be popped.
+			expectPops(t, q, "1")
+		})
+
+		t.Run(kind.name+"/PopWait", func(t *testing.T) {
+			q := kind.new()
+			done := popWait(func() (string, error) { return q.PopWait(context.Background()) })
+
+			q.Close()
+			if res := await(t, done); !errors.Is(res.err, pqueue.ErrClosed) {
+				t.Fatalf("PopWait: got %q, %v, want ErrClosed", res.v, res.err)
+			}
+		})
+
+		t.Run(kind.name+"/PushWait", func(t *testing.T) {
+			q := kind.new(pqueue.WithCapacity(1, pqueue.OverflowBlock))
+			fill(t, q, 1)
+			done := popWait(func() (struct{}, error) { return struct{}{}, q.PushWait(context.Background(), "2", 2) })
+
+			q.Close()
+			if res := await(t, done); !errors.Is(res.err, pqueue.ErrClosed) {
+				t.Fatalf("PushWait: got %v, want ErrClosed", res.err)
+			}
+			expectPops(t, q, "1")
+		})
+
+		t.Run(kind.name+"/Done", func(t *testing.T) {
+			q := kind.new()
+			fill(t, q, 1, 2)
+			if isDone(q.Done()) {
+				t.Fatal("Done closed before Close")
+			}
+
+			q.Close()
+			if _, err := q.PopWait(context.Background()); err != nil || isDone(q.Done()) {
+				t.Fatalf("Done closed with elements left (PopWait: %v)", err)
+			}
+			if _, ok := q.Pop(); !ok || !isDone(q.Done()) {
+				t.Fatal("Done not closed once drained")
+			}
+			if _, err := q.PopWait(context.Background()); !errors.Is(err, pqueue.ErrClosed) {
+				t.Fatalf("PopWait on drained queue: got %v, want ErrClosed", err)
+			}
+
+			// Closing an empty queue closes Done at once.
+			empty := kind.new()
+			empty.Close()
+			if !isDone(empty.Done()) {
+				t.Fatal("Done not closed after closing an empty queue")
+			}
+		})
+	}
+
+	t.Run("CircularBuffer", func(t *testing.T) {
+		cb := pqueue.NewCircularBuffer[int](pqueue.WithCapacity(1, pqueue.OverflowBlock))
+		_ = cb.Push(1)
+		pushed := popWait(func() (struct{}, error) { return struct{}{}, cb.PushWait(context.Background(), 2) })
+
+		cb.Close()
+		if res := await(t, pushed); !errors.Is(res.err, pqueue.ErrClosed) {
+			t.Fatalf("PushWait: got %v, want ErrClosed", res.err)
+		}
+		if err := cb.Push(2); !errors.Is(err, pqueue.ErrClosed) {
+			t.Fatalf("Push: got %v, want ErrClosed", err)
+		}
+		if isDone(cb.Done()) {
+			t.Fatal("Done closed with values left")
+		}
+
+		if v, err := cb.PopWait(context.Background()); err != nil || v != 1 {
+			t.Fatalf("PopWait: got %d, %v, want 1", v, err)
+		}
+		if !isDone(cb.Done()) {
+			t.Fatal("Done not closed once drained")
+		}
+		if _, err := cb.PopWait(context.Background()); !errors.Is(err, pqueue.ErrClosed) {
+			t.Fatalf("PopWait on drained buffer: got %v, want ErrClosed", err)
+		}
+	})
+
+	t.Run("CircularBuffer/PopWait", func(t *testing.T) {
+		cb := pqueue.NewCircularBuffer[int]()
+		done := popWait(func() (int, error) { return cb.PopWait(context.Background()) })
+
+		cb.Close()
+		if res := await(t, done); !errors.Is(res.err, pqueue.ErrClosed) {
+			t.Fatalf("PopWait: got %v, want ErrClosed", res.err)
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package test
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// intCodec encodes ints as varints, which keeps codec overhead out of the serialization benchmarks.
+type intCodec struct{}
+
+func (intCodec) Marshal(v int) ([]byte, error) {
+	return binary.AppendVarint(nil, int64(v)), nil
+}
+
+func (intCodec) Unmarshal(data []byte) (int, error) {
+	v, _ := binary.Varint(data)
+	return int(v), nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	items := []pqueue.Item[int, string]{
+		{Priority: 3, Value: "c"}, {Priority: 1, Value: "a"}, {Priority: 4, Value: "d"}, {Priority: 2, Value: "b"},
+	}
+
+	formats := []struct {
+		name      string
+		marshal   func(q heap) ([]byte, error)
+		unmarshal func(q heap, data []byte) error
+	}{
+		{"Binary", heap.MarshalBinary, heap.UnmarshalBinary},
+		{"JSON", heap.MarshalJSON, heap.UnmarshalJSON},
+	}
+
+	for _, f := range formats {
+		for _, from := range heapKinds {
+			for _, to := range heapKinds {
+				t.Run(f.name+"/"+from.name+"/"+to.name, func(t *testing.T) {
+					data, err := f.marshal(from.newFrom(items))
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					// Loading replaces the previous contents of the queue.
+					q := to.new()
+					fill(t, q, 0)
+					if err := f.unmarshal(q, data); err != nil {
+						t.Fatal(err)
+					}
+					if err := q.Validate(); err != nil {
+						t.Fatal(err)
+					}
+					expectPops(t, q, "a", "b", "c", "d")
+				})
+			}
+
+			t.Run(f.name+"/"+from.name+"/Empty", func(t *testing.T) {
+				data, err := f.marshal(from.new())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				q := from.new()
+				fill(t, q, 0)
+				if err := f.unmarshal(q, data); err != nil || q.Size() != 0 {
+					t.Fatalf("got %v with size %d, want an empty queue", err, q.Size())
+				}
+			})
+		}
+	}
+
+	t.Run("Codecs", func(t *testing.T) {
+		opts := []pqueue.Option{pqueue.WithKeyCodec[int](intCodec{}), pqueue.WithValueCodec[string](pqueue.GobCodec[string]{})}
+		data, err := pqueue.NewSkewFrom(items, opts...).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q := pqueue.NewPairing[int, string](opts...)
+		if err := q.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		expectPops(t, q, "a", "b", "c", "d")
+	})
+
+	t.Run("CircularBuffer", func(t *testing.T) {
+		cb := pqueue.NewCircularBuffer[int]()
+		for v := range 4 {
+			_ = cb.Push(v)
+		}
+
+		binaryData, err := cb.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		jsonData, err := cb.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, load := range []func(*pqueue.CircularBuffer[int]) error{
+			func(cb *pqueue.CircularBuffer[int]) error { return cb.UnmarshalBinary(binaryData) },
+			func(cb *pqueue.CircularBuffer[int]) error { return cb.UnmarshalJSON(jsonData) },
+		} {
+			restored := pqueue.NewCircularBuffer[int]()
+			_ = restored.Push(9)
+			if err := load(restored); err != nil {
+				t.Fatal(err)
+			}
+			for want := range 4 {
+				if v, ok := restored.Pop(); !ok || v != want {
+					t.Fatalf("Pop: got %d, %v, want %d", v, ok, want)
+				}
+			}
+			if restored.Size() != 0 {
+				t.Fatalf("Size: got %d, want 0", restored.Size())
+			}
+		}
+	})
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	items := []pqueue.Item[int, string]{{Priority: 2, Value: "b"}, {Priority: 1, Value: "a"}}
+	valid, err := pqueue.NewBinaryFrom(items).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validJSON, err := pqueue.NewBinaryFrom(items).MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cb := pqueue.NewCircularBuffer[string]()
+	_ = cb.Push("a")
+	fifo, err := cb.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fifoJSON, err := cb.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// jsonTests holds JSON snapshots that no heap-based queue can load.
+	jsonTests := map[string][]byte{
+		"Version":     []byte(`{"version":99,"kind":"heap","items":[]}`),
+		"Kind":        fifoJSON,
+		"MissingKind": []byte(`{"version":1,"items":[]}`),
+		"NoItems":     []byte(`{"version":1,"kind":"heap"}`),
+		"NullItems":   []byte(`{"version":1,"kind":"heap","items":null}`),
+	}
+
+	tests := map[string][]byte{
+		"Empty":        nil,
+		"Version":      append([]byte{99}, valid[1:]...),
+		"Kind":         fifo,
+		"TrailingData": append(slices.Clone(valid), 0),
+		"Count":        append(slices.Clone(valid[:2]), 0xff, 0xff, 0xff, 0xff, 0x0f),
+	}
+	// Every proper prefix of a valid snapshot is truncated.
+	for n := 1; n < len(valid); n++ {
+		tests["Truncated/"+strconv.Itoa(n)] = valid[:n]
+	}
+
+	for _, kind := range heapKinds {
+		for name, data := range tests {
+			t.Run(kind.name+"/"+name, func(t *testing.T) {
+				q := kind.new()
+				fill(t, q, 7)
+
+				if err := q.UnmarshalBinary(data); !errors.Is(err, pqueue.ErrInvalidFormat) {
+					t.Fatalf("UnmarshalBinary: got %v, want ErrInvalidFormat", err)
+				}
+				// A failed load leaves the queue untouched.
+				expectPops(t, q, "7")
+			})
+		}
+
+		for name, data := range jsonTests {
+			t.Run(kind.name+"/JSON/"+name, func(t *testing.T) {
+				q := kind.new()
+				fill(t, q, 7)
+
+				if err := q.UnmarshalJSON(data); !errors.Is(err, pqueue.ErrInvalidFormat) {
+					t.Fatalf("UnmarshalJSON: got %v, want ErrInvalidFormat", err)
+				}
+				expectPops(t, q, "7")
+			})
+		}
+
+		t.Run(kind.name+"/JSON", func(t *testing.T) {
+			q := kind.new()
+			fill(t, q, 7)
+
+			if err := q.UnmarshalJSON([]byte(`{"version":1,"kind":"heap","items":[{"priority":"x","value":"a"}]}`)); err == nil {
+				t.Fatal("UnmarshalJSON of a mistyped priority succeeded")
+			}
+			if err := q.UnmarshalJSON([]byte(`{"version":1,`)); err == nil {
+				t.Fatal("UnmarshalJSON of truncated data succeeded")
+			}
+			expectPops(t, q, "7")
+		})
+	}
+
+	t.Run("CircularBuffer", func(t *testing.T) {
+		for name, data := range map[string][]byte{
+			"Kind":         valid,
+			"TrailingData": append(slices.Clone(fifo), 0),
+			"Truncated":    fifo[:len(fifo)-1],
+		} {
+			cb := pqueue.NewCircularBuffer[string]()
+			_ = cb.Push("z")
+
+			if err := cb.UnmarshalBinary(data); !errors.Is(err, pqueue.ErrInvalidFormat) {
+				t.Fatalf("%s: got %v, want ErrInvalidFormat", name, err)
+			}
+			if v, ok := cb.Pop(); !ok || v != "z" {
+				t.Fatalf("%s: buffer was modified", name)
+			}
+		}
+
+		for name, data := range map[string][]byte{
+			"Kind":        validJSON,
+			"MissingKind": []byte(`{"version":1,"values":[]}`),
+			"NoValues":    []byte(`{"version":1,"kind":"fifo"}`),
+		} {
+			cb := pqueue.NewCircularBuffer[string]()
+			_ = cb.Push("z")
+
+			if err := cb.UnmarshalJSON(data); !errors.Is(err, pqueue.ErrInvalidFormat) {
+				t.Fatalf("JSON/%s: got %v, want ErrInvalidFormat", name, err)
+			}
+			if v, ok := cb.Pop(); !ok || v != "z" {
+				t.Fatalf("JSON/%s: buffer was modified", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/AndrewChon/pqueue"
+	"github.com/AndrewChon/pqueue/httpdebug"
+)
+
+type debugItem struct {
+	Priority string
+	Value    string
+}
+
+type debugQueue struct {
+	Kind  string
+	ID    uint64
+	Size  int
+	Top   []debugItem
+	Shape map[string]any
+}
+
+// getDebug fetches the registered queues from the debug handler.
+func getDebug(t *testing.T, url string) map[string]debugQueue {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status: got %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var queues map[string]debugQueue
+	if err := json.NewDecoder(resp.Body).Decode(&queues); err != nil {
+		t.Fatal(err)
+	}
+	return queues
+}
+
+func TestDebugHandler(t *testing.T) {
+	srv := httptest.NewServer(httpdebug.Handler())
+	defer srv.Close()
+
+	b := pqueue.NewBinary[int, string]()
+	p := pqueue.NewPairing[int, string]()
+	sb := pqueue.NewSkewBinomial[int, string]()
+	cb := pqueue.NewCircularBuffer[string]()
+	for i, v := range []string{"d", "a", "c", "b", "e"} {
+		_ = b.Push(v, int(v[0]))
+		_ = p.Push(v, int(v[0]))
+		_ = sb.Push(v, i)
+		_ = cb.Push(v)
+	}
+
+	pqueue.Register("binary", b)
+	pqueue.Register("pairing", p)
+	pqueue.Register("skewbinomial", sb)
+	pqueue.Register("buffer", cb)
+	defer func() {
+		for _, name := range []string{"binary", "pairing", "skewbinomial", "buffer"} {
+			pqueue.Unregister(name)
+		}
+	}()
+
+	queues := getDebug(t, srv.URL+"?top=2")
+	if len(queues) != 4 {
+		t.Fatalf("got %d queues, want 4", len(queues))
+	}
+
+	binary := queues["binary"]
+	want := []debugItem{{"97", "a"}, {"98", "b"}}
+	if binary.Kind != "Binary" || binary.Size != 5 || !slices.Equal(binary.Top, want) {
+		t.Fatalf("binary: got %+v, want top %v", binary, want)
+	}
+	if binary.Shape["arrayLength"] != 5.0 || binary.Shape["arrayCapacity"].(float64) < 5 {
+		t.Fatalf("binary shape: got %v", binary.Shape)
+	}
+
+	if _, ok := queues["pairing"].Shape["rootChildren"]; !ok {
+		t.Fatalf("pairing shape: got %v", queues["pairing"].Shape)
+	}
+	// Five insertions leave two trees of rank 0 in front of a tree of rank 1.
+	if ranks, _ := queues["skewbinomial"].Shape["treeRanks"].([]any); !slices.Equal(ranks, []any{0.0, 0.0, 1.0}) {
+		t.Fatalf("skewbinomial shape: got %v", queues["skewbinomial"].Shape)
+	}
+
+	buffer := queues["buffer"]
+	if want := []debugItem{{"<nil>", "d"}, {"<nil>", "a"}}; !slices.Equal(buffer.Top, want) {
+		t.Fatalf("buffer: got top %v, want %v", buffer.Top, want)
+	}
+
+	pqueue.Unregister("buffer")
+	if _, ok := getDebug(t, srv.URL)["buffer"]; ok {
+		t.Fatal("unregistered queue is still listed")
+	}
+}
+
+func TestDebugHandlerBadTop(t *testing.T) {
+	srv := httptest.NewServer(httpdebug.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?top=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status: got %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func BenchmarkInspectPairing(b *testing.B) {
+	q := pqueue.NewPairing[int, int]()
+	for range 1000 {
+		q.Push(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Inspect(10)
+	}
+}
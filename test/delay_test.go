@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// manualClock is a pqueue.Clock that only moves when Advance is called.
+type manualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []manualTimer
+	waiting chan struct{}
+}
+
+type manualTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Unix(0, 0), waiting: make(chan struct{}, 1)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.timers = append(c.timers, manualTimer{c.now.Add(d), ch})
+	select {
+	case c.waiting <- struct{}{}:
+	default:
+	}
+	return ch
+}
+
+// Advance moves the clock forward by d, firing every timer that expires.
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.ch <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+// Skip moves the clock forward by d without firing any timer, as if the goroutines waiting on them were late to run.
+func (c *manualClock) Skip(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// awaitTimer blocks until a timer has been scheduled since the last call.
+func (c *manualClock) awaitTimer(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-c.waiting:
+	case <-time.After(time.Second):
+		t.Fatal("no timer was scheduled")
+	}
+}
+
+func TestDelayQueuePopReady(t *testing.T) {
+	clock := newManualClock()
+	q := pqueue.NewDelayQueue[string](pqueue.WithClock(clock))
+
+	_ = q.PushAfter("b", 2*time.Second)
+	_ = q.PushAfter("a", time.Second)
+	if v, ok := q.PopReady(); ok {
+		t.Fatalf("PopReady before any deadline: got %q", v)
+	}
+
+	clock.Advance(time.Second)
+	if v, ok := q.PopReady(); !ok || v != "a" {
+		t.Fatalf("PopReady: got %q, %v, want \"a\"", v, ok)
+	}
+	if v, ok := q.PopReady(); ok {
+		t.Fatalf("PopReady before second deadline: got %q", v)
+	}
+	if n := q.Size(); n != 1 {
+		t.Fatalf("Size: got %d, want 1", n)
+	}
+}
+
+func TestDelayQueueTakeReschedules(t *testing.T) {
+	clock := newManualClock()
+	q := pqueue.NewDelayQueue[string](pqueue.WithClock(clock))
+
+	_ = q.PushAfter("late", time.Hour)
+	taken := make(chan string)
+	go func() {
+		v, err := q.Take(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		taken <- v
+	}()
+	clock.awaitTimer(t)
+
+	// An earlier value must wake Take up, which then sleeps until the new, earlier deadline.
+	_ = q.PushAfter("early", time.Second)
+	clock.awaitTimer(t)
+	clock.Advance(time.Second)
+
+	select {
+	case v := <-taken:
+		if v != "early" {
+			t.Fatalf("Take: got %q, want \"early\"", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Take did not return after the earlier deadline passed")
+	}
+}
+
+func TestDelayQueueTakeClosed(t *testing.T) {
+	q := pqueue.NewDelayQueue[int](pqueue.WithClock(newManualClock()))
+
+	done := make(chan error)
+	go func() {
+		_, err := q.Take(context.Background())
+		done <- err
+	}()
+	q.Close()
+
+	if err := <-done; err != pqueue.ErrClosed {
+		t.Fatalf("Take after Close: got %v, want %v", err, pqueue.ErrClosed)
+	}
+}
+
+func TestDelayQueueTakeCanceled(t *testing.T) {
+	q := pqueue.NewDelayQueue[int](pqueue.WithClock(newManualClock()))
+	_ = q.PushAfter(1, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.Take(ctx); err != context.Canceled {
+		t.Fatalf("Take with canceled context: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func BenchmarkDelayQueuePush(b *testing.B) {
+	q := pqueue.NewDelayQueue[int](pqueue.WithClock(newManualClock()))
+
+	for b.Loop() {
+		q.PushAfter(0, time.Duration(rand.Int63()))
+	}
+}
+
+func BenchmarkDelayQueuePopReady(b *testing.B) {
+	clock := newManualClock()
+	q := pqueue.NewDelayQueue[int](pqueue.WithClock(clock))
+
+	for i := 0; i < b.N; i++ {
+		q.PushAfter(0, time.Duration(rand.Int63n(int64(time.Hour))))
+	}
+	clock.Advance(time.Hour)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.PopReady()
+	}
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+func TestWriteDOT(t *testing.T) {
+	tests := []struct {
+		name  string
+		queue interface {
+			pqueue.Queue[int, string]
+			WriteDOT(w io.Writer) error
+		}
+		want []string
+	}{
+		{"Binary", pqueue.NewBinary[int, string](), []string{"digraph binary {", `n0 [label="1\n[0]"]`, "n0 -> n1;", "n1 -> n3;"}},
+		{"Pairing", pqueue.NewPairing[int, string](), []string{"digraph pairing {", `[label="child"]`, `[label="sibling", style=dashed]`}},
+		{"Skew", pqueue.NewSkew[int, string](), []string{"digraph skew {", `[label="L"]`, "style=dotted"}},
+		{"SkewBinomial", pqueue.NewSkewBinomial[int, string](), []string{"digraph skewbinomial {", `\nr1"]`, "rank=same"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.queue.WriteDOT(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if s := buf.String(); !strings.HasSuffix(s, "{\n\tnode [shape=circle];\n}\n") {
+				t.Fatalf("empty queue: got %q", s)
+			}
+
+			for _, p := range []int{4, 2, 5, 1, 3} {
+				_ = tt.queue.Push("", p)
+			}
+			buf.Reset()
+			if err := tt.queue.WriteDOT(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			s := buf.String()
+			if n := strings.Count(s, "[label="); n < 5 {
+				t.Fatalf("got %d labels, want at least 5:\n%s", n, s)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(s, want) {
+					t.Fatalf("graph does not contain %q:\n%s", want, s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package test
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+	"github.com/AndrewChon/pqueue/durable"
+)
+
+// openDurable opens the durable queue in dir on a fresh FIFO Pairing heap, failing the test on error.
+func openDurable(t testing.TB, dir string, opts ...durable.Option) *durable.Queue[int, int] {
+	t.Helper()
+
+	q, err := durable.Open[int, int](dir, pqueue.NewPairing[int, int](pqueue.WithTieBreak(pqueue.TieBreakFIFO)), opts...)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return q
+}
+
+// crash reopens the queue in dir without closing the old one, as if the process had crashed.
+func crash(t testing.TB, dir string, opts ...durable.Option) *durable.Queue[int, int] {
+	t.Helper()
+	return openDurable(t, dir, opts...)
+}
+
+// contents pops every element of q, and returns them sorted by priority and then by value.
+func contents(t testing.TB, q *durable.Queue[int, int]) []pqueue.Item[int, int] {
+	t.Helper()
+
+	var items []pqueue.Item[int, int]
+	for {
+		p, v, ok, err := q.PopItem()
+		if err != nil {
+			t.Fatalf("PopItem: %v", err)
+		}
+		if !ok {
+			break
+		}
+		items = append(items, pqueue.Item[int, int]{Priority: p, Value: v})
+	}
+
+	slices.SortFunc(items, func(a, b pqueue.Item[int, int]) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.Value, b.Value))
+	})
+	return items
+}
+
+// logPath returns the path of the only log in dir.
+func logPath(t testing.TB, dir string) string {
+	t.Helper()
+
+	logs, _ := filepath.Glob(filepath.Join(dir, "wal-*"))
+	if len(logs) != 1 {
+		t.Fatalf("expected exactly one log, found %v", logs)
+	}
+	return logs[0]
+}
+
+func fileSize(t testing.TB, path string) int64 {
+	t.Helper()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Size()
+}
+
+func items(kv ...int) []pqueue.Item[int, int] {
+	var out []pqueue.Item[int, int]
+	for i := 0; i < len(kv); i += 2 {
+		out = append(out, pqueue.Item[int, int]{Priority: kv[i], Value: kv[i+1]})
+	}
+	return out
+}
+
+func TestDurableRecovery(t *testing.T) {
+	dir := t.TempDir()
+	q := openDurable(t, dir, durable.WithCheckpointEvery(100))
+
+	// Few distinct priorities make for many ties, which the log must replay in the same order.
+	var want []pqueue.Item[int, int]
+	for i := 0; i < 1000; i++ {
+		switch r := rand.Intn(10); {
+		case r < 6:
+			p, v := rand.Intn(8), rand.Intn(math.MaxInt32)
+			if err := q.Push(v, p); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, pqueue.Item[int, int]{Priority: p, Value: v})
+		case r < 9:
+			p, v, ok, err := q.PopItem()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				i := slices.Index(want, pqueue.Item[int, int]{Priority: p, Value: v})
+				if i < 0 {
+					t.Fatalf("popped (%d, %d), which was never pushed", p, v)
+				}
+				want = slices.Delete(want, i, i+1)
+			}
+		default:
+			other := pqueue.NewBinary[int, int]()
+			for range 5 {
+				p, v := rand.Intn(8), rand.Intn(math.MaxInt32)
+				_ = other.Push(v, p)
+				want = append(want, pqueue.Item[int, int]{Priority: p, Value: v})
+			}
+			if err := q.Meld(other); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	slices.SortFunc(want, func(a, b pqueue.Item[int, int]) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.Value, b.Value))
+	})
+	if got := contents(t, crash(t, dir)); !slices.Equal(got, want) {
+		t.Fatalf("recovered %d elements that do not match the %d expected", len(got), len(want))
+	}
+}
+
+func TestDurableTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	q := openDurable(t, dir)
+	if err := q.Push(1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	path := logPath(t, dir)
+	before := fileSize(t, path)
+	if err := q.Push(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	after := fileSize(t, path)
+
+	// Cut the second record at every possible offset, as if the process had crashed halfway through writing it.
+	full, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cut := before; cut < after; cut++ {
+		if err := os.WriteFile(path, full[:cut], 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		q = crash(t, dir)
+		if size := fileSize(t, path); size != before {
+			t.Fatalf("cut at %d: torn record was not truncated (log is %d bytes, want %d)", cut, size, before)
+		}
+
+		// Records appended after recovery must survive the next recovery too.
+		if err := q.Push(3, 3); err != nil {
+			t.Fatal(err)
+		}
+		if got := contents(t, crash(t, dir)); !slices.Equal(got, items(1, 1, 3, 3)) {
+			t.Fatalf("cut at %d: recovered %v", cut, got)
+		}
+	}
+}
+
+func TestDurableCorruptTail(t *testing.T) {
+	dir := t.TempDir()
+	q := openDurable(t, dir)
+	for i := range 3 {
+		if err := q.Push(i, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Flip a bit in the payload of the last record, as if a sector had only been partially overwritten.
+	path := logPath(t, dir)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 1
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := contents(t, crash(t, dir)); !slices.Equal(got, items(0, 0, 1, 1)) {
+		t.Fatalf("recovered %v", got)
+	}
+}
+
+func TestDurableCheckpointCrash(t *testing.T) {
+	dir := t.TempDir()
+	q := openDurable(t, dir)
+	for i := range 3 {
+		if err := q.Push(i, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := q.Pop(); err != nil {
+		t.Fatal(err)
+	}
+
+	oldPath := logPath(t, dir)
+	oldLog, err := os.ReadFile(oldPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(3, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Put the old log back, as if the process had crashed after the snapshot was written but before the old log was
+	// removed. Its records are already part of the snapshot, and must not be replayed.
+	if err := os.WriteFile(oldPath, oldLog, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	q = crash(t, dir)
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Fatalf("stale log was not removed: %v", err)
+	}
+	if got := contents(t, q); !slices.Equal(got, items(1, 1, 2, 2, 3, 3)) {
+		t.Fatalf("recovered %v", got)
+	}
+}
+
+func TestDurableClose(t *testing.T) {
+	dir := t.TempDir()
+	q := openDurable(t, dir, durable.WithSyncInterval(time.Millisecond))
+	if err := q.Push(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(2, 2); err != pqueue.ErrClosed {
+		t.Fatalf("Push after Close: got %v, want %v", err, pqueue.ErrClosed)
+	}
+
+	if got := contents(t, openDurable(t, dir)); !slices.Equal(got, items(1, 1)) {
+		t.Fatalf("recovered %v", got)
+	}
+}
+
+func BenchmarkDurablePush(b *testing.B) {
+	q := openDurable(b, b.TempDir(), durable.WithSyncPolicy(durable.SyncNever), durable.WithKeyCodec[int](intCodec{}),
+		durable.WithValueCodec[int](intCodec{}))
+	defer q.Close()
+
+	for b.Loop() {
+		_ = q.Push(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+}
+
+func BenchmarkDurablePop(b *testing.B) {
+	q := openDurable(b, b.TempDir(), durable.WithSyncPolicy(durable.SyncNever), durable.WithKeyCodec[int](intCodec{}),
+		durable.WithValueCodec[int](intCodec{}))
+	defer q.Close()
+
+	for i := 0; i < b.N; i++ {
+		_ = q.Push(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = q.Pop()
+	}
+}
+
+func TestDurableMeldRejected(t *testing.T) {
+	dir := t.TempDir()
+	newHeap := func() durable.Heap[int, int] {
+		return pqueue.NewPairing[int, int](pqueue.WithTieBreak(pqueue.TieBreakFIFO),
+			pqueue.WithCapacity(2, pqueue.OverflowError))
+	}
+	q, err := durable.Open[int, int](dir, newHeap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The elements rejected by the queue are pushed back onto other.
+	other := pqueue.NewSkew[int, int]()
+	_ = other.Push(2, 2)
+	_ = other.Push(3, 3)
+	if err := q.Meld(other); !errors.Is(err, pqueue.ErrFull) {
+		t.Fatalf("Meld: got %v, want ErrFull", err)
+	}
+	if other.Size() != 2 || q.Size() != 1 {
+		t.Fatalf("sizes after a failed Meld: got %d and %d, want 2 and 1", other.Size(), q.Size())
+	}
+
+	// If other rejects them too, they are returned in the error.
+	other.Close()
+	err = q.Meld(other)
+	var meldErr *durable.MeldError[int, int]
+	if !errors.As(err, &meldErr) || !errors.Is(err, pqueue.ErrFull) || !errors.Is(err, pqueue.ErrClosed) {
+		t.Fatalf("Meld: got %v, want a MeldError wrapping ErrFull and ErrClosed", err)
+	}
+	slices.SortFunc(meldErr.Items, func(a, b pqueue.Item[int, int]) int { return cmp.Compare(a.Priority, b.Priority) })
+	if want := []pqueue.Item[int, int]{{Priority: 2, Value: 2}, {Priority: 3, Value: 3}}; !slices.Equal(meldErr.Items, want) {
+		t.Fatalf("MeldError.Items: got %v, want %v", meldErr.Items, want)
+	}
+
+	// The failed melds are logged, but fail again when they are replayed.
+	if q, err = durable.Open[int, int](dir, newHeap()); err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(t, q); !slices.Equal(got, []pqueue.Item[int, int]{{Priority: 1, Value: 1}}) {
+		t.Fatalf("recovered %v, want only (1, 1)", got)
+	}
+}
+
+func TestDurableTieBreak(t *testing.T) {
+	if _, err := durable.Open[int, int](t.TempDir(), pqueue.NewPairing[int, int]()); !errors.Is(err, durable.ErrTieBreak) {
+		t.Fatalf("Open: got %v, want ErrTieBreak", err)
+	}
+}
+
+func TestDurableEvictions(t *testing.T) {
+	dir := t.TempDir()
+	newHeap := func() durable.Heap[int, int] {
+		return pqueue.NewPairing[int, int](pqueue.WithTieBreak(pqueue.TieBreakFIFO),
+			pqueue.WithCapacity(3, pqueue.OverflowEvictMax))
+	}
+	q, err := durable.Open[int, int](dir, newHeap())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both evictions pick between elements with equal priorities, which replaying the log must pick the same way.
+	for _, v := range []int{10, 11, 12} {
+		if err := q.Push(v, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Push(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	other := pqueue.NewBinary[int, int]()
+	_ = other.Push(0, 0)
+	if err := q.Meld(other); err != nil {
+		t.Fatal(err)
+	}
+
+	if q, err = durable.Open[int, int](dir, newHeap()); err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(t, q); !slices.Equal(got, items(0, 0, 1, 1, 5, 10)) {
+		t.Fatalf("recovered %v, want (0, 0), (1, 1) and (5, 10)", got)
+	}
+}
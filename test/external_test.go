@@ -0,0 +1,74 @@
+package test
+
+import (
+	"cmp"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// externalLimit keeps the in-memory heap small enough that the benchmarks spill runs to disk.
+const externalLimit = 1 << 14
+
+func TestExternalMergeRuns(t *testing.T) {
+	dir := t.TempDir()
+	q := pqueue.NewExternal[int, int](1, pqueue.WithSpillDir(dir), pqueue.WithTieBreak(pqueue.TieBreakFIFO),
+		pqueue.WithKeyCodec[int](intCodec{}), pqueue.WithValueCodec[int](intCodec{}))
+	defer q.Clear()
+
+	// Every push past the first spills a run, so the runs must be merged to stay below the limit. Few distinct
+	// priorities make for many ties, which merging must keep in the order they were pushed.
+	for i := range 1000 {
+		if err := q.Push(i, rand.Intn(8)); err != nil {
+			t.Fatal(err)
+		}
+		if runs := q.Runs(); runs > 64 {
+			t.Fatalf("after %d pushes: %d runs", i+1, runs)
+		}
+	}
+
+	var got []pqueue.Item[int, int]
+	for p, v := range q.Drain() {
+		got = append(got, pqueue.Item[int, int]{Priority: p, Value: v})
+	}
+	if err := q.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1000 || !slices.IsSortedFunc(got, func(a, b pqueue.Item[int, int]) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.Value, b.Value))
+	}) {
+		t.Fatalf("popped %d elements out of order", len(got))
+	}
+	if files, _ := os.ReadDir(dir); len(files) > 0 {
+		t.Fatalf("%d runs were left behind", len(files))
+	}
+}
+
+func BenchmarkExternalPush(b *testing.B) {
+	q := pqueue.NewExternal[int, int](externalLimit, pqueue.WithSpillDir(b.TempDir()),
+		pqueue.WithKeyCodec[int](intCodec{}), pqueue.WithValueCodec[int](intCodec{}))
+	defer q.Clear()
+
+	for b.Loop() {
+		q.Push(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+}
+
+func BenchmarkExternalPop(b *testing.B) {
+	q := pqueue.NewExternal[int, int](externalLimit, pqueue.WithSpillDir(b.TempDir()),
+		pqueue.WithKeyCodec[int](intCodec{}), pqueue.WithValueCodec[int](intCodec{}))
+	defer q.Clear()
+
+	for i := 0; i < b.N; i++ {
+		q.Push(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Pop()
+	}
+}
@@ -0,0 +1,659 @@
+package test
+
+import (
+	"cmp"
+	"context"
+	"encoding"
+	"encoding/json"
+	"iter"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// heap is implemented by every heap-based queue.
+type heap interface {
+	pqueue.Queue[int, string]
+	PushMany(items []pqueue.Item[int, string]) error
+	PushManyWait(ctx context.Context, items []pqueue.Item[int, string]) error
+	PopN(n int) []pqueue.Item[int, string]
+	All() iter.Seq2[int, string]
+	PushHandle(v string, priority int) (*pqueue.Handle[int, string], error)
+	PushHandleWait(ctx context.Context, v string, priority int) (*pqueue.Handle[int, string], error)
+	Update(h *pqueue.Handle[int, string], priority int) bool
+	Remove(h *pqueue.Handle[int, string]) bool
+	Validate() error
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	json.Marshaler
+	json.Unmarshaler
+}
+
+// heapKind holds the constructors of a heap-based queue.
+type heapKind struct {
+	name     string
+	new      func(opts ...pqueue.Option) heap
+	newFunc  func(less func(a, b int) bool, opts ...pqueue.Option) heap
+	newFrom  func(items []pqueue.Item[int, string], opts ...pqueue.Option) heap
+	fromFunc func(items []pqueue.Item[int, string], less func(a, b int) bool, opts ...pqueue.Option) heap
+	// meld calls Meld, which only accepts queues of the same type.
+	meld func(q, other heap) error
+	// cloneFunc calls CloneFunc, which returns a queue of the same type.
+	cloneFunc func(q heap, copyValue func(string) string) heap
+}
+
+var heapKinds = []heapKind{
+	{
+		name: "Binary",
+		new:  func(opts ...pqueue.Option) heap { return pqueue.NewBinary[int, string](opts...) },
+		newFunc: func(less func(a, b int) bool, opts ...pqueue.Option) heap {
+			return pqueue.NewBinaryFunc[int, string](less, opts...)
+		},
+		newFrom: func(items []pqueue.Item[int, string], opts ...pqueue.Option) heap {
+			return pqueue.NewBinaryFrom(items, opts...)
+		},
+		fromFunc: func(items []pqueue.Item[int, string], less func(a, b int) bool, opts ...pqueue.Option) heap {
+			return pqueue.NewBinaryFromFunc(items, less, opts...)
+		},
+		meld: func(q, other heap) error {
+			return q.(*pqueue.Binary[int, string]).Meld(other.(*pqueue.Binary[int, string]))
+		},
+		cloneFunc: func(q heap, copyValue func(string) string) heap {
+			return q.(*pqueue.Binary[int, string]).CloneFunc(copyValue)
+		},
+	},
+	{
+		name: "Pairing",
+		new:  func(opts ...pqueue.Option) heap { return pqueue.NewPairing[int, string](opts...) },
+		newFunc: func(less func(a, b int) bool, opts ...pqueue.Option) heap {
+			return pqueue.NewPairingFunc[int, string](less, opts...)
+		},
+		newFrom: func(items []pqueue.Item[int, string], opts ...pqueue.Option) heap {
+			return pqueue.NewPairingFrom(items, opts...)
+		},
+		fromFunc: func(items []pqueue.Item[int, string], less func(a, b int) bool, opts ...pqueue.Option) heap {
+			return pqueue.NewPairingFromFunc(items, less, opts...)
+		},
+		meld: func(q, other heap) error {
+			return q.(*pqueue.Pairing[int, string]).Meld(other.(*pqueue.Pairing[int, string]))
+		},
+		cloneFunc: func(q heap, copyValue func(string) string) heap {
+			return q.(*pqueue.Pairing[int, string]).CloneFunc(copyValue)
+		},
+	},
+	{
+		name: "Skew",
+		new:  func(opts ...pqueue.Option) heap { return pqueue.NewSkew[int, string](opts...) },
+		newFunc: func(less func(a, b int) bool, opts ...pqueue.Option) heap {
+			return pqueue.NewSkewFunc[int, string](less, opts...)
+		},
+		newFrom: func(items []pqueue.Item[int, string], opts ...pqueue.Option) heap {
+			return pqueue.NewSkewFrom(items, opts...)
+		},
+		fromFunc: func(items []pqueue.Item[int, string], less func(a, b int) bool, opts ...pqueue.Option) heap {
+			return pqueue.NewSkewFromFunc(items, less, opts...)
+		},
+		meld: func(q, other heap) error {
+			return q.(*pqueue.Skew[int, string]).Meld(other.(*pqueue.Skew[int, string]))
+		},
+		cloneFunc: func(q heap, copyValue func(string) string) heap {
+			return q.(*pqueue.Skew[int, string]).CloneFunc(copyValue)
+		},
+	},
+	{
+		name: "SkewBinomial",
+		new:  func(opts ...pqueue.Option) heap { return pqueue.NewSkewBinomial[int, string](opts...) },
+		newFunc: func(less func(a, b int) bool, opts ...pqueue.Option) heap {
+			return pqueue.NewSkewBinomialFunc[int, string](less, opts...)
+		},
+		newFrom: func(items []pqueue.Item[int, string], opts ...pqueue.Option) heap {
+			return pqueue.NewSkewBinomialFrom(items, opts...)
+		},
+		fromFunc: func(items []pqueue.Item[int, string], less func(a, b int) bool, opts ...pqueue.Option) heap {
+			return pqueue.NewSkewBinomialFromFunc(items, less, opts...)
+		},
+		meld: func(q, other heap) error {
+			return q.(*pqueue.SkewBinomial[int, string]).Meld(other.(*pqueue.SkewBinomial[int, string]))
+		},
+		cloneFunc: func(q heap, copyValue func(string) string) heap {
+			return q.(*pqueue.SkewBinomial[int, string]).CloneFunc(copyValue)
+		},
+	},
+}
+
+// expectPops pops every element of q and checks that the values come out in the wanted order.
+func expectPops(t *testing.T, q pqueue.Queue[int, string], want ...string) {
+	t.Helper()
+
+	var got []string
+	for v, ok := q.Pop(); ok; v, ok = q.Pop() {
+		got = append(got, v)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("pops: got %q, want %q", got, want)
+	}
+}
+
+func TestHandles(t *testing.T) {
+	for _, kind := range heapKinds {
+		t.Run(kind.name, func(t *testing.T) {
+			q := kind.new()
+
+			handles := make(map[string]*pqueue.Handle[int, string])
+			for i, v := range []string{"a", "b", "c", "d", "e", "f"} {
+				h, err := q.PushHandle(v, (i+1)*10)
+				if err != nil || h == nil {
+					t.Fatalf("PushHandle(%q): got %v, %v", v, h, err)
+				}
+				handles[v] = h
+			}
+
+			// Decrease, increase, and remove elements.
+			if !q.Update(handles["e"], 5) || !q.Update(handles["a"], 45) || !q.Remove(handles["c"]) {
+				t.Fatal("Update or Remove of a live handle failed")
+			}
+
+			if v, ok := q.Pop(); !ok || v != "e" {
+				t.Fatalf("Pop: got %q, want \"e\"", v)
+			}
+
+			// Handles to elements that were popped or removed are stale.
+			for _, v := range []string{"e", "c"} {
+				if q.Update(handles[v], 1) {
+					t.Fatalf("Update of stale handle %q succeeded", v)
+				}
+				if q.Remove(handles[v]) {
+					t.Fatalf("Remove of stale handle %q succeeded", v)
+				}
+			}
+			if q.Update(nil, 1) || q.Remove(nil) {
+				t.Fatal("Update or Remove of a nil handle succeeded")
+			}
+
+			// Handles do not refer to elements of other queues.
+			other := kind.new()
+			if other.Update(handles["b"], 1) || other.Remove(handles["b"]) {
+				t.Fatal("handle was accepted by another queue")
+			}
+
+			expectPops(t, q, "b", "d", "a", "f")
+			if q.Update(handles["f"], 1) || q.Remove(handles["f"]) {
+				t.Fatal("handle to the last popped element is still live")
+			}
+		})
+	}
+}
+
+func TestLessFunc(t *testing.T) {
+	tests := []struct {
+		name string
+		less func(a, b int) bool
+		want []string
+	}{
+		{"Max", func(a, b int) bool { return a > b }, []string{"c", "f", "b", "e", "a", "d"}},
+		{"Abs", func(a, b int) bool { return abs(a) < abs(b) }, []string{"e", "b", "a", "f", "d", "c"}},
+	}
+
+	for k, kind := range heapKinds {
+		for _, tt := range tests {
+			t.Run(kind.name+"/"+tt.name, func(t *testing.T) {
+				q := kind.newFunc(tt.less)
+				for i, p := range []int{-4, 2, 9, -7, 1} {
+					_ = q.Push(string(rune('a'+i)), p)
+				}
+
+				// Melding in a queue of another kind must keep the order.
+				other := heapKinds[(k+1)%len(heapKinds)].newFunc(tt.less)
+				_ = other.Push("f", 5)
+				if err := q.CrossMeld(other); err != nil {
+					t.Fatal(err)
+				}
+
+				expectPops(t, q, tt.want...)
+			})
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func TestTieBreak(t *testing.T) {
+	tests := []struct {
+		tieBreak pqueue.TieBreak
+		name     string
+		want     []string
+	}{
+		{pqueue.TieBreakFIFO, "FIFO", []string{"a", "c", "e", "b", "d", "f"}},
+		{pqueue.TieBreakLIFO, "LIFO", []string{"e", "c", "a", "f", "d", "b"}},
+	}
+
+	// push pushes the elements a to f onto q and other, alternating between priorities 1 and 2.
+	push := func(t *testing.T, q, other heap) {
+		t.Helper()
+		for i, v := range []string{"a", "b", "c", "d", "e", "f"} {
+			dst := q
+			if i >= 3 {
+				dst = other
+			}
+			if err := dst.Push(v, i%2+1); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for k, kind := range heapKinds {
+		next := heapKinds[(k+1)%len(heapKinds)]
+
+		for _, tt := range tests {
+			t.Run(kind.name+"/"+tt.name, func(t *testing.T) {
+				t.Run("Push", func(t *testing.T) {
+					q := kind.new(pqueue.WithTieBreak(tt.tieBreak))
+					push(t, q, q)
+					expectPops(t, q, tt.want...)
+				})
+
+				t.Run("Meld", func(t *testing.T) {
+					q, other := kind.new(pqueue.WithTieBreak(tt.tieBreak)), kind.new(pqueue.WithTieBreak(tt.tieBreak))
+					push(t, q, other)
+					if err := kind.meld(q, other); err != nil {
+						t.Fatal(err)
+					}
+					expectPops(t, q, tt.want...)
+				})
+
+				t.Run("CrossMeld", func(t *testing.T) {
+					q, other := kind.new(pqueue.WithTieBreak(tt.tieBreak)), next.new(pqueue.WithTieBreak(tt.tieBreak))
+					push(t, q, other)
+					if err := q.CrossMeld(other); err != nil {
+						t.Fatal(err)
+					}
+					expectPops(t, q, tt.want...)
+				})
+
+				t.Run("MarshalBinary", func(t *testing.T) {
+					q := kind.new(pqueue.WithTieBreak(tt.tieBreak))
+					push(t, q, q)
+					data, err := q.MarshalBinary()
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					restored := next.new(pqueue.WithTieBreak(tt.tieBreak))
+					if err := restored.UnmarshalBinary(data); err != nil {
+						t.Fatal(err)
+					}
+					expectPops(t, restored, tt.want...)
+				})
+
+				t.Run("MarshalJSON", func(t *testing.T) {
+					q := kind.new(pqueue.WithTieBreak(tt.tieBreak))
+					push(t, q, q)
+					data, err := q.MarshalJSON()
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					restored := next.new(pqueue.WithTieBreak(tt.tieBreak))
+					if err := restored.UnmarshalJSON(data); err != nil {
+						t.Fatal(err)
+					}
+
+					// Elements pushed after a restore are ordered after the restored ones.
+					_ = restored.Push("g", 1)
+					want := slices.Insert(slices.Clone(tt.want), 3, "g")
+					if tt.tieBreak == pqueue.TieBreakLIFO {
+						want = slices.Insert(slices.Clone(tt.want), 0, "g")
+					}
+					expectPops(t, restored, want...)
+				})
+			})
+		}
+	}
+}
+
+func TestPeekItem(t *testing.T) {
+	for _, kind := range heapKinds {
+		t.Run(kind.name, func(t *testing.T) {
+			q := kind.new()
+			if p, v, ok := q.PeekItem(); ok || p != 0 || v != "" {
+				t.Fatalf("PeekItem on empty queue: got %d, %q, %v", p, v, ok)
+			}
+			if p, v, ok := q.PopItem(); ok || p != 0 || v != "" {
+				t.Fatalf("PopItem on empty queue: got %d, %q, %v", p, v, ok)
+			}
+
+			// The zero value is a valid element, told apart from an empty queue by ok.
+			_ = q.Push("", 0)
+			_ = q.Push("b", 2)
+			if p, v, ok := q.PeekItem(); !ok || p != 0 || v != "" {
+				t.Fatalf("PeekItem: got %d, %q, %v, want 0, \"\", true", p, v, ok)
+			}
+			if p, v, ok := q.PopItem(); !ok || p != 0 || v != "" {
+				t.Fatalf("PopItem: got %d, %q, %v, want 0, \"\", true", p, v, ok)
+			}
+			if p, v, ok := q.PopItem(); !ok || p != 2 || v != "b" {
+				t.Fatalf("PopItem: got %d, %q, %v, want 2, \"b\", true", p, v, ok)
+			}
+			if _, _, ok := q.PopItem(); ok || q.Size() != 0 {
+				t.Fatalf("PopItem on drained queue: got ok %v, size %d", ok, q.Size())
+			}
+		})
+	}
+
+	t.Run("CircularBuffer", func(t *testing.T) {
+		q := pqueue.NewCircularBuffer[int]()
+		if v, ok := q.PeekItem(); ok || v != 0 {
+			t.Fatalf("PeekItem on empty buffer: got %d, %v", v, ok)
+		}
+
+		_ = q.Push(0)
+		if v, ok := q.PeekItem(); !ok || v != 0 || q.Size() != 1 {
+			t.Fatalf("PeekItem: got %d, %v, size %d", v, ok, q.Size())
+		}
+		if v, ok := q.Pop(); !ok || v != 0 {
+			t.Fatalf("Pop: got %d, %v", v, ok)
+		}
+		if _, ok := q.PeekItem(); ok {
+			t.Fatal("PeekItem on drained buffer succeeded")
+		}
+	})
+}
+
+func TestBulk(t *testing.T) {
+	const n = 1000
+
+	items := make([]pqueue.Item[int, string], n)
+	for i := range items {
+		p := rand.Intn(n / 4)
+		items[i] = pqueue.Item[int, string]{Priority: p, Value: strconv.Itoa(p)}
+	}
+
+	// popAll pops q in batches of the provided size and checks that the priorities come out in the order of sorted.
+	popAll := func(t *testing.T, q heap, batch int, sorted []pqueue.Item[int, string]) {
+		t.Helper()
+
+		var got []pqueue.Item[int, string]
+		for q.Size() > 0 {
+			popped := q.PopN(batch)
+			if len(popped) != min(batch, len(sorted)-len(got)) {
+				t.Fatalf("PopN(%d): got %d items with %d left", batch, len(popped), len(sorted)-len(got))
+			}
+			got = append(got, popped...)
+		}
+
+		if len(got) != len(sorted) {
+			t.Fatalf("got %d items, want %d", len(got), len(sorted))
+		}
+		for i := range got {
+			if got[i].Priority != sorted[i].Priority || got[i].Value != strconv.Itoa(got[i].Priority) {
+				t.Fatalf("item %d: got %v, want priority %d", i, got[i], sorted[i].Priority)
+			}
+		}
+	}
+
+	ascending := slices.SortedFunc(slices.Values(items), func(a, b pqueue.Item[int, string]) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+	descending := slices.Clone(ascending)
+	slices.Reverse(descending)
+
+	for _, kind := range heapKinds {
+		t.Run(kind.name, func(t *testing.T) {
+			t.Run("From", func(t *testing.T) {
+				q := kind.newFrom(items)
+				if q.Size() != n {
+					t.Fatalf("Size: got %d, want %d", q.Size(), n)
+				}
+				if err := q.Validate(); err != nil {
+					t.Fatal(err)
+				}
+				popAll(t, q, 7, ascending)
+			})
+
+			t.Run("FromFunc", func(t *testing.T) {
+				q := kind.fromFunc(items, func(a, b int) bool { return a > b })
+				if err := q.Validate(); err != nil {
+					t.Fatal(err)
+				}
+				popAll(t, q, n, descending)
+			})
+
+			t.Run("PushMany", func(t *testing.T) {
+				q := kind.new()
+				for _, item := range items[:n/2] {
+					_ = q.Push(item.Value, item.Priority)
+				}
+				if err := q.PushMany(items[n/2:]); err != nil {
+					t.Fatal(err)
+				}
+				if err := q.PushMany(nil); err != nil {
+					t.Fatal(err)
+				}
+				if err := q.Validate(); err != nil {
+					t.Fatal(err)
+				}
+				popAll(t, q, 64, ascending)
+			})
+
+			t.Run("PopN", func(t *testing.T) {
+				q := kind.newFrom(nil)
+				if got := q.PopN(3); len(got) != 0 {
+					t.Fatalf("PopN on empty queue: got %v", got)
+				}
+
+				_ = q.PushMany(items[:3])
+				if got := q.PopN(0); len(got) != 0 || q.Size() != 3 {
+					t.Fatalf("PopN(0): got %v, size %d", got, q.Size())
+				}
+				if got := q.PopN(-1); len(got) != 0 || q.Size() != 3 {
+					t.Fatalf("PopN(-1): got %v, size %d", got, q.Size())
+				}
+				if got := q.PopN(10); len(got) != 3 || q.Size() != 0 {
+					t.Fatalf("PopN(10): got %v, size %d", got, q.Size())
+				}
+			})
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	for _, kind := range heapKinds {
+		t.Run(kind.name, func(t *testing.T) {
+			q := kind.new(pqueue.WithTieBreak(pqueue.TieBreakFIFO))
+			handles := make(map[string]*pqueue.Handle[int, string])
+			for i, v := range []string{"a", "b", "c", "d"} {
+				handles[v], _ = q.PushHandle(v, i)
+			}
+
+			clone := kind.cloneFunc(q, nil)
+			upper := kind.cloneFunc(q, strings.ToUpper)
+
+			// Changes to the original do not show in the clones.
+			if !q.Update(handles["a"], 10) || !q.Remove(handles["b"]) {
+				t.Fatal("Update or Remove of a live handle failed")
+			}
+			_ = q.Push("e", 0)
+
+			// Handles from the original do not refer to the clones' elements.
+			if clone.Update(handles["c"], -1) || clone.Remove(handles["d"]) {
+				t.Fatal("handle from the original was accepted by the clone")
+			}
+
+			// Changes to the clone do not show in the original.
+			_ = clone.Push("f", 1)
+			if err := clone.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			expectPops(t, q, "e", "c", "d", "a")
+			expectPops(t, clone, "a", "b", "f", "c", "d")
+			expectPops(t, upper, "A", "B", "C", "D")
+		})
+	}
+}
+
+// randomPriority returns a random priority for the benchmarks.
+func randomPriority() int {
+	return rand.Intn(math.MaxInt64)
+}
+
+func BenchmarkPush(b *testing.B) {
+	for _, kind := range heapKinds {
+		b.Run(kind.name, func(b *testing.B) {
+			q := kind.new()
+
+			for b.Loop() {
+				q.Push("", randomPriority())
+			}
+		})
+	}
+}
+
+func BenchmarkFrom(b *testing.B) {
+	for _, kind := range heapKinds {
+		b.Run(kind.name, func(b *testing.B) {
+			items := make([]pqueue.Item[int, string], b.N)
+			for i := range items {
+				items[i] = pqueue.Item[int, string]{Priority: randomPriority()}
+			}
+
+			b.ResetTimer()
+			kind.newFrom(items)
+		})
+	}
+}
+
+func BenchmarkMeld(b *testing.B) {
+	for _, kind := range heapKinds {
+		b.Run(kind.name, func(b *testing.B) {
+			qa, qb := kind.new(), kind.new()
+
+			for b.Loop() {
+				qa.Push("", randomPriority())
+				qb.Push("", randomPriority())
+			}
+
+			b.ResetTimer()
+			b.StartTimer()
+			kind.meld(qa, qb)
+			b.StopTimer()
+
+			b.ReportMetric(b.Elapsed().Seconds(), "s/total")
+		})
+	}
+}
+
+func BenchmarkCrossMeld(b *testing.B) {
+	for k, kind := range heapKinds {
+		next := heapKinds[(k+1)%len(heapKinds)]
+
+		b.Run(kind.name, func(b *testing.B) {
+			qa, qb := kind.new(), next.new()
+
+			for b.Loop() {
+				qa.Push("", randomPriority())
+				qb.Push("", randomPriority())
+			}
+
+			b.ResetTimer()
+			b.StartTimer()
+			qa.CrossMeld(qb)
+			b.StopTimer()
+
+			b.ReportMetric(b.Elapsed().Seconds(), "s/total")
+		})
+	}
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	for _, kind := range heapKinds {
+		b.Run(kind.name, func(b *testing.B) {
+			q := kind.new()
+
+			handles := make([]*pqueue.Handle[int, string], b.N)
+			for i := 0; i < b.N; i++ {
+				handles[i], _ = q.PushHandle("", randomPriority())
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				q.Update(handles[i], randomPriority())
+			}
+		})
+	}
+}
+
+func BenchmarkPop(b *testing.B) {
+	for _, kind := range heapKinds {
+		b.Run(kind.name, func(b *testing.B) {
+			q := kind.new()
+
+			for i := 0; i < b.N; i++ {
+				q.Push("", randomPriority())
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				q.Pop()
+			}
+		})
+	}
+}
+
+func BenchmarkAll(b *testing.B) {
+	for _, kind := range heapKinds {
+		b.Run(kind.name, func(b *testing.B) {
+			q := kind.new()
+
+			for i := 0; i < b.N; i++ {
+				q.Push("", randomPriority())
+			}
+
+			b.ResetTimer()
+			for range q.All() {
+			}
+		})
+	}
+}
+
+func BenchmarkClone(b *testing.B) {
+	for _, kind := range heapKinds {
+		b.Run(kind.name, func(b *testing.B) {
+			q := kind.new()
+
+			for i := 0; i < b.N; i++ {
+				q.Push("", randomPriority())
+			}
+
+			b.ResetTimer()
+			kind.cloneFunc(q, nil)
+		})
+	}
+}
+
+func BenchmarkMarshalBinary(b *testing.B) {
+	for _, kind := range heapKinds {
+		b.Run(kind.name, func(b *testing.B) {
+			q := kind.new(pqueue.WithKeyCodec[int](intCodec{}))
+
+			for i := 0; i < b.N; i++ {
+				q.Push("", randomPriority())
+			}
+
+			b.ResetTimer()
+			if _, err := q.MarshalBinary(); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
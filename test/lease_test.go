@@ -0,0 +1,158 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+func TestLeaserAck(t *testing.T) {
+	q := pqueue.NewPairing[int, string]()
+	lr := pqueue.NewLeaser[int, string](q, pqueue.WithClock(newManualClock()))
+	defer lr.Close()
+
+	_ = q.Push("a", 1)
+	r, ok := lr.Lease(time.Minute)
+	if !ok || r.Value != "a" {
+		t.Fatalf("Lease: got %q, %v, want \"a\"", r.Value, ok)
+	}
+	if _, ok := lr.Lease(time.Minute); ok {
+		t.Fatal("leased element is still visible")
+	}
+
+	if err := lr.Ack(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := lr.Ack(r); err != pqueue.ErrLeaseExpired {
+		t.Fatalf("second Ack: got %v, want %v", err, pqueue.ErrLeaseExpired)
+	}
+	if n := lr.Leased(); n != 0 {
+		t.Fatalf("Leased: got %d, want 0", n)
+	}
+}
+
+func TestLeaserSettleExpired(t *testing.T) {
+	clock := newManualClock()
+	q := pqueue.NewPairing[int, string]()
+	lr := pqueue.NewLeaser[int, string](q, pqueue.WithClock(clock))
+	defer lr.Close()
+
+	_ = q.Push("a", 1)
+	_ = q.Push("b", 2)
+	ra, _ := lr.Lease(time.Minute)
+	rb, _ := lr.Lease(time.Minute)
+
+	// Both leases are past their deadline, but have yet to be reclaimed by the reaping goroutine. Settling them must not
+	// succeed, and must return their elements to the queue.
+	clock.Skip(time.Minute)
+	if err := lr.Ack(ra); err != pqueue.ErrLeaseExpired {
+		t.Fatalf("Ack after deadline: got %v, want %v", err, pqueue.ErrLeaseExpired)
+	}
+	if err := lr.Nack(rb, 0); err != pqueue.ErrLeaseExpired {
+		t.Fatalf("Nack after deadline: got %v, want %v", err, pqueue.ErrLeaseExpired)
+	}
+	expectPops(t, q, "a", "b")
+}
+
+func TestLeaserExpiry(t *testing.T) {
+	clock := newManualClock()
+	q := pqueue.NewPairing[int, string]()
+	lr := pqueue.NewLeaser[int, string](q, pqueue.WithClock(clock))
+	defer lr.Close()
+
+	_ = q.Push("a", 1)
+	r, _ := lr.Lease(time.Minute)
+
+	// The reaping goroutine must push the element back once the lease expires, even if the Leaser is not used again.
+	clock.awaitTimer(t)
+	clock.Advance(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if p, v, err := q.PopItemWait(ctx); err != nil || p != 1 || v != "a" {
+		t.Fatalf("PopItemWait after expiry: got %d, %q, %v", p, v, err)
+	}
+	if err := lr.Ack(r); err != pqueue.ErrLeaseExpired {
+		t.Fatalf("Ack after expiry: got %v, want %v", err, pqueue.ErrLeaseExpired)
+	}
+}
+
+func TestLeaserNack(t *testing.T) {
+	q := pqueue.NewPairing[int, string]()
+	lr := pqueue.NewLeaser[int, string](q, pqueue.WithClock(newManualClock()))
+	defer lr.Close()
+
+	_ = q.Push("a", 1)
+	_ = q.Push("b", 2)
+	r, _ := lr.Lease(time.Minute)
+	if err := lr.Nack(r, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, _ := lr.Lease(time.Minute); r.Value != "b" {
+		t.Fatalf("Lease after Nack: got %q, want \"b\"", r.Value)
+	}
+	if r, _ := lr.Lease(time.Minute); r.Value != "a" || r.Priority != 3 {
+		t.Fatalf("Lease after Nack: got %q with priority %d, want \"a\" with priority 3", r.Value, r.Priority)
+	}
+}
+
+func TestLeaserClose(t *testing.T) {
+	q := pqueue.NewPairing[int, string]()
+	lr := pqueue.NewLeaser[int, string](q, pqueue.WithClock(newManualClock()))
+
+	_ = q.Push("a", 1)
+	lr.Lease(time.Minute)
+	lr.Close()
+	lr.Close()
+
+	if v, ok := q.Pop(); !ok || v != "a" {
+		t.Fatalf("Pop after Close: got %q, %v, want \"a\"", v, ok)
+	}
+}
+
+func TestLeaserLeaseAfterClose(t *testing.T) {
+	q := pqueue.NewPairing[int, string]()
+	lr := pqueue.NewLeaser[int, string](q, pqueue.WithClock(newManualClock()))
+
+	blocked := popWait(func() (pqueue.Receipt[int, string], error) {
+		return lr.LeaseWait(context.Background(), time.Minute)
+	})
+	lr.Close()
+	if res := await(t, blocked); !errors.Is(res.err, pqueue.ErrClosed) {
+		t.Fatalf("blocked LeaseWait: got %v, want ErrClosed", res.err)
+	}
+
+	// Nothing reaps leases once the Leaser is closed, so none may be granted.
+	_ = q.Push("a", 1)
+	if r, ok := lr.Lease(time.Minute); ok {
+		t.Fatalf("Lease after Close: got %q", r.Value)
+	}
+	if _, err := lr.LeaseWait(context.Background(), time.Minute); !errors.Is(err, pqueue.ErrClosed) {
+		t.Fatalf("LeaseWait after Close: got %v, want ErrClosed", err)
+	}
+	if v, ok := q.Pop(); !ok || v != "a" {
+		t.Fatalf("Pop after Close: got %q, %v, want \"a\"", v, ok)
+	}
+}
+
+func BenchmarkLeaserLeaseAck(b *testing.B) {
+	q := pqueue.NewPairing[int, int]()
+	lr := pqueue.NewLeaser[int, int](q, pqueue.WithClock(newManualClock()))
+	defer lr.Close()
+
+	for i := 0; i < b.N; i++ {
+		q.Push(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, _ := lr.Lease(time.Minute)
+		lr.Ack(r)
+	}
+}
@@ -0,0 +1,278 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+	"github.com/AndrewChon/pqueue/expvarmetrics"
+)
+
+// readMetrics decodes the variables published by m.
+func readMetrics(t *testing.T, m *expvarmetrics.Metrics) map[string]json.RawMessage {
+	t.Helper()
+
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(m.Map().String()), &vars); err != nil {
+		t.Fatalf("metrics are not valid JSON: %v", err)
+	}
+	return vars
+}
+
+func expectMetric(t *testing.T, vars map[string]json.RawMessage, name string, want int) {
+	t.Helper()
+
+	if got := string(vars[name]); got != strconv.Itoa(want) {
+		t.Fatalf("%s: got %s, want %d", name, got, want)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := expvarmetrics.NewUnpublished()
+	q := pqueue.NewPairing[int, string](pqueue.WithMetrics(m))
+	other := pqueue.NewPairing[int, string]()
+
+	_ = q.Push("a", 1)
+	_ = q.Push("b", 2)
+	_ = other.Push("c", 3)
+	_ = q.Meld(other)
+	q.Pop()
+
+	vars := readMetrics(t, m)
+	expectMetric(t, vars, "pushes", 2)
+	expectMetric(t, vars, "pops", 1)
+	expectMetric(t, vars, "melds", 1)
+	expectMetric(t, vars, "melded", 1)
+	expectMetric(t, vars, "depth", 2)
+
+	q.Clear()
+	vars = readMetrics(t, m)
+	expectMetric(t, vars, "clears", 1)
+	expectMetric(t, vars, "depth", 0)
+
+	var waited struct {
+		Buckets map[string]int
+		Count   int
+	}
+	if err := json.Unmarshal(vars["waited"], &waited); err != nil {
+		t.Fatal(err)
+	}
+	if waited.Count != 1 || len(waited.Buckets) != 17 {
+		t.Fatalf("waited: got %d observations in %d buckets, want 1 in 17", waited.Count, len(waited.Buckets))
+	}
+}
+
+func TestMetricsCircularBuffer(t *testing.T) {
+	m := expvarmetrics.NewUnpublished()
+	cb := pqueue.NewCircularBuffer[int](pqueue.WithMetrics(m), pqueue.WithCapacity(2, pqueue.OverflowOverwriteOldest))
+
+	for i := range 3 {
+		_ = cb.Push(i)
+	}
+	cb.Pop()
+
+	vars := readMetrics(t, m)
+	expectMetric(t, vars, "pushes", 3)
+	expectMetric(t, vars, "pops", 1)
+	expectMetric(t, vars, "depth", 1)
+
+	// Cloning and decoding the buffer are not pushes.
+	cb.Clone()
+	data, err := pqueue.CollectCircularBuffer(slices.Values([]int{7, 8})).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cb.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	vars = readMetrics(t, m)
+	expectMetric(t, vars, "pushes", 3)
+	expectMetric(t, vars, "depth", 2)
+}
+
+func TestMetricsClone(t *testing.T) {
+	for _, kind := range heapKinds {
+		t.Run(kind.name, func(t *testing.T) {
+			m := expvarmetrics.NewUnpublished()
+			q := kind.new(pqueue.WithMetrics(m))
+			fill(t, q, 1, 2)
+
+			// The clone does not report to the original's Metrics.
+			clone := kind.cloneFunc(q, nil)
+			fill(t, clone, 3)
+			clone.Pop()
+
+			vars := readMetrics(t, m)
+			expectMetric(t, vars, "pushes", 2)
+			expectMetric(t, vars, "pops", 0)
+			expectMetric(t, vars, "depth", 2)
+		})
+	}
+
+	t.Run("CircularBuffer", func(t *testing.T) {
+		m := expvarmetrics.NewUnpublished()
+		cb := pqueue.NewCircularBuffer[int](pqueue.WithMetrics(m))
+		_ = cb.Push(1)
+
+		clone := cb.Clone()
+		_ = clone.Push(2)
+		_ = clone.Push(3)
+
+		vars := readMetrics(t, m)
+		expectMetric(t, vars, "pushes", 1)
+		expectMetric(t, vars, "depth", 1)
+	})
+}
+
+// histogramCount returns the number of durations observed by the histogram published under name.
+func histogramCount(t *testing.T, vars map[string]json.RawMessage, name string) int {
+	t.Helper()
+
+	var h struct{ Count int }
+	if err := json.Unmarshal(vars[name], &h); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return h.Count
+}
+
+func TestMetricsMeld(t *testing.T) {
+	for k, kind := range heapKinds {
+		next := heapKinds[(k+1)%len(heapKinds)]
+
+		t.Run(kind.name, func(t *testing.T) {
+			m := expvarmetrics.NewUnpublished()
+			q := kind.new(pqueue.WithMetrics(m))
+			fill(t, q, 1, 2)
+
+			// Elements melded in from queues without Metrics are treated as pushed when they are melded.
+			other, crossOther := kind.new(), next.new()
+			fill(t, other, 3, 4)
+			fill(t, crossOther, 5)
+			if err := kind.meld(q, other); err != nil {
+				t.Fatal(err)
+			}
+			if err := q.CrossMeld(crossOther); err != nil {
+				t.Fatal(err)
+			}
+
+			vars := readMetrics(t, m)
+			expectMetric(t, vars, "melds", 2)
+			expectMetric(t, vars, "melded", 3)
+			expectMetric(t, vars, "depth", 5)
+
+			// Removing an element counts as popping it.
+			h, _ := q.PushHandle("0", 0)
+			if !q.Remove(h) {
+				t.Fatal("Remove of a live handle failed")
+			}
+			for q.Size() > 0 {
+				q.Pop()
+			}
+
+			vars = readMetrics(t, m)
+			expectMetric(t, vars, "pops", 6)
+			expectMetric(t, vars, "depth", 0)
+			if n := histogramCount(t, vars, "waited"); n != 6 {
+				t.Fatalf("waited: got %d observations, want 6", n)
+			}
+			if n := histogramCount(t, vars, "lockWaited"); n == 0 {
+				t.Fatal("lockWaited: got no observations")
+			}
+		})
+	}
+
+	t.Run("CircularBuffer", func(t *testing.T) {
+		m := expvarmetrics.NewUnpublished()
+		cb := pqueue.NewCircularBuffer[int](pqueue.WithMetrics(m), pqueue.WithCapacity(3, pqueue.OverflowOverwriteOldest))
+		_ = cb.Push(1)
+		_ = cb.Push(2)
+
+		other := pqueue.NewCircularBuffer[int]()
+		_ = other.Push(3)
+		_ = other.Push(4)
+		if err := cb.Meld(other); err != nil {
+			t.Fatal(err)
+		}
+
+		// The depth is reported once the buffer has been trimmed back down to its capacity.
+		vars := readMetrics(t, m)
+		expectMetric(t, vars, "melded", 2)
+		expectMetric(t, vars, "depth", 3)
+
+		for cb.Size() > 0 {
+			cb.Pop()
+		}
+		vars = readMetrics(t, m)
+		expectMetric(t, vars, "pops", 3)
+		if n := histogramCount(t, vars, "waited"); n != 3 {
+			t.Fatalf("waited: got %d observations, want 3", n)
+		}
+	})
+}
+
+func TestHistogram(t *testing.T) {
+	var h expvarmetrics.Histogram
+	for _, d := range []time.Duration{0, time.Microsecond, 4 * time.Microsecond, 5 * time.Microsecond, time.Hour} {
+		h.Observe(d)
+	}
+
+	var got struct {
+		Buckets map[string]int
+		Count   int
+		Sum     float64
+	}
+	if err := json.Unmarshal([]byte(h.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"1µs": 2, "4µs": 1, "16µs": 1, "+Inf": 1}
+	for bucket, n := range want {
+		if got.Buckets[bucket] != n {
+			t.Fatalf("bucket %s: got %d, want %d", bucket, got.Buckets[bucket], n)
+		}
+	}
+	if got.Count != 5 || h.Sum() != time.Hour+10*time.Microsecond {
+		t.Fatalf("got count %d and sum %v", got.Count, h.Sum())
+	}
+}
+
+func BenchmarkMetricsPairingPushPop(b *testing.B) {
+	q := pqueue.NewPairing[int, int](pqueue.WithMetrics(expvarmetrics.NewUnpublished()))
+
+	for i := 0; i < b.N; i++ {
+		q.Push(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Pop()
+	}
+}
+
+func TestMetricsLockWaited(t *testing.T) {
+	for _, kind := range heapKinds {
+		t.Run(kind.name, func(t *testing.T) {
+			m := expvarmetrics.NewUnpublished()
+			q := kind.new(pqueue.WithMetrics(m), pqueue.WithCapacity(1, pqueue.OverflowBlock))
+			fill(t, q, 1)
+
+			// PushWait acquires the lock once to find the queue full, and again once Pop has made room.
+			done := popWait(func() (struct{}, error) { return struct{}{}, q.PushWait(context.Background(), "2", 2) })
+			q.Pop()
+			if res := await(t, done); res.err != nil {
+				t.Fatal(res.err)
+			}
+
+			if n := histogramCount(t, readMetrics(t, m), "lockWaited"); n != 4 {
+				t.Fatalf("lockWaited: got %d observations, want 4", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// recorder is an Observer that records every event as a string.
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) OnPush(priority, value any) {
+	r.events = append(r.events, fmt.Sprintf("push %v %v", priority, value))
+}
+
+func (r *recorder) OnPop(priority, value any) {
+	r.events = append(r.events, fmt.Sprintf("pop %v %v", priority, value))
+}
+
+func (r *recorder) OnMeld(n int) {
+	r.events = append(r.events, fmt.Sprintf("meld %d", n))
+}
+
+func (r *recorder) OnClear(n int) {
+	r.events = append(r.events, fmt.Sprintf("clear %d", n))
+}
+
+func (r *recorder) OnDrop(priority, value any) {
+	r.events = append(r.events, fmt.Sprintf("drop %v %v", priority, value))
+}
+
+func expectEvents(t *testing.T, r *recorder, want ...string) {
+	t.Helper()
+
+	if !slices.Equal(r.events, want) {
+		t.Fatalf("events: got %q, want %q", r.events, want)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	r := &recorder{}
+	q := pqueue.NewSkewBinomial[int, string](pqueue.WithObserver(r), pqueue.WithCapacity(2, pqueue.OverflowEvictMax))
+	other := pqueue.NewSkewBinomial[int, string]()
+
+	_ = q.Push("b", 2)
+	_ = q.Push("c", 3)
+	_ = q.Push("a", 1)
+	_ = q.Push("d", 4)
+	q.Pop()
+	_ = other.Push("e", 5)
+	_ = other.Push("f", 6)
+	_ = q.Meld(other)
+	q.Clear()
+
+	expectEvents(t, r,
+		"push 2 b", "push 3 c", "drop 3 c", "push 1 a", "drop 4 d", "pop 1 a",
+		"meld 2", "drop 6 f", "clear 2",
+	)
+}
+
+func TestObserverCircularBuffer(t *testing.T) {
+	r := &recorder{}
+	cb := pqueue.NewCircularBuffer[string](pqueue.WithObserver(r), pqueue.WithCapacity(1, pqueue.OverflowOverwriteOldest))
+
+	_ = cb.Push("a")
+	_ = cb.Push("b")
+	cb.Pop()
+
+	expectEvents(t, r, "push <nil> a", "drop <nil> a", "push <nil> b", "pop <nil> b")
+}
+
+func TestSlogObserver(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	q := pqueue.NewPairing[int, string](pqueue.WithObserver(pqueue.NewSlogObserver(logger, slog.LevelDebug, 3)))
+
+	for i := range 6 {
+		_ = q.Push(fmt.Sprint(i), i)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "priority=0") || !strings.Contains(lines[1], "priority=3") {
+		t.Fatalf("got logs %q, want the pushes of 0 and 3", lines)
+	}
+}
+
+func BenchmarkObserverPairingPush(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	q := pqueue.NewPairing[int, int](pqueue.WithObserver(pqueue.NewSlogObserver(logger, slog.LevelDebug, 1)))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Push(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+}
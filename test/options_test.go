@@ -0,0 +1,47 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+	"github.com/AndrewChon/pqueue/expvarmetrics"
+)
+
+func TestUnsupportedOption(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func()
+	}{
+		{"Pairing/WithSpillDir", func() { pqueue.NewPairing[int, string](pqueue.WithSpillDir(t.TempDir())) }},
+		{"Binary/WithBackoff", func() {
+			pqueue.NewBinary[int, string](pqueue.WithBackoff(pqueue.FixedBackoff(time.Second)))
+		}},
+		{"Skew/WithClock", func() { pqueue.NewSkew[int, string](pqueue.WithClock(newManualClock())) }},
+		{"CircularBuffer/WithTieBreak", func() {
+			pqueue.NewCircularBuffer[string](pqueue.WithTieBreak(pqueue.TieBreakFIFO))
+		}},
+		{"External/WithCapacity", func() {
+			pqueue.NewExternal[int, string](1, pqueue.WithCapacity(1, pqueue.OverflowError))
+		}},
+		{"DelayQueue/WithObserver", func() {
+			pqueue.NewDelayQueue[string](pqueue.WithObserver(&recorder{}))
+		}},
+		{"Leaser/WithMetrics", func() {
+			lr := pqueue.NewLeaser[int, string](pqueue.NewPairing[int, string](),
+				pqueue.WithMetrics(expvarmetrics.NewUnpublished()))
+			lr.Close()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != pqueue.ErrUnsupportedOption {
+					t.Fatalf("got panic %v, want ErrUnsupportedOption", r)
+				}
+			}()
+			tt.new()
+		})
+	}
+}
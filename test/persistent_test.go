@@ -0,0 +1,122 @@
+package test
+
+import (
+	"iter"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	skewpersistent "github.com/AndrewChon/pqueue/skew/persistent"
+	skewbinomialpersistent "github.com/AndrewChon/pqueue/skewbinomial/persistent"
+)
+
+// persistentHeap is implemented by the heaps of the persistent packages.
+type persistentHeap[H any] interface {
+	Size() int
+	Insert(key, value int) H
+	Meld(other H) H
+	RemoveMin() H
+	All() iter.Seq2[int, int]
+}
+
+// testPersistence checks that every version of a heap keeps its contents when newer versions are derived from it with
+// Insert, RemoveMin and Meld.
+func testPersistence[H persistentHeap[H]](t *testing.T, empty H) {
+	var versions []H
+	var want [][]int
+
+	// record adds a version of the heap, which must hold exactly keys.
+	record := func(h H, keys ...int) H {
+		versions = append(versions, h)
+		want = append(want, slices.Sorted(slices.Values(keys)))
+		return h
+	}
+
+	h := record(empty)
+	var keys []int
+	for _, k := range rand.Perm(50) {
+		keys = append(keys, k)
+		h = record(h.Insert(k, -k), keys...)
+	}
+
+	other, otherKeys := empty, []int{50, 51, 52}
+	for _, k := range otherKeys {
+		other = other.Insert(k, -k)
+	}
+	melded := record(h.Meld(other), append(slices.Clone(keys), otherKeys...)...)
+	record(other, otherKeys...)
+
+	slices.Sort(keys)
+	for i := range keys {
+		h = record(h.RemoveMin(), keys[i+1:]...)
+	}
+	record(melded.RemoveMin().RemoveMin(), append(slices.Clone(keys[2:]), otherKeys...)...)
+
+	// Removing from an empty heap leaves it empty.
+	record(empty.RemoveMin())
+
+	for i, v := range versions {
+		var got []int
+		for k, value := range v.All() {
+			if value != -k {
+				t.Fatalf("version %d: key %d has value %d", i, k, value)
+			}
+			got = append(got, k)
+		}
+
+		if v.Size() != len(want[i]) || !slices.Equal(got, want[i]) {
+			t.Fatalf("version %d: got %v (size %d), want %v", i, got, v.Size(), want[i])
+		}
+	}
+}
+
+func TestPersistentSkew(t *testing.T) {
+	testPersistence(t, skewpersistent.New[int, int]())
+}
+
+func TestPersistentSkewBinomial(t *testing.T) {
+	testPersistence(t, skewbinomialpersistent.New[int, int]())
+}
+
+func BenchmarkPersistentSkewInsert(b *testing.B) {
+	h := skewpersistent.New[int, int]()
+
+	for b.Loop() {
+		h = h.Insert(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+}
+
+func BenchmarkPersistentSkewRemoveMin(b *testing.B) {
+	h := skewpersistent.New[int, int]()
+
+	for i := 0; i < b.N; i++ {
+		h = h.Insert(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h = h.RemoveMin()
+	}
+}
+
+func BenchmarkPersistentSkewBinomialInsert(b *testing.B) {
+	h := skewbinomialpersistent.New[int, int]()
+
+	for b.Loop() {
+		h = h.Insert(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+}
+
+func BenchmarkPersistentSkewBinomialRemoveMin(b *testing.B) {
+	h := skewbinomialpersistent.New[int, int]()
+
+	for i := 0; i < b.N; i++ {
+		h = h.Insert(rand.Intn(math.MaxInt64), rand.Intn(math.MaxInt64))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h = h.RemoveMin()
+	}
+}
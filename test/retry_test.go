@@ -0,0 +1,151 @@
+package test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := pqueue.ExponentialBackoff(time.Second, 10*time.Second)
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if d := b.Delay(i + 1); d != w {
+			t.Fatalf("Delay(%d): got %v, want %v", i+1, d, w)
+		}
+	}
+	if d := pqueue.ExponentialBackoff(time.Second, math.MaxInt64).Delay(100); d != math.MaxInt64 {
+		t.Fatalf("Delay(100) with no practical limit: got %v, want %v", d, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	b := pqueue.JitteredBackoff(pqueue.FixedBackoff(10*time.Second), 0.2)
+	for range 100 {
+		if d := b.Delay(1); d < 8*time.Second || d > 12*time.Second {
+			t.Fatalf("Delay: got %v, want between 8s and 12s", d)
+		}
+	}
+
+	// Fractions outside of [0, 1] are clamped.
+	for _, tt := range []struct {
+		fraction float64
+		min, max time.Duration
+	}{
+		{5, 0, 20 * time.Second},
+		{-1, 10 * time.Second, 10 * time.Second},
+	} {
+		b := pqueue.JitteredBackoff(pqueue.FixedBackoff(10*time.Second), tt.fraction)
+		for range 100 {
+			if d := b.Delay(1); d < tt.min || d > tt.max {
+				t.Fatalf("Delay with fraction %v: got %v, want between %v and %v", tt.fraction, d, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetrierDeadLetter(t *testing.T) {
+	clock := newManualClock()
+	before := func(a, b time.Time) bool { return a.Before(b) }
+	q := pqueue.NewPairingFunc[time.Time, pqueue.Attempt[string]](before)
+	dead := pqueue.NewPairingFunc[time.Time, pqueue.Attempt[string]](before)
+	r := pqueue.NewRetrier(q, dead, pqueue.RetryAt(clock), pqueue.WithBackoff(pqueue.FixedBackoff(time.Minute)),
+		pqueue.WithMaxFailures(3))
+
+	_ = r.Push("a", clock.Now())
+	for failures := 1; failures <= 3; failures++ {
+		p, a, ok := q.PopItem()
+		if !ok {
+			t.Fatalf("attempt %d: queue is empty", failures)
+		}
+
+		isDead, err := r.Fail(a, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isDead != (failures == 3) {
+			t.Fatalf("attempt %d: dead is %v", failures, isDead)
+		}
+		if !isDead {
+			if p, _, _ := q.PeekItem(); !p.Equal(clock.Now().Add(time.Minute)) {
+				t.Fatalf("attempt %d: rescheduled for %v, want %v", failures, p, clock.Now().Add(time.Minute))
+			}
+		}
+		clock.Advance(time.Minute)
+	}
+
+	if a, ok := dead.Pop(); !ok || a.Value != "a" || a.Failures != 3 {
+		t.Fatalf("dead-letter queue: got %+v, %v", a, ok)
+	}
+	if n := q.Size(); n != 0 {
+		t.Fatalf("Size: got %d, want 0", n)
+	}
+}
+
+func TestRetrierFailLease(t *testing.T) {
+	clock := newManualClock()
+	q := pqueue.NewPairing[int64, pqueue.Attempt[string]]()
+	dead := pqueue.NewPairing[int64, pqueue.Attempt[string]]()
+	lr := pqueue.NewLeaser[int64, pqueue.Attempt[string]](q, pqueue.WithClock(clock))
+	defer lr.Close()
+	r := pqueue.NewRetrier(q, dead, pqueue.RetryAtUnixNano(clock), pqueue.WithBackoff(pqueue.FixedBackoff(time.Second)))
+
+	_ = r.Push("a", 0)
+	receipt, _ := lr.Lease(time.Minute)
+	if _, err := r.FailLease(lr, receipt); err != nil {
+		t.Fatal(err)
+	}
+	if err := lr.Ack(receipt); err != pqueue.ErrLeaseExpired {
+		t.Fatalf("Ack after FailLease: got %v, want %v", err, pqueue.ErrLeaseExpired)
+	}
+
+	p, a, _ := q.PopItem()
+	if a.Failures != 1 || p != clock.Now().Add(time.Second).UnixNano() {
+		t.Fatalf("retried element: got %+v with priority %d", a, p)
+	}
+}
+
+func TestRetrierFailExpiredLease(t *testing.T) {
+	clock := newManualClock()
+	q := pqueue.NewPairing[int64, pqueue.Attempt[string]]()
+	dead := pqueue.NewPairing[int64, pqueue.Attempt[string]]()
+	lr := pqueue.NewLeaser[int64, pqueue.Attempt[string]](q, pqueue.WithClock(clock))
+	defer lr.Close()
+	r := pqueue.NewRetrier(q, dead, pqueue.RetryAtUnixNano(clock), pqueue.WithBackoff(pqueue.FixedBackoff(time.Second)))
+
+	_ = r.Push("a", 0)
+	receipt, _ := lr.Lease(time.Minute)
+
+	// The lease is past its deadline, but has yet to be reclaimed by the reaping goroutine. Failing it must not succeed,
+	// or the element would be delivered twice: once when it is retried, and once when the lease is reclaimed.
+	clock.Skip(time.Minute)
+	if _, err := r.FailLease(lr, receipt); err != pqueue.ErrLeaseExpired {
+		t.Fatalf("FailLease after deadline: got %v, want %v", err, pqueue.ErrLeaseExpired)
+	}
+
+	if n := q.Size(); n != 1 {
+		t.Fatalf("Size: got %d, want 1", n)
+	}
+	if p, a, _ := q.PopItem(); p != 0 || a.Failures != 0 {
+		t.Fatalf("reclaimed element: got %+v with priority %d, want its original attempt", a, p)
+	}
+}
+
+func BenchmarkRetrierFail(b *testing.B) {
+	q := pqueue.NewPairing[int64, pqueue.Attempt[int]]()
+	dead := pqueue.NewPairing[int64, pqueue.Attempt[int]]()
+	r := pqueue.NewRetrier(q, dead, pqueue.RetryAtUnixNano(nil), pqueue.WithMaxFailures(math.MaxInt))
+
+	for i := 0; i < b.N; i++ {
+		r.Push(rand.Intn(math.MaxInt64), rand.Int63())
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, a, _ := q.PopItem()
+		r.Fail(a, p)
+	}
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/AndrewChon/pqueue"
+	"github.com/AndrewChon/pqueue/binary"
+	"github.com/AndrewChon/pqueue/pairing"
+	"github.com/AndrewChon/pqueue/skew"
+	"github.com/AndrewChon/pqueue/skewbinomial"
+)
+
+type validatedQueue interface {
+	pqueue.Queue[int, int]
+	PushHandle(v, priority int) (*pqueue.Handle[int, int], error)
+	Update(h *pqueue.Handle[int, int], priority int) bool
+	Remove(h *pqueue.Handle[int, int]) bool
+	CrossMeld(other pqueue.CrossMeldable) error
+	Validate() error
+}
+
+func TestValidate(t *testing.T) {
+	queues := map[string]func(opts ...pqueue.Option) validatedQueue{
+		"Binary":       func(opts ...pqueue.Option) validatedQueue { return pqueue.NewBinary[int, int](opts...) },
+		"Pairing":      func(opts ...pqueue.Option) validatedQueue { return pqueue.NewPairing[int, int](opts...) },
+		"Skew":         func(opts ...pqueue.Option) validatedQueue { return pqueue.NewSkew[int, int](opts...) },
+		"SkewBinomial": func(opts ...pqueue.Option) validatedQueue { return pqueue.NewSkewBinomial[int, int](opts...) },
+	}
+
+	for name, newQueue := range queues {
+		t.Run(name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			q := newQueue(pqueue.WithCapacity(200, pqueue.OverflowEvictMax), pqueue.WithTieBreak(pqueue.TieBreakFIFO))
+
+			var handles []*pqueue.Handle[int, int]
+			for i := range 2000 {
+				switch op := r.Intn(10); {
+				case op < 4:
+					h, _ := q.PushHandle(i, r.Intn(100))
+					handles = append(handles, h)
+				case op < 6:
+					q.Pop()
+				case op < 7 && len(handles) > 0:
+					q.Update(handles[r.Intn(len(handles))], r.Intn(100))
+				case op < 8 && len(handles) > 0:
+					q.Remove(handles[r.Intn(len(handles))])
+				case op < 9:
+					other := newQueue()
+					for range r.Intn(50) {
+						_ = other.Push(i, r.Intn(100))
+					}
+					_ = q.CrossMeld(other)
+				case r.Intn(20) == 0:
+					q.Clear()
+				}
+
+				if err := q.Validate(); err != nil {
+					t.Fatalf("operation %d: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCircularBuffer(t *testing.T) {
+	cb := pqueue.NewCircularBuffer[int](pqueue.WithCapacity(10, pqueue.OverflowOverwriteOldest))
+	other := pqueue.NewCircularBuffer[int]()
+	for i := range 30 {
+		_ = cb.Push(i)
+		_ = other.Push(i)
+		if i%7 == 0 {
+			cb.Pop()
+		}
+		if i%11 == 0 {
+			_ = cb.Meld(other)
+		}
+
+		if err := cb.Validate(); err != nil {
+			t.Fatalf("operation %d: %v", i, err)
+		}
+	}
+}
+
+func TestValidateCorrupt(t *testing.T) {
+	// Reversing the order of keys after the fact corrupts the heap, as mutating keys in place would.
+	reversed := false
+	less := func(a, b int) bool { return (a < b) != reversed }
+
+	q := pqueue.NewSkewFunc[int, int](less)
+	for i := range 10 {
+		_ = q.Push(i, i)
+	}
+	reversed = true
+	if err := q.Validate(); !errors.Is(err, pqueue.ErrInvalid) || !errors.Is(err, skew.ErrInvalid) {
+		t.Fatalf("Validate: got %v, want an error wrapping %v and %v", err, pqueue.ErrInvalid, skew.ErrInvalid)
+	}
+	reversed = false
+
+	bh := binary.NewHeapFunc[int, int](less)
+	pt := (*pairing.Tree[int, int])(nil)
+	f := skewbinomial.NewForestFunc[int, int](less)
+	for i := range 10 {
+		bh.Insert(binary.NewNode(i, i))
+		pt = pairing.InsertFunc(pt, pairing.NewTree(i, i), less)
+		f.Insert(i, i)
+	}
+	if err := errors.Join(bh.Validate(), pairing.ValidateFunc(pt, less), f.Validate()); err != nil {
+		t.Fatal(err)
+	}
+
+	reversed = true
+	if err := bh.Validate(); !errors.Is(err, binary.ErrInvalid) {
+		t.Fatalf("binary: got %v, want %v", err, binary.ErrInvalid)
+	}
+	if err := pairing.ValidateFunc(pt, less); !errors.Is(err, pairing.ErrInvalid) {
+		t.Fatalf("pairing: got %v, want %v", err, pairing.ErrInvalid)
+	}
+	if err := f.Validate(); !errors.Is(err, skewbinomial.ErrInvalid) {
+		t.Fatalf("skewbinomial: got %v, want %v", err, skewbinomial.ErrInvalid)
+	}
+}
+
+func BenchmarkValidatePairing(b *testing.B) {
+	q := pqueue.NewPairing[int, int]()
+	for i := range 1000 {
+		_ = q.Push(i, rand.Int())
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Validate()
+	}
+}
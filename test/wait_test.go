@@ -0,0 +1,133 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AndrewChon/pqueue"
+)
+
+// waitResult is the outcome of a call to PopWait.
+type waitResult[T any] struct {
+	v   T
+	err error
+}
+
+// popWait calls pop in a new goroutine and returns a channel that receives its result. It gives pop a moment to block
+// before returning, so that the caller's next operation is the one that wakes it.
+func popWait[T any](pop func() (T, error)) <-chan waitResult[T] {
+	done := make(chan waitResult[T], 1)
+	go func() {
+		v, err := pop()
+		done <- waitResult[T]{v, err}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	return done
+}
+
+// await returns the result received from done, failing the test if none arrives in time.
+func await[T any](t *testing.T, done <-chan waitResult[T]) waitResult[T] {
+	t.Helper()
+
+	select {
+	case res := <-done:
+		return res
+	case <-time.After(time.Second):
+		t.Fatal("PopWait was not woken")
+		panic("unreachable")
+	}
+}
+
+func TestPopWait(t *testing.T) {
+	for k, kind := range heapKinds {
+		next := heapKinds[(k+1)%len(heapKinds)]
+
+		wakers := []struct {
+			name string
+			wake func(q heap) error
+		}{
+			{"Push", func(q heap) error { return q.Push("a", 1) }},
+			{"PushMany", func(q heap) error {
+				return q.PushMany([]pqueue.Item[int, string]{{Priority: 2, Value: "b"}, {Priority: 1, Value: "a"}})
+			}},
+			{"Meld", func(q heap) error {
+				other := kind.new()
+				_ = other.Push("a", 1)
+				return kind.meld(q, other)
+			}},
+			{"CrossMeld", func(q heap) error {
+				other := next.new()
+				_ = other.Push("a", 1)
+				return q.CrossMeld(other)
+			}},
+		}
+
+		for _, w := range wakers {
+			t.Run(kind.name+"/"+w.name, func(t *testing.T) {
+				q := kind.new()
+				done := popWait(func() (string, error) { return q.PopWait(context.Background()) })
+
+				if err := w.wake(q); err != nil {
+					t.Fatal(err)
+				}
+				if res := await(t, done); res.err != nil || res.v != "a" {
+					t.Fatalf("PopWait: got %q, %v, want \"a\"", res.v, res.err)
+				}
+			})
+		}
+
+		t.Run(kind.name+"/Cancel", func(t *testing.T) {
+			q := kind.new()
+			ctx, cancel := context.WithCancel(context.Background())
+			done := popWait(func() (string, error) { return q.PopWait(ctx) })
+
+			cancel()
+			if res := await(t, done); !errors.Is(res.err, context.Canceled) {
+				t.Fatalf("PopWait: got %v, want context.Canceled", res.err)
+			}
+
+			// A canceled waiter does not consume elements pushed afterwards.
+			_ = q.Push("a", 1)
+			if q.Size() != 1 {
+				t.Fatalf("Size: got %d, want 1", q.Size())
+			}
+		})
+	}
+
+	t.Run("CircularBuffer/Push", func(t *testing.T) {
+		q := pqueue.NewCircularBuffer[int]()
+		done := popWait(func() (int, error) { return q.PopWait(context.Background()) })
+
+		_ = q.Push(7)
+		if res := await(t, done); res.err != nil || res.v != 7 {
+			t.Fatalf("PopWait: got %d, %v, want 7", res.v, res.err)
+		}
+	})
+
+	t.Run("CircularBuffer/Meld", func(t *testing.T) {
+		q, other := pqueue.NewCircularBuffer[int](), pqueue.NewCircularBuffer[int]()
+		done := popWait(func() (int, error) { return q.PopWait(context.Background()) })
+
+		_ = other.Push(7)
+		_ = other.Push(8)
+		if err := q.Meld(other); err != nil {
+			t.Fatal(err)
+		}
+		if res := await(t, done); res.err != nil || res.v != 7 {
+			t.Fatalf("PopWait: got %d, %v, want 7", res.v, res.err)
+		}
+	})
+
+	t.Run("CircularBuffer/Cancel", func(t *testing.T) {
+		q := pqueue.NewCircularBuffer[int]()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		if _, err := q.PopWait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("PopWait: got %v, want context.DeadlineExceeded", err)
+		}
+	})
+}
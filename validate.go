@@ -0,0 +1,37 @@
+package pqueue
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// ErrInvalid is returned by Validate when a queue's invariants do not hold, which indicates that the queue has been
+// corrupted. Errors returned by the Validate functions of the heap packages are wrapped along with it.
+var ErrInvalid = errors.New("pqueue: invalid queue")
+
+// invalid wraps an error returned by the Validate function of a heap package with ErrInvalid.
+func invalid(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalid, err)
+}
+
+// validateSize checks that a queue records the number of elements it holds, count, as its size, and that its size fits
+// within its capacity. bd is nil for queues that cannot be bounded.
+func validateSize(count, size int, bd *bound) error {
+	if count != size {
+		return fmt.Errorf("%w: size is %d, but the queue holds %d elements", ErrInvalid, size, count)
+	}
+	if bd != nil && bd.excess(size) > 0 {
+		return fmt.Errorf("%w: size is %d, which exceeds the capacity of %d", ErrInvalid, size, bd.capacity)
+	}
+	return nil
+}
+
+// countOf returns the number of nodes in nodes, which must be finite.
+func countOf[N any](nodes iter.Seq[N]) int {
+	count := 0
+	for range nodes {
+		count++
+	}
+	return count
+}
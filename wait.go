@@ -0,0 +1,57 @@
+package pqueue
+
+import (
+	"context"
+	"sync"
+)
+
+// waiters lets goroutines wait for a change in a queue's state, such as the queue becoming non-empty. Its methods must
+// be called with the queue's lock held, which is what guarantees that no wakeups are lost: a goroutine obtains the
+// channel to wait on before releasing the lock, so any broadcast that follows is guaranteed to close that channel.
+type waiters struct {
+	ch chan struct{}
+}
+
+// wait returns a channel that is closed the next time broadcast is called.
+func (w *waiters) wait() <-chan struct{} {
+	if w.ch == nil {
+		w.ch = make(chan struct{})
+	}
+	return w.ch
+}
+
+// broadcast wakes every goroutine that is currently waiting.
+func (w *waiters) broadcast() {
+	if w.ch != nil {
+		close(w.ch)
+		w.ch = nil
+	}
+}
+
+// waitFor repeatedly calls try with l held until it succeeds, waiting on w in between attempts. It returns ctx's error
+// if ctx is done before try succeeds, or ErrClosed if try fails after c has been closed. mt is the meter of the queue
+// that l belongs to.
+func waitFor[T any](ctx context.Context, mt *meter, l *sync.RWMutex, w *waiters, c *closer, try func() (T, bool)) (T, error) {
+	for {
+		mt.lock(l)
+		v, ok := try()
+		if ok {
+			l.Unlock()
+			return v, nil
+		}
+		if c.closed {
+			l.Unlock()
+			return v, ErrClosed
+		}
+
+		ch := w.wait()
+		l.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}